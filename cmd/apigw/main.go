@@ -6,23 +6,24 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/access"
 	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/apigateway/v1"
 	ycsdk "github.com/yandex-cloud/go-sdk"
-	"github.com/yandex-cloud/go-sdk/iamkey"
 	"github.com/yc-actions/sourcecraft-actions/internal/container"
+	"github.com/yc-actions/sourcecraft-actions/pkg/accessbinding"
+	"github.com/yc-actions/sourcecraft-actions/pkg/auth"
 	"github.com/yc-actions/sourcecraft-actions/pkg/env"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
 	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
 )
 
 // Input constants.
 const (
-	inputFolderID            = "FOLDER_ID"
-	inputGatewayName         = "GATEWAY_NAME"
-	inputSpecFile            = "SPEC_FILE"
-	inputSpec                = "SPEC"
-	inputYcSaJsonCredentials = "YC_SA_JSON_CREDENTIALS"
-	inputYcIamToken          = "YC_IAM_TOKEN"
-	inputVariables           = "VARIABLES" // Optional input for additional variables in the spec
+	inputFolderID    = "FOLDER_ID"
+	inputGatewayName = "GATEWAY_NAME"
+	inputSpecFile    = "SPEC_FILE"
+	inputSpec        = "SPEC"
+	inputVariables   = "VARIABLES" // Optional input for additional variables in the spec
 )
 
 // Gateway represents an API Gateway.
@@ -68,58 +69,18 @@ func main() {
 
 	variables := env.ParseEnvironmentVariables(sourcecraft.GetMultilineInput(inputVariables))
 
-	// Get credentials
-	ycSaJsonCredentials := sourcecraft.GetInput(inputYcSaJsonCredentials)
-	ycIamToken := sourcecraft.GetInput(inputYcIamToken)
-
-	// Create SDK
-	var sdk *ycsdk.SDK
-
-	var err error
-
-	if ycSaJsonCredentials != "" {
-		// Create credentials from service account JSON
-		key, err := iamkey.ReadFromJSONBytes([]byte(ycSaJsonCredentials))
-		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to read service account JSON: %v", err))
-
-			return
-		}
-
-		credentials, err := ycsdk.ServiceAccountKey(key)
-		if err != nil {
-			sourcecraft.SetFailed(
-				fmt.Sprintf("Failed to create credentials from service account JSON: %v", err),
-			)
-
-			return
-		}
-
-		// Create SDK
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: credentials,
-		})
-		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
-
-			return
-		}
-
-		sourcecraft.Info("Using service account JSON credentials")
-	} else if ycIamToken != "" {
-		// Create SDK with IAM token
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: ycsdk.NewIAMTokenCredentials(ycIamToken),
-		})
-		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
+	// API gateways have no invoker role, so there is no public shorthand
+	bindings, err := accessbinding.InputBindings("")
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse access bindings: %v", err))
 
-			return
-		}
+		return
+	}
 
-		sourcecraft.Info("Using IAM token")
-	} else {
-		sourcecraft.SetFailed("No credentials provided")
+	// Create SDK
+	sdk, err := auth.NewSDK(ctx)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
 
 		return
 	}
@@ -166,6 +127,15 @@ func main() {
 
 	var gateway Gateway
 
+	if plan.DryRun() {
+		err = planGateway(ctx, sdk, listResp.ApiGateways, folderID, gatewayName, specContent, bindings)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to plan API gateway deployment: %v", err))
+		}
+
+		return
+	}
+
 	if len(listResp.ApiGateways) > 0 {
 		// Gateway exists, update it
 		existingGateway := listResp.ApiGateways[0]
@@ -199,25 +169,90 @@ func main() {
 
 		sourcecraft.Info(fmt.Sprintf("Gateway successfully created. Id: %s", gateway.ID))
 	}
+
+	// Reconcile access bindings
+	if len(bindings) > 0 {
+		err = accessbinding.Reconcile(ctx, sdk, sdk.Serverless().APIGateway().ApiGateway(), gateway.ID, bindings, true)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to update access bindings: %v", err))
+
+			return
+		}
+	}
+
 	// Set outputs
 	sourcecraft.SetOutput("GATEWAY_ID", gateway.ID)
 	sourcecraft.SetOutput("GATEWAY_DOMAIN", gateway.Domain)
 }
 
-func createGateway(
+// planGateway publishes the plan of creating or updating the gateway.
+func planGateway(
 	ctx context.Context,
 	sdk *ycsdk.SDK,
-	gateway *Gateway,
+	existing []*apigateway.ApiGateway,
 	folderID string,
 	gatewayName string,
 	specContent []byte,
+	bindings []*access.AccessBinding,
 ) error {
+	p := plan.New("apigw")
+
+	var (
+		err       error
+		gatewayID string
+	)
+
+	if len(existing) > 0 {
+		gatewayID = existing[0].Id
+		err = p.AddRequest(
+			fmt.Sprintf("Update API gateway '%s' (%s)", gatewayName, existing[0].Id),
+			newUpdateGatewayRequest(existing[0].Id, specContent),
+		)
+	} else {
+		err = p.AddRequest(
+			fmt.Sprintf("Create API gateway '%s'", gatewayName),
+			newCreateGatewayRequest(folderID, gatewayName, specContent),
+		)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(bindings) > 0 {
+		request, err := accessbinding.NewUpdateRequest(
+			ctx,
+			sdk.Serverless().APIGateway().ApiGateway(),
+			gatewayID,
+			bindings,
+			true,
+		)
+		if err != nil {
+			return err
+		}
+
+		if request != nil {
+			err = p.AddRequest(fmt.Sprintf("Update access bindings of API gateway '%s'", gatewayName), request)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return p.Publish()
+}
+
+// newCreateGatewayRequest builds the request creating the gateway.
+func newCreateGatewayRequest(
+	folderID string,
+	gatewayName string,
+	specContent []byte,
+) *apigateway.CreateApiGatewayRequest {
 	// Get repository info for description
 	repoOwner := sourcecraft.GetSourcecraftRepositoryOwner()
 	repoName := sourcecraft.GetSourcecraftRepository()
 
-	// Create gateway
-	createReq := &apigateway.CreateApiGatewayRequest{
+	return &apigateway.CreateApiGatewayRequest{
 		FolderId:    folderID,
 		Name:        gatewayName,
 		Description: fmt.Sprintf("Created from: %s/%s", repoOwner, repoName),
@@ -225,6 +260,31 @@ func createGateway(
 			OpenapiSpec: string(specContent),
 		},
 	}
+}
+
+// newUpdateGatewayRequest builds the request updating the gateway spec.
+func newUpdateGatewayRequest(
+	gatewayID string,
+	specContent []byte,
+) *apigateway.UpdateApiGatewayRequest {
+	return &apigateway.UpdateApiGatewayRequest{
+		ApiGatewayId: gatewayID,
+		Spec: &apigateway.UpdateApiGatewayRequest_OpenapiSpec{
+			OpenapiSpec: string(specContent),
+		},
+	}
+}
+
+func createGateway(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	gateway *Gateway,
+	folderID string,
+	gatewayName string,
+	specContent []byte,
+) error {
+	// Create gateway
+	createReq := newCreateGatewayRequest(folderID, gatewayName, specContent)
 
 	// Create the gateway and wrap the operation
 	metaOp, err := sdk.WrapOperation(
@@ -263,12 +323,7 @@ func updateGateway(
 	specContent []byte,
 ) error {
 	// Update gateway
-	updateReq := &apigateway.UpdateApiGatewayRequest{
-		ApiGatewayId: gateway.ID,
-		Spec: &apigateway.UpdateApiGatewayRequest_OpenapiSpec{
-			OpenapiSpec: string(specContent),
-		},
-	}
+	updateReq := newUpdateGatewayRequest(gateway.ID, specContent)
 
 	// Update the gateway and wrap the operation
 	metaOp, err := sdk.WrapOperation(
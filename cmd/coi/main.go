@@ -6,11 +6,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/yandex-cloud/go-genproto/yandex/cloud/compute/v1"
 	ycsdk "github.com/yandex-cloud/go-sdk"
-	"github.com/yandex-cloud/go-sdk/iamkey"
+	"github.com/yc-actions/sourcecraft-actions/pkg/auth"
 	"github.com/yc-actions/sourcecraft-actions/pkg/memory"
+	"github.com/yc-actions/sourcecraft-actions/pkg/operation"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
 	"github.com/yc-actions/sourcecraft-actions/pkg/serviceaccount"
 	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
 )
@@ -38,9 +41,7 @@ const (
 	inputVMDiskType           = "VM_DISK_TYPE"
 	inputVMDiskSize           = "VM_DISK_SIZE"
 	inputVMCoreFraction       = "VM_CORE_FRACTION"
-	inputYcSaJsonCredentials  = "YC_SA_JSON_CREDENTIALS"
-	inputYcIamToken           = "YC_IAM_TOKEN"
-	inputYcSaID               = "YC_SA_ID"
+	inputOperationTimeout     = "OPERATION_TIMEOUT"
 )
 
 // No local environment variable constants needed as they are defined in pkg/sourcecraft/sdk.go
@@ -150,31 +151,20 @@ func setOutputs(instance *compute.Instance) {
 	}
 }
 
-// createVM creates a new VM.
-func createVM(
-	ctx context.Context,
-	sdk *ycsdk.SDK,
+// newCreateInstanceRequest builds the request creating the VM from the COI image.
+func newCreateInstanceRequest(
 	vmParams *VMParams,
+	coiImageID string,
 	repoOwner, repoName string,
-) error {
-	coiImageID, err := findCoiImageID(ctx, sdk)
-	if err != nil {
-		return err
-	}
-
-	sourcecraft.StartGroup("Create new VM")
-	defer sourcecraft.EndGroup()
-
-	sourcecraft.SetOutput("VM_CREATED", "true")
-
+) (*compute.CreateInstanceRequest, error) {
 	userData, err := prepareConfig(vmParams.UserDataPath)
 	if err != nil {
-		return fmt.Errorf("failed to prepare user data: %w", err)
+		return nil, fmt.Errorf("failed to prepare user data: %w", err)
 	}
 
 	dockerCompose, err := prepareConfig(vmParams.DockerComposePath)
 	if err != nil {
-		return fmt.Errorf("failed to prepare docker compose: %w", err)
+		return nil, fmt.Errorf("failed to prepare docker compose: %w", err)
 	}
 
 	// Get Sourcecraft SHA
@@ -225,21 +215,132 @@ func createVM(
 		req.NetworkInterfaceSpecs[0].PrimaryV4AddressSpec.OneToOneNatSpec.Address = vmParams.IPAddress
 	}
 
+	return req, nil
+}
+
+// newUpdateMetadataRequest builds the request updating the metadata of an existing VM.
+func newUpdateMetadataRequest(
+	instanceID string,
+	vmParams *VMParams,
+) (*compute.UpdateInstanceMetadataRequest, error) {
+	userData, err := prepareConfig(vmParams.UserDataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare user data: %w", err)
+	}
+
+	dockerCompose, err := prepareConfig(vmParams.DockerComposePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare docker compose: %w", err)
+	}
+
+	// Get Sourcecraft SHA
+	sourcecraftSHA := sourcecraft.GetSourcecraftSHA()
+
+	return &compute.UpdateInstanceMetadataRequest{
+		InstanceId: instanceID,
+		Upsert: map[string]string{
+			"user-data":       userData,
+			"docker-compose":  dockerCompose,
+			"sourcecraft-sha": sourcecraftSHA,
+		},
+	}, nil
+}
+
+// planVM publishes the plan of creating the VM or updating its metadata.
+func planVM(ctx context.Context, sdk *ycsdk.SDK, instanceID string, vmParams *VMParams) error {
+	p := plan.New("coi")
+
+	if instanceID == "" {
+		coiImageID, err := findCoiImageID(ctx, sdk)
+		if err != nil {
+			return err
+		}
+
+		req, err := newCreateInstanceRequest(
+			vmParams,
+			coiImageID,
+			sourcecraft.GetSourcecraftRepositoryOwner(),
+			sourcecraft.GetSourcecraftRepository(),
+		)
+		if err != nil {
+			return err
+		}
+
+		err = p.AddRequest(fmt.Sprintf("Create VM '%s'", vmParams.Name), req)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := detectMetadataConflict(ctx, sdk, instanceID)
+		if err != nil {
+			return fmt.Errorf("metadata conflict detected: %w", err)
+		}
+
+		req, err := newUpdateMetadataRequest(instanceID, vmParams)
+		if err != nil {
+			return err
+		}
+
+		err = p.AddRequest(
+			fmt.Sprintf("Update metadata of VM '%s' (%s)", vmParams.Name, instanceID),
+			req,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.Publish()
+}
+
+// createVM creates a new VM.
+func createVM(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	waiter *operation.Waiter,
+	vmParams *VMParams,
+	repoOwner, repoName string,
+) error {
+	coiImageID, err := findCoiImageID(ctx, sdk)
+	if err != nil {
+		return err
+	}
+
+	sourcecraft.StartGroup("Create new VM")
+	defer sourcecraft.EndGroup()
+
+	sourcecraft.SetOutput("VM_CREATED", "true")
+
+	req, err := newCreateInstanceRequest(vmParams, coiImageID, repoOwner, repoName)
+	if err != nil {
+		return err
+	}
+
 	instanceService := sdk.Compute().Instance()
 
-	op, err := instanceService.Create(ctx, req)
+	op, err := sdk.WrapOperation(instanceService.Create(ctx, req))
 	if err != nil {
 		return fmt.Errorf("failed to create instance: %w", err)
 	}
 
-	// Since we can't use sdk.WrapOperation due to SDK version differences,
-	// we'll just check if the operation is done
-	if !op.Done {
-		return fmt.Errorf("operation is not done")
+	// Wait for operation to complete
+	err = waiter.Wait(ctx, op)
+	if err != nil {
+		return fmt.Errorf("failed to wait for operation: %w", err)
+	}
+
+	meta, err := op.Metadata()
+	if err != nil {
+		return fmt.Errorf("failed to get operation metadata: %w", err)
+	}
+
+	// Get instance ID from metadata
+	createInstanceMetadata, ok := meta.(*compute.CreateInstanceMetadata)
+	if !ok {
+		return fmt.Errorf("unexpected operation metadata type: %T", meta)
 	}
 
-	// Get instance ID directly from the operation
-	instanceID := op.GetId()
+	instanceID := createInstanceMetadata.InstanceId
 
 	// Get instance
 	instance, err := instanceService.Get(ctx, &compute.GetInstanceRequest{
@@ -259,6 +360,7 @@ func createVM(
 func updateMetadata(
 	ctx context.Context,
 	sdk *ycsdk.SDK,
+	waiter *operation.Waiter,
 	instanceID string,
 	vmParams *VMParams,
 ) error {
@@ -267,39 +369,22 @@ func updateMetadata(
 
 	sourcecraft.SetOutput("created", "false")
 
-	userData, err := prepareConfig(vmParams.UserDataPath)
-	if err != nil {
-		return fmt.Errorf("failed to prepare user data: %w", err)
-	}
-
-	dockerCompose, err := prepareConfig(vmParams.DockerComposePath)
+	req, err := newUpdateMetadataRequest(instanceID, vmParams)
 	if err != nil {
-		return fmt.Errorf("failed to prepare docker compose: %w", err)
-	}
-
-	// Get Sourcecraft SHA
-	sourcecraftSHA := sourcecraft.GetSourcecraftSHA()
-
-	req := &compute.UpdateInstanceMetadataRequest{
-		InstanceId: instanceID,
-		Upsert: map[string]string{
-			"user-data":       userData,
-			"docker-compose":  dockerCompose,
-			"sourcecraft-sha": sourcecraftSHA,
-		},
+		return err
 	}
 
 	instanceService := sdk.Compute().Instance()
 
-	op, err := instanceService.UpdateMetadata(ctx, req)
+	op, err := sdk.WrapOperation(instanceService.UpdateMetadata(ctx, req))
 	if err != nil {
 		return fmt.Errorf("failed to update instance metadata: %w", err)
 	}
 
-	// Since we can't use sdk.WrapOperation due to SDK version differences,
-	// we'll just check if the operation is done
-	if !op.Done {
-		return fmt.Errorf("operation is not done")
+	// Wait for operation to complete
+	err = waiter.Wait(ctx, op)
+	if err != nil {
+		return fmt.Errorf("failed to wait for operation: %w", err)
 	}
 
 	// Get instance
@@ -447,68 +532,10 @@ func detectMetadataConflict(ctx context.Context, sdk *ycsdk.SDK, instanceID stri
 func main() {
 	ctx := context.Background()
 
-	// Parse inputs
-	ycSaJsonCredentials := sourcecraft.GetInput(inputYcSaJsonCredentials)
-	ycIamToken := sourcecraft.GetInput(inputYcIamToken)
-	ycSaID := sourcecraft.GetInput(inputYcSaID)
-
 	// Create SDK
-	var sdk *ycsdk.SDK
-
-	var err error
-
-	if ycSaJsonCredentials != "" {
-		// Create credentials
-		var key *iamkey.Key
-
-		key, err = iamkey.ReadFromJSONBytes([]byte(ycSaJsonCredentials))
-		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to read service account JSON: %v", err))
-
-			return
-		}
-
-		var credentials ycsdk.Credentials
-
-		credentials, err = ycsdk.ServiceAccountKey(key)
-		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create credentials: %v", err))
-
-			return
-		}
-
-		// Create SDK
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: credentials,
-		})
-		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
-
-			return
-		}
-
-		sourcecraft.Info("Parsed Service account JSON")
-	} else if ycIamToken != "" {
-		// Create SDK with IAM token
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: ycsdk.NewIAMTokenCredentials(ycIamToken),
-		})
-		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
-
-			return
-		}
-
-		sourcecraft.Info("Using IAM token")
-	} else if ycSaID != "" {
-		// In Sourcecraft, we would use getIDToken() to get a Sourcecraft token
-		// Since there's no direct equivalent in Go, we'll use a different approach
-		// This is a placeholder for now
-		sourcecraft.SetFailed("Token exchange not implemented in Go version yet")
-
-		return
-	} else {
-		sourcecraft.SetFailed("No credentials provided")
+	sdk, err := auth.NewSDK(ctx)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
 
 		return
 	}
@@ -523,6 +550,13 @@ func main() {
 
 	sourcecraft.Info(fmt.Sprintf("Folder ID: %s, name: %s", vmParams.FolderID, vmParams.Name))
 
+	// Parse operation timeout
+	operationTimeout := sourcecraft.GetInt64Input(
+		inputOperationTimeout,
+		int64(operation.DefaultTimeout/time.Second),
+	)
+	waiter := operation.NewWaiter(time.Duration(operationTimeout) * time.Second)
+
 	// Resolve service account ID if name is provided
 	if vmParams.ServiceAccountID == "" && vmParams.ServiceAccountName != "" {
 		serviceAccountID, err := serviceaccount.ResolveID(
@@ -561,13 +595,22 @@ func main() {
 		return
 	}
 
+	if plan.DryRun() {
+		err = planVM(ctx, sdk, vmID, vmParams)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to plan VM deployment: %v", err))
+		}
+
+		return
+	}
+
 	// Create or update VM
 	if vmID == "" {
 		// Get repository owner and name from environment variables
 		repoOwner := sourcecraft.GetSourcecraftRepositoryOwner()
 		repoName := sourcecraft.GetSourcecraftRepository()
 
-		err = createVM(ctx, sdk, vmParams, repoOwner, repoName)
+		err = createVM(ctx, sdk, waiter, vmParams, repoOwner, repoName)
 		if err != nil {
 			sourcecraft.SetFailed(fmt.Sprintf("Failed to create VM: %v", err))
 
@@ -583,7 +626,7 @@ func main() {
 		}
 
 		// Update VM metadata
-		err = updateMetadata(ctx, sdk, vmID, vmParams)
+		err = updateMetadata(ctx, sdk, waiter, vmID, vmParams)
 		if err != nil {
 			sourcecraft.SetFailed(fmt.Sprintf("Failed to update VM metadata: %v", err))
 
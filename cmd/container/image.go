@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/pkg/env"
+	"github.com/yc-actions/sourcecraft-actions/pkg/ociimage"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// Image input constants.
+const (
+	inputImageRepository = "IMAGE_REPOSITORY"
+	inputImageBase       = "IMAGE_BASE"
+	inputImageFiles      = "IMAGE_FILES"
+	inputImageEntrypoint = "IMAGE_ENTRYPOINT"
+	inputImageCmd        = "IMAGE_CMD"
+	inputImageWorkdir    = "IMAGE_WORKDIR"
+	inputImageEnv        = "IMAGE_ENV"
+)
+
+// layeredImage is the revision image made of files of the workspace layered onto a base image.
+type layeredImage struct {
+	options *ociimage.Options
+	tag     name.Tag
+}
+
+// parseLayeredImage parses the image inputs and checks that the base image reference is valid and the files
+// exist, or returns nil if IMAGE_REPOSITORY isn't set.
+func parseLayeredImage() (*layeredImage, error) {
+	repository := sourcecraft.GetInput(inputImageRepository)
+	if repository == "" {
+		return nil, nil
+	}
+
+	sha := sourcecraft.GetSourcecraftSHA()
+	if sha == "" {
+		return nil, fmt.Errorf("commit SHA is required to tag the image")
+	}
+
+	tag, err := ociimage.TagReference(repository, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ociimage.ParseFiles(sourcecraft.GetMultilineInput(inputImageFiles))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s:\n%w", inputImageFiles, err)
+	}
+
+	options := &ociimage.Options{
+		Base:      sourcecraft.GetInput(inputImageBase),
+		SourceDir: sourcecraft.GetSourcecraftWorkspace(),
+		Files:     files,
+		Config: ociimage.Config{
+			Entrypoint: sourcecraft.GetMultilineInput(inputImageEntrypoint),
+			Cmd:        sourcecraft.GetMultilineInput(inputImageCmd),
+			WorkingDir: sourcecraft.GetInput(inputImageWorkdir),
+			Env:        env.ParseEnvironmentVariables(sourcecraft.GetMultilineInput(inputImageEnv)),
+		},
+	}
+
+	err = options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &layeredImage{options: options, tag: tag}, nil
+}
+
+// description describes the image in the plan.
+func (b *layeredImage) description() string {
+	return fmt.Sprintf("Layer %d file(s) onto %s as image %s and push it", len(b.options.Files), b.options.Base, b.tag)
+}
+
+// run layers the files onto the base image, pushes the image with the IAM credentials of the SDK and returns
+// the reference pinned to its digest.
+func (b *layeredImage) run(ctx context.Context, sdk *ycsdk.SDK) (string, error) {
+	sourcecraft.StartGroup("Layer image")
+	defer sourcecraft.EndGroup()
+
+	keychain, err := registryKeychain(ctx, sdk)
+	if err != nil {
+		return "", err
+	}
+
+	layerDir, err := os.MkdirTemp("", "image-layers-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create layer directory: %w", err)
+	}
+	defer os.RemoveAll(layerDir)
+
+	b.options.Keychain = keychain
+	b.options.LayerDir = layerDir
+
+	sourcecraft.Info(fmt.Sprintf("Layering %d file(s) onto %s", len(b.options.Files), b.options.Base))
+
+	image, err := ociimage.LayerFiles(ctx, b.options)
+	if err != nil {
+		return "", fmt.Errorf("failed to layer image: %w", err)
+	}
+
+	sourcecraft.Info(fmt.Sprintf("Pushing image %s", b.tag))
+
+	pinned, err := ociimage.Push(ctx, image, b.tag, keychain)
+	if err != nil {
+		return "", err
+	}
+
+	sourcecraft.Info(fmt.Sprintf("Image pushed: %s", pinned))
+	setImageOutputs(pinned)
+
+	return pinned.String(), nil
+}
+
+// registryKeychain returns the keychain authenticating to Container Registry with the IAM token of the SDK.
+func registryKeychain(ctx context.Context, sdk *ycsdk.SDK) (authn.Keychain, error) {
+	token, err := sdk.CreateIAMToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IAM token: %w", err)
+	}
+
+	return ociimage.IAMKeychain(token.IamToken), nil
+}
+
+// setImageOutputs sets the digest-pinned image URL and the digest of the deployed image as outputs.
+func setImageOutputs(pinned name.Digest) {
+	sourcecraft.SetOutput("IMAGE_URL", pinned.String())
+	sourcecraft.SetOutput("IMAGE_DIGEST", pinned.DigestStr())
+}
+
+// pinImage checks that the image exists and returns its reference pinned to the digest, so that
+// the revision doesn't change if the tag is moved.
+func pinImage(ctx context.Context, sdk *ycsdk.SDK, imageURL string) (string, error) {
+	ref, err := ociimage.ParseReference(imageURL)
+	if err != nil {
+		return "", err
+	}
+
+	keychain, err := registryKeychain(ctx, sdk)
+	if err != nil {
+		return "", err
+	}
+
+	pinned, err := ociimage.Resolve(ctx, ref, keychain)
+	if err != nil {
+		return "", err
+	}
+
+	sourcecraft.Info(fmt.Sprintf("Image %s resolved to %s", imageURL, pinned))
+	setImageOutputs(pinned)
+
+	return pinned.String(), nil
+}
@@ -2,267 +2,235 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/yandex-cloud/go-genproto/yandex/cloud/access"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/operation"
 	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/containers/v1"
 	ycsdk "github.com/yandex-cloud/go-sdk"
-	"github.com/yandex-cloud/go-sdk/iamkey"
 	"github.com/yc-actions/sourcecraft-actions/internal/container"
+	"github.com/yc-actions/sourcecraft-actions/pkg/accessbinding"
+	"github.com/yc-actions/sourcecraft-actions/pkg/auth"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
+	"github.com/yc-actions/sourcecraft-actions/pkg/serviceaccount"
+	"github.com/yc-actions/sourcecraft-actions/pkg/smoke"
 	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
-	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/proto"
 )
 
 // Input constants.
 const (
-	inputFolderID            = "FOLDER_ID"
-	inputContainerName       = "CONTAINER_NAME"
-	inputPublic              = "PUBLIC"
-	inputYcSaJsonCredentials = "YC_SA_JSON_CREDENTIALS"
-	inputYcIamToken          = "YC_IAM_TOKEN"
+	inputFolderID      = "FOLDER_ID"
+	inputContainerName = "CONTAINER_NAME"
+	inputPublic        = "PUBLIC"
+	inputMode          = "MODE"
+	inputTraffic       = "TRAFFIC"
+	inputRollbackRevID = "ROLLBACK_REVISION_ID"
 )
 
-// createRevision creates a new revision for a container.
-func createRevision(
-	ctx context.Context,
-	sdk *ycsdk.SDK,
-	options *container.CreateRevisionOptions,
-) (string, error) {
-	// Create the request
-	req := &containers.DeployContainerRevisionRequest{
-		ContainerId: options.ContainerID,
-		Resources: &containers.Resources{
-			Memory:       options.MemoryValue,
-			Cores:        options.Cores,
-			CoreFraction: options.CoreFraction,
-		},
-		ExecutionTimeout: &durationpb.Duration{Seconds: options.ExecutionTimeout},
-		Concurrency:      options.Concurrency,
-		ServiceAccountId: options.ServiceAccountID,
-	}
-
-	// Set image spec
-	req.ImageSpec = &containers.ImageSpec{
-		ImageUrl:   options.ImageURL,
-		WorkingDir: options.WorkingDir,
-	}
+// waitOperation returns the function waiting for operations of the SDK.
+func waitOperation(sdk *ycsdk.SDK) container.WaitFunc {
+	return func(ctx context.Context, op *operation.Operation) (proto.Message, error) {
+		wrapped, err := sdk.WrapOperation(op, nil)
+		if err != nil {
+			return nil, err
+		}
 
-	// Set commands if provided
-	if len(options.Commands) > 0 {
-		// Create a Command struct with the commands
-		req.ImageSpec.Command = &containers.Command{
-			Command: options.Commands,
+		err = wrapped.Wait(ctx)
+		if err != nil {
+			return nil, err
 		}
+
+		return wrapped.Response()
 	}
+}
 
-	// Set args if provided
-	if len(options.Args) > 0 {
-		// Create an Args struct with the args
-		req.ImageSpec.Args = &containers.Args{
-			Args: options.Args,
-		}
+// newDeployer creates the deployer over the Containers service of the SDK.
+func newDeployer(sdk *ycsdk.SDK) *container.Deployer {
+	return &container.Deployer{
+		Client: sdk.Serverless().Containers().Container(),
+		Wait:   waitOperation(sdk),
+		ResolveServiceAccount: func(ctx context.Context, folderID, serviceAccountID, serviceAccountName string) (string, error) {
+			return serviceaccount.ResolveID(ctx, sdk, folderID, serviceAccountID, serviceAccountName)
+		},
 	}
+}
 
-	// Set environment variables if provided
-	if len(options.Env) > 0 {
-		req.ImageSpec.Environment = options.Env
+// declaredAccessBindings returns the declared access bindings of the container, with the public
+// binding if PUBLIC is set, and whether they are authoritative.
+func declaredAccessBindings(isPublic bool) ([]*access.AccessBinding, bool, error) {
+	bindings, err := accessbinding.InputBindings(accessbinding.ContainerInvokerRole)
+	if err != nil {
+		return nil, false, err
 	}
 
-	// Set network ID if provided
-	if options.NetworkID != "" {
-		req.Connectivity = &containers.Connectivity{
-			NetworkId: options.NetworkID,
-		}
+	if isPublic {
+		bindings = append(bindings, accessbinding.PublicBinding(accessbinding.ContainerInvokerRole))
 	}
 
-	// Set provisioned instances if provided
-	if options.Provisioned != nil {
-		req.ProvisionPolicy = &containers.ProvisionPolicy{
-			MinInstances: *options.Provisioned,
-		}
+	return bindings, accessbinding.Declared(), nil
+}
+
+// planContainer publishes the plan of deploying the container revision.
+func planContainer(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	deployer *container.Deployer,
+	deployment *container.Deployment,
+	layered *layeredImage,
+	bindings []*access.AccessBinding,
+	pruneBindings bool,
+) error {
+	p := plan.New("container")
+
+	if layered != nil {
+		p.Add(layered.description())
 	}
 
-	// Set secrets if provided
-	if len(options.Secrets) > 0 {
-		req.Secrets = make([]*containers.Secret, 0, len(options.Secrets))
-		for _, secret := range options.Secrets {
-			req.Secrets = append(req.Secrets, &containers.Secret{
-				Id:        secret.SecretID,
-				VersionId: secret.VersionID,
-				Key:       secret.Key,
-				Reference: &containers.Secret_EnvironmentVariable{
-					EnvironmentVariable: secret.EnvironmentVariable,
-				},
-			})
-		}
+	containerID, err := deployer.FindContainer(ctx, deployment.FolderID, deployment.ContainerName)
+	if err != nil {
+		return err
 	}
 
-	// Set log options if provided
-	if options.LogOptions != nil {
-		req.LogOptions = &containers.LogOptions{
-			Disabled: options.LogOptions.Disabled,
-			MinLevel: options.LogOptions.MinLevel,
-		}
+	var previousRevisionID string
 
-		if options.LogOptions.LogGroupID != "" {
-			req.LogOptions.Destination = &containers.LogOptions_LogGroupId{
-				LogGroupId: options.LogOptions.LogGroupID,
-			}
-		} else if options.LogOptions.FolderID != "" {
-			req.LogOptions.Destination = &containers.LogOptions_FolderId{
-				FolderId: options.LogOptions.FolderID,
-			}
+	if containerID == "" {
+		err = p.AddRequest(
+			fmt.Sprintf("Create container '%s'", deployment.ContainerName),
+			container.NewCreateContainerRequest(deployment.FolderID, deployment.ContainerName),
+		)
+		if err != nil {
+			return err
 		}
-	}
-
-	// Set storage mounts if provided
-	if len(options.StorageMounts) > 0 {
-		req.StorageMounts = make([]*containers.StorageMount, 0, len(options.StorageMounts))
-		for _, mount := range options.StorageMounts {
-			req.StorageMounts = append(req.StorageMounts, &containers.StorageMount{
-				BucketId:       mount.BucketID,
-				Prefix:         mount.Prefix,
-				MountPointPath: mount.MountPointPath,
-				ReadOnly:       mount.ReadOnly,
-			})
+	} else {
+		previousRevisionID, err = deployer.FindActiveRevision(ctx, containerID)
+		if err != nil {
+			return err
 		}
 	}
 
-	// Create the revision and wrap the operation
-	op, err := sdk.WrapOperation(
-		sdk.Serverless().Containers().Container().DeployRevision(ctx, req),
-	)
+	err = deployer.ResolveServiceAccounts(ctx, deployment.FolderID, deployment.Options)
 	if err != nil {
-		return "", fmt.Errorf("failed to create revision: %w", err)
+		return err
 	}
 
-	// Wait for the operation to complete
-	err = op.Wait(ctx)
+	deployment.Options.ContainerID = containerID
+
+	err = p.AddRequest(
+		fmt.Sprintf("Deploy revision of container '%s' (%s)", deployment.ContainerName, containerID),
+		container.NewRevisionRequest(deployment.Options),
+	)
 	if err != nil {
-		return "", fmt.Errorf("failed to wait for operation: %w", err)
+		return err
 	}
 
-	// Get the created revision
-	result, err := op.Response()
+	request, err := accessbinding.NewUpdateRequest(
+		ctx,
+		sdk.Serverless().Containers().Container(),
+		containerID,
+		bindings,
+		pruneBindings,
+	)
 	if err != nil {
-		return "", fmt.Errorf("failed to get operation response: %w", err)
+		return err
 	}
 
-	// Extract the revision ID
-	revision, ok := result.(*containers.Revision)
-	if !ok {
-		return "", fmt.Errorf("unexpected response type: %T", result)
+	if request != nil {
+		err = p.AddRequest(
+			fmt.Sprintf("Update access bindings of container '%s'", deployment.ContainerName),
+			request,
+		)
+		if err != nil {
+			return err
+		}
 	}
 
-	return revision.Id, nil
-}
+	sourcecraft.SetOutput("PREVIOUS_REVISION_ID", previousRevisionID)
 
-// createContainer creates a new container.
-func createContainer(ctx context.Context, sdk *ycsdk.SDK, folderID, name string) (string, error) {
-	// Get repository info for description
-	repoOwner := sourcecraft.GetSourcecraftRepositoryOwner()
-	repoName := sourcecraft.GetSourcecraftRepository()
+	return p.Publish()
+}
 
-	// Create container
-	req := &containers.CreateContainerRequest{
-		FolderId:    folderID,
-		Name:        name,
-		Description: fmt.Sprintf("Created from: %s/%s", repoOwner, repoName),
-	}
+// publishDeployment sets the outputs of the deployment and updates the access bindings of the container.
+func publishDeployment(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	result *container.DeployResult,
+	bindings []*access.AccessBinding,
+	pruneBindings bool,
+) error {
+	sourcecraft.SetOutput("CONTAINER_ID", result.ContainerID)
+	sourcecraft.SetOutput("REVISION_ID", result.RevisionID)
+	sourcecraft.SetOutput("PREVIOUS_REVISION_ID", result.PreviousRevisionID)
 
-	// Create the container and wrap the operation
-	op, err := sdk.WrapOperation(
-		sdk.Serverless().Containers().Container().Create(ctx, req),
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to create container: %w", err)
+	if len(bindings) == 0 {
+		return nil
 	}
 
-	// Wait for the operation to complete
-	err = op.Wait(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to wait for operation: %w", err)
-	}
+	sourcecraft.Info(fmt.Sprintf("Updating access bindings of container %s", result.ContainerID))
 
-	// Get the created container
-	result, err := op.Response()
+	err := accessbinding.Reconcile(
+		ctx,
+		sdk,
+		sdk.Serverless().Containers().Container(),
+		result.ContainerID,
+		bindings,
+		pruneBindings,
+	)
 	if err != nil {
-		return "", fmt.Errorf("failed to get operation response: %w", err)
+		return fmt.Errorf("failed to update access bindings: %w", err)
 	}
 
-	// Extract the container ID
-	c, ok := result.(*containers.Container)
-	if !ok {
-		return "", fmt.Errorf("unexpected response type: %T", result)
-	}
-
-	return c.Id, nil
+	return nil
 }
 
-// findContainerByName finds a container by name.
-func findContainerByName(
+// smokeTestContainer calls the container URL and checks the response.
+// If the check fails and rollback is requested, the previous revision is restored.
+func smokeTestContainer(
 	ctx context.Context,
 	sdk *ycsdk.SDK,
-	folderID, name string,
-) (string, error) {
-	// Create a filter to find the container by name
-	filter := fmt.Sprintf("name = \"%s\"", name)
-
-	// List containers with the filter
-	resp, err := sdk.Serverless().
-		Containers().
-		Container().
-		List(ctx, &containers.ListContainersRequest{
-			FolderId: folderID,
-			Filter:   filter,
-		})
+	deployer *container.Deployer,
+	check *smoke.Check,
+	containerID, previousRevisionID string,
+) error {
+	sourcecraft.StartGroup("Smoke test")
+	defer sourcecraft.EndGroup()
+
+	c, err := deployer.Client.Get(ctx, &containers.GetContainerRequest{
+		ContainerId: containerID,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to list containers: %w", err)
+		return fmt.Errorf("failed to get container: %w", err)
 	}
 
-	// Check if any containers were found
-	if len(resp.Containers) == 0 {
-		return "", nil
+	target, err := check.URL(c.Url, nil)
+	if err != nil {
+		return err
 	}
 
-	// Return the ID of the first matching container
-	return resp.Containers[0].Id, nil
-}
-
-// makeContainerPublic sets the access bindings for a container to make it publicly accessible.
-func makeContainerPublic(ctx context.Context, sdk *ycsdk.SDK, containerID string) error {
-	// Create the access binding for allUsers
-	binding := &access.AccessBinding{
-		RoleId: "serverless.containers.invoker",
-		Subject: &access.Subject{
-			Id:   "allUsers",
-			Type: "system",
-		},
+	token, err := check.Token(ctx, sdk)
+	if err != nil {
+		return err
 	}
 
-	// Create the request to set access bindings
-	req := &access.SetAccessBindingsRequest{
-		ResourceId: containerID,
-		AccessBindings: []*access.AccessBinding{
-			binding,
-		},
+	err = check.Run(ctx, http.DefaultClient, target, token)
+	if err == nil || !check.Rollback {
+		return err
 	}
 
-	// Call the API to set access bindings
-	op, err := sdk.WrapOperation(
-		sdk.Serverless().Containers().Container().SetAccessBindings(ctx, req),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to set access bindings: %w", err)
+	if previousRevisionID == "" {
+		return fmt.Errorf("%w; there is no previous revision to roll back to", err)
 	}
 
-	// Wait for the operation to complete
-	err = op.Wait(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to wait for operation: %w", err)
+	rollbackErr := deployer.Rollback(ctx, containerID, previousRevisionID)
+	if rollbackErr != nil {
+		return errors.Join(err, rollbackErr)
 	}
 
-	return nil
+	sourcecraft.SetOutput("REVISION_ID", previousRevisionID)
+
+	return fmt.Errorf("%w; rolled back to revision %s", err, previousRevisionID)
 }
 
 func main() {
@@ -285,148 +253,148 @@ func main() {
 		return
 	}
 
-	// Get authentication credentials
-	ycSaJsonCredentials := sourcecraft.GetInput(inputYcSaJsonCredentials)
-	ycIamToken := sourcecraft.GetInput(inputYcIamToken)
+	// Parse mode
+	mode := sourcecraft.GetInput(inputMode)
+	if mode == "" {
+		mode = container.ModeDeploy
+	}
 
-	// Check if we have valid credentials
-	if ycSaJsonCredentials == "" && ycIamToken == "" {
-		sourcecraft.SetFailed("No credentials provided")
+	rollbackRevisionID := sourcecraft.GetInput(inputRollbackRevID)
 
-		return
-	}
+	err := container.ValidateMode(mode, rollbackRevisionID)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid mode: %v", err))
 
-	// Log which credentials we're using
-	if ycSaJsonCredentials != "" {
-		sourcecraft.Info("Using service account JSON credentials")
-	} else {
-		sourcecraft.Info("Using IAM token")
+		return
 	}
 
-	// Create SDK
-	var sdk *ycsdk.SDK
-
-	var err error
+	// Parse the layered image, invalid files are rejected before any request is made
+	var layered *layeredImage
 
-	if ycSaJsonCredentials != "" {
-		// Create credentials from service account JSON
-		key, err := iamkey.ReadFromJSONBytes([]byte(ycSaJsonCredentials))
+	if mode == container.ModeDeploy {
+		layered, err = parseLayeredImage()
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to read service account JSON: %v", err))
+			sourcecraft.SetFailed(fmt.Sprintf("Invalid image: %v", err))
 
 			return
 		}
+	}
 
-		credentials, err := ycsdk.ServiceAccountKey(key)
-		if err != nil {
-			sourcecraft.SetFailed(
-				fmt.Sprintf("Failed to create credentials from service account JSON: %v", err),
-			)
+	// Create SDK
+	sdk, err := auth.NewSDK(ctx)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
 
-			return
-		}
+		return
+	}
 
-		// Create SDK
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: credentials,
-		})
+	if mode == container.ModeRollback {
+		err = rollbackToRevision(ctx, newDeployer(sdk), folderID, containerName, rollbackRevisionID)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
-
-			return
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to roll back container: %v", err))
 		}
-	} else {
-		// Create SDK with IAM token
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: ycsdk.NewIAMTokenCredentials(ycIamToken),
-		})
-		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
 
-			return
-		}
+		return
 	}
 
-	revOptions, err := container.ParseRevOptions()
+	// Validate traffic of the new revision
+	err = container.ValidateTraffic(sourcecraft.GetInput(inputTraffic))
 	if err != nil {
-		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse revision options: %v", err))
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid traffic: %v", err))
 
 		return
 	}
 
-	// Parse public flag
-	isPublic := sourcecraft.GetBooleanInput(inputPublic)
+	var layeredImageURL string
+	if layered != nil {
+		layeredImageURL = layered.tag.String()
+	}
+
+	revOptions, err := container.ParseRevOptionsWithImage(layeredImageURL)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse revision options: %v", err))
+
+		return
+	}
 
-	// Create a container object to store the results
-	var containerID string
+	// Pin the image to its digest, a layered image is pinned once it's pushed
+	if layered == nil {
+		revOptions.ImageURL, err = pinImage(ctx, sdk, revOptions.ImageURL)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to resolve image: %v", err))
 
-	var revisionID string
+			return
+		}
+	}
 
-	// Find the container by name
-	containerID, err = findContainerByName(ctx, sdk, folderID, containerName)
+	// Parse access bindings
+	bindings, pruneBindings, err := declaredAccessBindings(sourcecraft.GetBooleanInput(inputPublic))
 	if err != nil {
-		sourcecraft.SetFailed(fmt.Sprintf("Failed to find container: %v", err))
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse access bindings: %v", err))
 
 		return
 	}
 
+	// Parse smoke test
+	var check *smoke.Check
+	if smoke.Enabled() {
+		check, err = smoke.InputCheck()
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Invalid smoke test: %v", err))
+
+			return
+		}
+	}
+
 	revOptions.Log()
 
-	if containerID == "" {
-		// Container does not exist, create a new one
-		sourcecraft.Info(
-			fmt.Sprintf("There is no container with name: %s. Creating a new one.", containerName),
-		)
+	deployer := newDeployer(sdk)
+	deployment := &container.Deployment{
+		FolderID:      folderID,
+		ContainerName: containerName,
+		Options:       revOptions,
+	}
+
+	if plan.DryRun() {
+		err = planContainer(ctx, sdk, deployer, deployment, layered, bindings, pruneBindings)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to plan container deployment: %v", err))
+		}
+
+		return
+	}
 
-		containerID, err = createContainer(ctx, sdk, folderID, containerName)
+	// Deploy the image pinned to the digest of the pushed layered image
+	if layered != nil {
+		revOptions.ImageURL, err = layered.run(ctx, sdk)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create container: %v", err))
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to layer image: %v", err))
 
 			return
 		}
-
-		sourcecraft.Info(fmt.Sprintf("Container successfully created. Id: %s", containerID))
-	} else {
-		// Container exists, update it
-		sourcecraft.Info(
-			fmt.Sprintf(
-				"Container with name: %s already exists and has id: %s",
-				containerName,
-				containerID,
-			),
-		)
 	}
 
-	// Create a new revision
-	revisionID, err = createRevision(
-		ctx,
-		sdk,
-		revOptions,
-	)
+	result, err := deployer.Deploy(ctx, deployment)
 	if err != nil {
-		sourcecraft.SetFailed(fmt.Sprintf("Failed to create revision: %v", err))
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to deploy revision: %v", err))
 
 		return
 	}
 
-	sourcecraft.Info(fmt.Sprintf("Revision created successfully. Id: %s", revisionID))
-
-	// Set outputs
-	sourcecraft.SetOutput("CONTAINER_ID", containerID)
-	sourcecraft.SetOutput("REVISION_ID", revisionID)
+	err = publishDeployment(ctx, sdk, result, bindings, pruneBindings)
+	if err != nil {
+		sourcecraft.SetFailed(err.Error())
 
-	// Make the container public if requested
-	if isPublic {
-		sourcecraft.Info(fmt.Sprintf("Making container %s public", containerID))
+		return
+	}
 
-		// Call the API to make the container public
-		err = makeContainerPublic(ctx, sdk, containerID)
+	// Check the new revision
+	if check != nil {
+		err = smokeTestContainer(ctx, sdk, deployer, check, result.ContainerID, result.PreviousRevisionID)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to make container public: %v", err))
+			sourcecraft.SetFailed(fmt.Sprintf("Smoke test failed: %v", err))
 
 			return
 		}
-
-		sourcecraft.Info("Container is public now")
 	}
 }
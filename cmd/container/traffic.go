@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/containers/v1"
+	"github.com/yc-actions/sourcecraft-actions/internal/container"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// rollbackToRevision runs the rollback mode: the revision becomes the serving one again,
+// or the plan of it is published in dry-run mode.
+func rollbackToRevision(
+	ctx context.Context,
+	deployer *container.Deployer,
+	folderID, containerName, revisionID string,
+) error {
+	sourcecraft.StartGroup(fmt.Sprintf("Roll back container '%s'", containerName))
+	defer sourcecraft.EndGroup()
+
+	containerID, err := deployer.FindContainer(ctx, folderID, containerName)
+	if err != nil {
+		return err
+	}
+
+	if containerID == "" {
+		return fmt.Errorf("there is no container named '%s' in the folder", containerName)
+	}
+
+	revision, err := deployer.Client.GetRevision(
+		ctx,
+		&containers.GetContainerRevisionRequest{ContainerRevisionId: revisionID},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get revision %s: %w", revisionID, err)
+	}
+
+	if revision.ContainerId != containerID {
+		return fmt.Errorf("revision %s belongs to another container %s", revision.Id, revision.ContainerId)
+	}
+
+	previousRevisionID, err := deployer.FindActiveRevision(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
+	sourcecraft.SetOutput("CONTAINER_ID", containerID)
+	sourcecraft.SetOutput("PREVIOUS_REVISION_ID", previousRevisionID)
+
+	if previousRevisionID == revisionID {
+		sourcecraft.Info(fmt.Sprintf("Revision %s is already serving", revisionID))
+		sourcecraft.SetOutput("REVISION_ID", revisionID)
+
+		return nil
+	}
+
+	if plan.DryRun() {
+		p := plan.New("container")
+
+		err = p.AddRequest(
+			fmt.Sprintf("Roll back container '%s' from revision %s", containerName, previousRevisionID),
+			container.NewRollbackRequest(containerID, revisionID),
+		)
+		if err != nil {
+			return err
+		}
+
+		return p.Publish()
+	}
+
+	err = deployer.Rollback(ctx, containerID, revisionID)
+	if err != nil {
+		return err
+	}
+
+	sourcecraft.SetOutput("REVISION_ID", revisionID)
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+	"github.com/yc-actions/sourcecraft-actions/pkg/accessbinding"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// reconcileAccessBindings makes the access bindings of the function match the declared ones.
+func reconcileAccessBindings(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	functionID string,
+	inputs *function.ActionInputs,
+) error {
+	if len(inputs.AccessBindings) == 0 {
+		return nil
+	}
+
+	sourcecraft.StartGroup("Update access bindings")
+	defer sourcecraft.EndGroup()
+
+	return accessbinding.Reconcile(
+		ctx,
+		sdk,
+		sdk.Serverless().Functions().Function(),
+		functionID,
+		inputs.AccessBindings,
+		true,
+	)
+}
+
+// addAccessBindingsPlan adds updating the access bindings of the function to the plan.
+func addAccessBindingsPlan(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	p *plan.Plan,
+	functionID string,
+	inputs *function.ActionInputs,
+) error {
+	if len(inputs.AccessBindings) == 0 {
+		return nil
+	}
+
+	request, err := accessbinding.NewUpdateRequest(
+		ctx,
+		sdk.Serverless().Functions().Function(),
+		functionID,
+		inputs.AccessBindings,
+		true,
+	)
+	if err != nil || request == nil {
+		return err
+	}
+
+	return p.AddRequest(fmt.Sprintf("Update access bindings of function '%s'", inputs.FunctionName), request)
+}
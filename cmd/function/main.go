@@ -1,232 +1,164 @@
 package main
 
 import (
-	"archive/zip"
-	"bytes"
+	"compress/flate"
 	"context"
 	"fmt"
-	"io"
 	"os"
-	"path/filepath"
+	"slices"
 
 	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
 	ycsdk "github.com/yandex-cloud/go-sdk"
-	"github.com/yandex-cloud/go-sdk/iamkey"
+	"github.com/yc-actions/sourcecraft-actions/internal/container"
 	"github.com/yc-actions/sourcecraft-actions/internal/function"
+	"github.com/yc-actions/sourcecraft-actions/pkg/accessbinding"
+	"github.com/yc-actions/sourcecraft-actions/pkg/auth"
 	"github.com/yc-actions/sourcecraft-actions/pkg/env"
 	"github.com/yc-actions/sourcecraft-actions/pkg/loglevel"
 	"github.com/yc-actions/sourcecraft-actions/pkg/memory"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
 	"github.com/yc-actions/sourcecraft-actions/pkg/serviceaccount"
+	"github.com/yc-actions/sourcecraft-actions/pkg/smoke"
 	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
 	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 // Action inputs.
 const (
-	inputFolderID            = "FOLDER_ID"
-	inputFunctionName        = "FUNCTION_NAME"
-	inputRuntime             = "RUNTIME"
-	inputEntrypoint          = "ENTRYPOINT"
-	inputMemory              = "MEMORY"
-	inputInclude             = "INCLUDE"
-	inputExclude             = "EXCLUDE"
-	inputSourceRoot          = "SOURCE_ROOT"
-	inputExecutionTimeout    = "EXECUTION_TIMEOUT"
-	inputEnvironment         = "ENVIRONMENT"
-	inputServiceAccount      = "SERVICE_ACCOUNT"
-	inputServiceAccountName  = "SERVICE_ACCOUNT_NAME"
-	inputBucket              = "BUCKET"
-	inputDescription         = "DESCRIPTION"
-	inputSecrets             = "SECRETS"
-	inputNetworkID           = "NETWORK_ID"
-	inputTags                = "TAGS"
-	inputLogsDisabled        = "LOGS_DISABLED"
-	inputLogsGroupID         = "LOGS_GROUP_ID"
-	inputLogLevel            = "LOG_LEVEL"
-	inputAsync               = "ASYNC"
-	inputAsyncSaID           = "ASYNC_SA_ID"
-	inputAsyncSaName         = "ASYNC_SA_NAME"
-	inputAsyncRetriesCount   = "ASYNC_RETRIES_COUNT"
-	inputAsyncSuccessYmqArn  = "ASYNC_SUCCESS_YMQ_ARN"
-	inputAsyncSuccessSaID    = "ASYNC_SUCCESS_SA_ID"
-	inputAsyncFailureYmqArn  = "ASYNC_FAILURE_YMQ_ARN"
-	inputAsyncFailureSaID    = "ASYNC_FAILURE_SA_ID"
-	inputAsyncSuccessSaName  = "ASYNC_SUCCESS_SA_NAME"
-	inputAsyncFailureSaName  = "ASYNC_FAILURE_SA_NAME"
-	inputYcSaJsonCredentials = "YC_SA_JSON_CREDENTIALS"
-	inputYcIamToken          = "YC_IAM_TOKEN"
+	inputFolderID           = "FOLDER_ID"
+	inputFunctionName       = "FUNCTION_NAME"
+	inputRuntime            = "RUNTIME"
+	inputEntrypoint         = "ENTRYPOINT"
+	inputMemory             = "MEMORY"
+	inputInclude            = "INCLUDE"
+	inputExclude            = "EXCLUDE"
+	inputSourceRoot         = "SOURCE_ROOT"
+	inputCompressionLevel   = "COMPRESSION_LEVEL"
+	inputBuild              = "BUILD"
+	inputBuildCommand       = "BUILD_COMMAND"
+	inputExecutionTimeout   = "EXECUTION_TIMEOUT"
+	inputEnvironment        = "ENVIRONMENT"
+	inputServiceAccount     = "SERVICE_ACCOUNT"
+	inputServiceAccountName = "SERVICE_ACCOUNT_NAME"
+	inputBucket             = "BUCKET"
+	inputDescription        = "DESCRIPTION"
+	inputSecrets            = "SECRETS"
+	inputNetworkID          = "NETWORK_ID"
+	inputTags               = "TAGS"
+	inputLogsDisabled       = "LOGS_DISABLED"
+	inputLogsGroupID        = "LOGS_GROUP_ID"
+	inputLogsFolderID       = "LOGS_FOLDER_ID"
+	inputLogLevel           = "LOG_LEVEL"
+	inputConcurrency        = "CONCURRENCY"
+	inputTmpfsSize          = "TMPFS_SIZE"
+	inputProvisioned        = "PROVISIONED"
+	inputScalingPolicies    = "SCALING_POLICIES"
+	inputScalingPrune       = "SCALING_POLICIES_PRUNE"
+	inputStorageMounts      = "STORAGE_MOUNTS"
+	inputMounts             = "MOUNTS"
+	inputMetadataGce        = "METADATA_GCE_HTTP_ENDPOINT"
+	inputMetadataAwsV1      = "METADATA_AWS_V1_HTTP_ENDPOINT"
+	inputAsync              = "ASYNC"
+	inputAsyncSaID          = "ASYNC_SA_ID"
+	inputAsyncSaName        = "ASYNC_SA_NAME"
+	inputAsyncRetriesCount  = "ASYNC_RETRIES_COUNT"
+	inputAsyncSuccessYmqArn = "ASYNC_SUCCESS_YMQ_ARN"
+	inputAsyncSuccessSaID   = "ASYNC_SUCCESS_SA_ID"
+	inputAsyncFailureYmqArn = "ASYNC_FAILURE_YMQ_ARN"
+	inputAsyncFailureSaID   = "ASYNC_FAILURE_SA_ID"
+	inputAsyncSuccessSaName = "ASYNC_SUCCESS_SA_NAME"
+	inputAsyncFailureSaName = "ASYNC_FAILURE_SA_NAME"
+	inputMode               = "MODE"
+	inputVersion            = "VERSION"
+	inputSetTags            = "SET_TAGS"
+	inputRemoveTags         = "REMOVE_TAGS"
+	inputPromoteFrom        = "PROMOTE_FROM"
+	inputPromoteTo          = "PROMOTE_TO"
 )
 
-// parseIgnoreGlobPatterns parses ignore glob patterns from a string slice.
-func parseIgnoreGlobPatterns(patterns []string) []string {
-	var result []string
-
-	for _, pattern := range patterns {
-		if pattern != "" {
-			result = append(result, pattern)
-		}
+// packageObjectName returns the bucket object name of the function package for the current commit.
+func packageObjectName(functionID string) (string, error) {
+	// Get Sourcecraft SHA
+	sourcecraftSHA := sourcecraft.GetSourcecraftSHA()
+	if sourcecraftSHA == "" {
+		return "", fmt.Errorf("missing SOURCECRAFT_COMMIT_SHA")
 	}
 
-	sourcecraft.Info(fmt.Sprintf("Source ignore pattern: %q", result))
-
-	return result
+	return fmt.Sprintf("%s/%s.zip", functionID, sourcecraftSHA), nil
 }
 
-// zipSources zips source files.
-func zipSources(inputs *function.ActionInputs) ([]byte, error) {
-	sourcecraft.StartGroup("ZipDirectory")
-	defer sourcecraft.EndGroup()
-
-	// Create a buffer to write the zip file to
-	buf := new(bytes.Buffer)
-	zipWriter := zip.NewWriter(buf)
-
-	// Get workspace directory
-	workspace := sourcecraft.GetSourcecraftWorkspace()
-
-	// Get source root
-	root := filepath.Join(workspace, inputs.SourceRoot)
-
-	// Parse ignore patterns
-	patterns := parseIgnoreGlobPatterns(inputs.ExcludePattern)
-
-	// Add files to zip
-	for _, include := range inputs.Include {
-		if include == "" {
-			continue
-		}
-
-		pathFromSourceRoot := filepath.Join(root, include)
-
-		matches, err := filepath.Glob(pathFromSourceRoot)
-		if err != nil {
-			return nil, fmt.Errorf("failed to glob pattern: %w", err)
-		}
-
-		for _, match := range matches {
-			info, err := os.Stat(match)
-			if err != nil {
-				return nil, fmt.Errorf("failed to stat file: %w", err)
-			}
-
-			if info.IsDir() {
-				sourcecraft.Debug(fmt.Sprintf("match: dir %s", match))
-
-				err = filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-
-					// Skip directories
-					if info.IsDir() {
-						return nil
-					}
-
-					// Check if file matches any ignore pattern
-					relPath, err := filepath.Rel(root, path)
-					if err != nil {
-						return fmt.Errorf("failed to get relative path: %w", err)
-					}
-
-					for _, pattern := range patterns {
-						matched, err := filepath.Match(pattern, relPath)
-						if err != nil {
-							return fmt.Errorf("failed to match pattern: %w", err)
-						}
-
-						if matched {
-							return nil
-						}
-					}
-
-					// Add file to zip
-					return addFileToZip(zipWriter, path, relPath)
-				})
-				if err != nil {
-					return nil, fmt.Errorf("failed to walk directory: %w", err)
-				}
-			} else {
-				sourcecraft.Debug(fmt.Sprintf("match: file %s", match))
-
-				relPath, err := filepath.Rel(root, match)
-				if err != nil {
-					return nil, fmt.Errorf("failed to get relative path: %w", err)
-				}
-
-				err = addFileToZip(zipWriter, match, relPath)
-				if err != nil {
-					return nil, fmt.Errorf("failed to add file to zip: %w", err)
-				}
-			}
-		}
-	}
+// uploadToS3 uploads the package to S3.
+func uploadToS3(
+	ctx context.Context,
+	bucket, objectName string,
+	sdk *ycsdk.SDK,
+	pkg *function.Package,
+) error {
+	sourcecraft.Info(fmt.Sprintf("Upload to bucket: %q", bucket+"/"+objectName))
 
-	// Close the zip writer
-	err := zipWriter.Close()
+	// Create storage service
+	options, err := storage.InputOptions()
 	if err != nil {
-		return nil, fmt.Errorf("failed to close zip writer: %w", err)
+		return err
 	}
 
-	sourcecraft.Info("Archive finalized")
-	sourcecraft.Info(fmt.Sprintf("Buffer size: %d bytes", buf.Len()))
-
-	return buf.Bytes(), nil
-}
+	storageService := storage.NewStorageServiceWithOptions(sdk, options)
 
-// addFileToZip adds a file to a zip writer.
-func addFileToZip(zipWriter *zip.Writer, filePath, zipPath string) error {
-	file, err := os.Open(filePath)
+	file, err := pkg.Open()
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return fmt.Errorf("failed to open package: %w", err)
 	}
-	defer file.Close()
 
-	sourcecraft.Info(fmt.Sprintf("add: %s", zipPath))
+	// Create storage object
+	storageObject := storage.NewStorageObject(bucket, objectName, file)
+	defer storageObject.Close()
 
-	writer, err := zipWriter.Create(zipPath)
-	if err != nil {
-		return fmt.Errorf("failed to create entry in zip: %w", err)
+	storageObject.Size = pkg.Size
+	storageObject.OnProgress = func(uploaded, total int64) {
+		sourcecraft.Info(fmt.Sprintf("Uploaded %d of %d bytes", uploaded, total))
 	}
 
-	_, err = io.Copy(writer, file)
+	// Upload object
+	err = storageService.PutObject(ctx, storageObject)
 	if err != nil {
-		return fmt.Errorf("failed to write file to zip: %w", err)
+		return fmt.Errorf("failed to upload to S3: %w", err)
 	}
 
 	return nil
 }
 
-// uploadToS3 uploads a file to S3.
-func uploadToS3(
+// findFunctionID returns the ID of the function with the given name, or an empty string if there is none.
+func findFunctionID(
 	ctx context.Context,
-	bucket, functionID string,
 	sdk *ycsdk.SDK,
-	fileContents []byte,
+	inputs *function.ActionInputs,
 ) (string, error) {
-	// Get Sourcecraft SHA
-	sourcecraftSHA := sourcecraft.GetSourcecraftSHA()
-	if sourcecraftSHA == "" {
-		return "", fmt.Errorf("missing SOURCECRAFT_COMMIT_SHA")
+	// List functions
+	resp, err := sdk.Serverless().Functions().Function().List(ctx, &functions.ListFunctionsRequest{
+		FolderId: inputs.FolderID,
+		Filter:   fmt.Sprintf("name = '%s'", inputs.FunctionName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list functions: %w", err)
 	}
 
-	// Set object name
-	objectName := fmt.Sprintf("%s/%s.zip", functionID, sourcecraftSHA)
-	sourcecraft.Info(fmt.Sprintf("Upload to bucket: %q", bucket+"/"+objectName))
-
-	// Create storage service
-	storageService := storage.NewStorageService(sdk)
+	if len(resp.Functions) == 0 {
+		return "", nil
+	}
 
-	// Create storage object
-	storageObject := storage.NewStorageObject(bucket, objectName, io.NopCloser(bytes.NewReader(fileContents)))
+	return resp.Functions[0].Id, nil
+}
 
-	// Upload object
-	err := storageService.PutObject(ctx, storageObject)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload to S3: %w", err)
+// newCreateFunctionRequest builds the request creating the function.
+func newCreateFunctionRequest(inputs *function.ActionInputs) *functions.CreateFunctionRequest {
+	return &functions.CreateFunctionRequest{
+		FolderId:    inputs.FolderID,
+		Name:        inputs.FunctionName,
+		Description: inputs.Description,
 	}
-
-	return objectName, nil
 }
 
 // getOrCreateFunctionID gets or creates a function ID.
@@ -238,20 +170,13 @@ func getOrCreateFunctionID(
 	sourcecraft.StartGroup("Find function id")
 	defer sourcecraft.EndGroup()
 
-	// List functions
-	functionService := sdk.Serverless().Functions()
-
-	resp, err := functionService.Function().List(ctx, &functions.ListFunctionsRequest{
-		FolderId: inputs.FolderID,
-		Filter:   fmt.Sprintf("name = '%s'", inputs.FunctionName),
-	})
+	functionID, err := findFunctionID(ctx, sdk, inputs)
 	if err != nil {
-		return "", fmt.Errorf("failed to list functions: %w", err)
+		return "", err
 	}
 
 	// If function exists, return its ID
-	if len(resp.Functions) > 0 {
-		functionID := resp.Functions[0].Id
+	if functionID != "" {
 		sourcecraft.Info(
 			fmt.Sprintf(
 				"There is the function named '%s' in the folder already. Its id is '%s'",
@@ -266,11 +191,7 @@ func getOrCreateFunctionID(
 
 	// Otherwise create a new function
 	op, err := sdk.WrapOperation(
-		functionService.Function().Create(ctx, &functions.CreateFunctionRequest{
-			FolderId:    inputs.FolderID,
-			Name:        inputs.FunctionName,
-			Description: inputs.Description,
-		}),
+		sdk.Serverless().Functions().Function().Create(ctx, newCreateFunctionRequest(inputs)),
 	)
 	if err != nil {
 		return "", fmt.Errorf("failed to create function: %w", err)
@@ -295,7 +216,7 @@ func getOrCreateFunctionID(
 
 	createFunctionMetadata = meta.(*functions.CreateFunctionMetadata)
 
-	functionID := createFunctionMetadata.FunctionId
+	functionID = createFunctionMetadata.FunctionId
 	sourcecraft.Info(
 		fmt.Sprintf(
 			"There was no function named '%s' in the folder. So it was created. Id is '%s'",
@@ -308,22 +229,15 @@ func getOrCreateFunctionID(
 	return functionID, nil
 }
 
-// createFunctionVersion creates a function version.
-func createFunctionVersion(
+// newFunctionVersionRequest builds the request creating a function version.
+func newFunctionVersionRequest(
 	ctx context.Context,
 	sdk *ycsdk.SDK,
 	functionID string,
-	fileContents []byte,
+	pkg *function.Package,
 	bucketObjectName string,
 	inputs *function.ActionInputs,
-) error {
-	sourcecraft.StartGroup("Create function version")
-	defer sourcecraft.EndGroup()
-
-	sourcecraft.Info(fmt.Sprintf("Function '%s' %s", inputs.FunctionName, functionID))
-	sourcecraft.Info(fmt.Sprintf("Parsed memory: %d", inputs.Memory))
-	sourcecraft.Info(fmt.Sprintf("Parsed timeout: %d", inputs.ExecutionTimeout))
-
+) (*functions.CreateFunctionVersionRequest, error) {
 	// Resolve service account ID
 	serviceAccountID, err := serviceaccount.ResolveID(
 		ctx,
@@ -333,7 +247,7 @@ func createFunctionVersion(
 		inputs.ServiceAccountName,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to resolve service account: %w", err)
+		return nil, fmt.Errorf("failed to resolve service account: %w", err)
 	}
 
 	// Create request
@@ -346,24 +260,49 @@ func createFunctionVersion(
 		Description:      inputs.Description,
 		Environment:      env.ParseEnvironmentVariables(inputs.Environment),
 		ExecutionTimeout: &durationpb.Duration{Seconds: int64(inputs.ExecutionTimeout)},
-		Tag:              inputs.Tags,
+		Tag:              append(slices.Clone(inputs.Tags), function.PackageTag(pkg.SHA256)),
 		Connectivity: &functions.Connectivity{
 			NetworkId: inputs.NetworkID,
 		},
 		LogOptions: &functions.LogOptions{
 			Disabled: inputs.LogsDisabled,
-			Destination: &functions.LogOptions_LogGroupId{
-				LogGroupId: inputs.LogsGroupID,
-			},
 			MinLevel: inputs.LogLevel,
 		},
+		Concurrency:     inputs.Concurrency,
+		TmpfsSize:       inputs.TmpfsSize,
+		MetadataOptions: function.MetadataOptions(inputs.MetadataOptions),
+	}
+
+	if inputs.LogsFolderID != "" {
+		request.LogOptions.Destination = &functions.LogOptions_FolderId{
+			FolderId: inputs.LogsFolderID,
+		}
+	} else {
+		request.LogOptions.Destination = &functions.LogOptions_LogGroupId{
+			LogGroupId: inputs.LogsGroupID,
+		}
+	}
+
+	// Set up storage mounts and mounts
+	if len(inputs.StorageMounts) > 0 {
+		request.StorageMounts, err = function.StorageMounts(inputs.StorageMounts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage mount: %w", err)
+		}
+	}
+
+	if len(inputs.Mounts) > 0 {
+		request.Mounts, err = function.Mounts(inputs.Mounts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mount: %w", err)
+		}
 	}
 
 	if function.IsAsync(inputs) {
 		// Set up async invocation config
 		asyncConfig, err := function.CreateAsyncInvocationConfig(ctx, sdk, inputs)
 		if err != nil {
-			return fmt.Errorf("failed to create async invocation config: %w", err)
+			return nil, fmt.Errorf("failed to create async invocation config: %w", err)
 		}
 
 		request.AsyncInvocationConfig = asyncConfig
@@ -394,15 +333,89 @@ func createFunctionVersion(
 	} else {
 		const limit = 3670016 // 3.5 MB
 
-		if len(fileContents) > limit {
-			return fmt.Errorf("zip file is too big: %d bytes. Provide bucket name", len(fileContents))
+		if pkg.Size > limit {
+			return nil, fmt.Errorf("zip file is too big: %d bytes. Provide bucket name", pkg.Size)
+		}
+
+		content, err := os.ReadFile(pkg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package: %w", err)
 		}
 
 		request.PackageSource = &functions.CreateFunctionVersionRequest_Content{
-			Content: fileContents,
+			Content: content,
 		}
 	}
 
+	return request, nil
+}
+
+// validateRuntime checks the runtime against the runtimes supported by Cloud Functions.
+func validateRuntime(ctx context.Context, sdk *ycsdk.SDK, runtime string) error {
+	resp, err := sdk.Serverless().Functions().Function().ListRuntimes(ctx, &functions.ListRuntimesRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to list runtimes: %w", err)
+	}
+
+	return function.ValidateRuntime(runtime, resp.Runtimes)
+}
+
+// findLatestVersion returns the $latest version of the function, if there is one.
+func findLatestVersion(ctx context.Context, sdk *ycsdk.SDK, functionID string) (*functions.Version, bool, error) {
+	version, err := sdk.Serverless().Functions().Function().GetVersionByTag(
+		ctx,
+		&functions.GetFunctionVersionByTagRequest{
+			FunctionId: functionID,
+			Tag:        function.LatestTag,
+		},
+	)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get latest function version: %w", err)
+	}
+
+	return version, true, nil
+}
+
+// findUnchangedVersion returns the $latest version if the request would create the same version.
+func findUnchangedVersion(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	request *functions.CreateFunctionVersionRequest,
+) (*functions.Version, bool, error) {
+	latest, found, err := findLatestVersion(ctx, sdk, request.FunctionId)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	if function.FindPackageTag(latest.GetTags()) == "" {
+		sourcecraft.Info(
+			fmt.Sprintf("Version %s has no package tag, so it can't be compared and a new version is created", latest.Id),
+		)
+
+		return latest, false, nil
+	}
+
+	return latest, function.VersionUnchanged(latest, request), nil
+}
+
+// createFunctionVersion creates a function version.
+func createFunctionVersion(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	request *functions.CreateFunctionVersionRequest,
+	inputs *function.ActionInputs,
+) error {
+	sourcecraft.StartGroup("Create function version")
+	defer sourcecraft.EndGroup()
+
+	sourcecraft.Info(fmt.Sprintf("Function '%s' %s", inputs.FunctionName, request.FunctionId))
+	sourcecraft.Info(fmt.Sprintf("Parsed memory: %d", inputs.Memory))
+	sourcecraft.Info(fmt.Sprintf("Parsed timeout: %d", inputs.ExecutionTimeout))
+
 	// Create function version
 	functionService := sdk.Serverless().Functions()
 
@@ -437,61 +450,210 @@ func createFunctionVersion(
 	return nil
 }
 
-func main() {
-	ctx := context.Background()
+// planFunction publishes the plan of deploying the function version.
+func planFunction(ctx context.Context, sdk *ycsdk.SDK, pkg *function.Package, inputs *function.ActionInputs) error {
+	p := plan.New("function")
 
-	// Parse inputs
-	ycSaJsonCredentials := sourcecraft.GetInput(inputYcSaJsonCredentials)
-	ycIamToken := sourcecraft.GetInput(inputYcIamToken)
+	functionID, err := findFunctionID(ctx, sdk, inputs)
+	if err != nil {
+		return err
+	}
 
-	// Create SDK
-	var sdk *ycsdk.SDK
+	if functionID == "" {
+		err = p.AddRequest(
+			fmt.Sprintf("Create function '%s'", inputs.FunctionName),
+			newCreateFunctionRequest(inputs),
+		)
+		if err != nil {
+			return err
+		}
+	}
 
-	var err error
+	var bucketObjectName string
+	if inputs.Bucket != "" {
+		bucketObjectName, err = packageObjectName(functionID)
+		if err != nil {
+			return err
+		}
+	}
+
+	request, err := newFunctionVersionRequest(ctx, sdk, functionID, pkg, bucketObjectName, inputs)
+	if err != nil {
+		return err
+	}
 
-	if ycSaJsonCredentials != "" {
-		// Create credentials
-		key, err := iamkey.ReadFromJSONBytes([]byte(ycSaJsonCredentials))
+	if functionID != "" {
+		latest, unchanged, err := findUnchangedVersion(ctx, sdk, request)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to read service account JSON: %v", err))
+			return err
+		}
 
-			return
+		if unchanged {
+			p.Add(fmt.Sprintf("Keep unchanged version %s of function '%s'", latest.Id, inputs.FunctionName))
+
+			err = addScalingPlan(ctx, sdk, p, functionID, inputs)
+			if err != nil {
+				return err
+			}
+
+			err = addAccessBindingsPlan(ctx, sdk, p, functionID, inputs)
+			if err != nil {
+				return err
+			}
+
+			return p.Publish()
 		}
+	}
 
-		credentials, err := ycsdk.ServiceAccountKey(key)
+	if inputs.Bucket != "" {
+		p.Add(
+			fmt.Sprintf(
+				"Upload package (%d bytes) to bucket: %q",
+				pkg.Size,
+				inputs.Bucket+"/"+bucketObjectName,
+			),
+		)
+	}
+
+	// Omit the inline package from the plan, it is only noted by size
+	description := fmt.Sprintf("Create version of function '%s' (%s)", inputs.FunctionName, functionID)
+	if inputs.Bucket == "" {
+		request.PackageSource = nil
+		description += fmt.Sprintf(" with inline package (%d bytes)", pkg.Size)
+	}
+
+	err = p.AddRequest(description, request)
+	if err != nil {
+		return err
+	}
+
+	err = addScalingPlan(ctx, sdk, p, functionID, inputs)
+	if err != nil {
+		return err
+	}
+
+	err = addAccessBindingsPlan(ctx, sdk, p, functionID, inputs)
+	if err != nil {
+		return err
+	}
+
+	return p.Publish()
+}
+
+// deployPackage deploys the packaged sources as a new version of the function unless it is unchanged.
+func deployPackage(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	pkg *function.Package,
+	inputs *function.ActionInputs,
+	check *smoke.Check,
+) error {
+	// Validate entrypoint
+	err := function.ValidateEntrypoint(pkg, inputs.Runtime, inputs.Entrypoint)
+	if err != nil {
+		return fmt.Errorf("invalid entrypoint: %w", err)
+	}
+
+	sourcecraft.SetOutput("PACKAGE_SHA256", pkg.SHA256)
+
+	if plan.DryRun() {
+		err = planFunction(ctx, sdk, pkg, inputs)
 		if err != nil {
-			sourcecraft.SetFailed(
-				fmt.Sprintf("Failed to create credentials from service account JSON: %v", err),
-			)
+			return fmt.Errorf("failed to plan function deployment: %w", err)
+		}
 
-			return
+		return nil
+	}
+
+	// Get or create function ID
+	functionID, err := getOrCreateFunctionID(ctx, sdk, inputs)
+	if err != nil {
+		return fmt.Errorf("failed to get or create function: %w", err)
+	}
+
+	// Set package object name if bucket is provided
+	var bucketObjectName string
+	if inputs.Bucket != "" {
+		bucketObjectName, err = packageObjectName(functionID)
+		if err != nil {
+			return fmt.Errorf("failed to upload to S3: %w", err)
 		}
+	}
 
-		// Create SDK
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: credentials,
-		})
+	request, err := newFunctionVersionRequest(ctx, sdk, functionID, pkg, bucketObjectName, inputs)
+	if err != nil {
+		return fmt.Errorf("failed to create function version: %w", err)
+	}
+
+	// Skip deployment if the latest version is the same
+	latest, unchanged, err := findUnchangedVersion(ctx, sdk, request)
+	if err != nil {
+		return fmt.Errorf("failed to compare with latest version: %w", err)
+	}
+
+	if unchanged {
+		sourcecraft.Info(fmt.Sprintf("Package and spec are unchanged, keeping version %s", latest.Id))
+		sourcecraft.SetOutput("VERSION_ID", latest.Id)
+		sourcecraft.SetOutput("CHANGED", "false")
+
+		err = reconcileScalingPolicies(ctx, sdk, functionID, inputs)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
+			return fmt.Errorf("failed to reconcile scaling policies: %w", err)
+		}
 
-			return
+		err = reconcileAccessBindings(ctx, sdk, functionID, inputs)
+		if err != nil {
+			return fmt.Errorf("failed to update access bindings: %w", err)
 		}
 
-		sourcecraft.Info("Parsed Service account JSON")
-	} else if ycIamToken != "" {
-		// Create SDK with IAM token
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: ycsdk.NewIAMTokenCredentials(ycIamToken),
-		})
+		return nil
+	}
+
+	// Upload to S3 if bucket is provided
+	if inputs.Bucket != "" {
+		err = uploadToS3(ctx, inputs.Bucket, bucketObjectName, sdk, pkg)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
+			return fmt.Errorf("failed to upload to S3: %w", err)
+		}
+	}
 
-			return
+	// Create function version
+	err = createFunctionVersion(ctx, sdk, request, inputs)
+	if err != nil {
+		return fmt.Errorf("failed to create function version: %w", err)
+	}
+
+	sourcecraft.SetOutput("CHANGED", "true")
+
+	// Reconcile scaling policies now that the version tags exist
+	err = reconcileScalingPolicies(ctx, sdk, functionID, inputs)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile scaling policies: %w", err)
+	}
+
+	err = reconcileAccessBindings(ctx, sdk, functionID, inputs)
+	if err != nil {
+		return fmt.Errorf("failed to update access bindings: %w", err)
+	}
+
+	// Check the new version
+	if check != nil {
+		err = smokeTestFunction(ctx, sdk, check, functionID, latest, request.Tag)
+		if err != nil {
+			return fmt.Errorf("smoke test failed: %w", err)
 		}
+	}
 
-		sourcecraft.Info("Using IAM token")
-	} else {
-		sourcecraft.SetFailed("No credentials")
+	return nil
+}
+
+func main() {
+	ctx := context.Background()
+
+	// Create SDK
+	sdk, err := auth.NewSDK(ctx)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
 
 		return
 	}
@@ -525,8 +687,67 @@ func main() {
 	// Parse async retries count
 	asyncRetriesCount := sourcecraft.GetIntInput(inputAsyncRetriesCount, 3)
 
+	// Parse tmpfs size
+	var tmpfsSize int64
+	if tmpfsSizeStr := sourcecraft.GetInput(inputTmpfsSize); tmpfsSizeStr != "" {
+		tmpfsSize, err = memory.ParseMemory(tmpfsSizeStr)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to parse tmpfs size: %v", err))
+
+			return
+		}
+	}
+
+	// Parse storage mounts and mounts
+	storageMounts, err := container.ParseStorageMounts(sourcecraft.GetMultilineInput(inputStorageMounts))
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse storage mounts: %v", err))
+
+		return
+	}
+
+	mounts, err := container.ParseMounts(sourcecraft.GetMultilineInput(inputMounts))
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse mounts: %v", err))
+
+		return
+	}
+
+	// Parse scaling policies
+	scalingPolicies, err := function.ParseScalingPolicies(sourcecraft.GetMultilineInput(inputScalingPolicies))
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse scaling policies: %v", err))
+
+		return
+	}
+
+	// Parse access bindings
+	accessBindings, err := accessbinding.InputBindings(accessbinding.FunctionInvokerRole)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse access bindings: %v", err))
+
+		return
+	}
+
+	// Parse metadata options
+	metadataOptions, err := container.ParseMetadataOptions(
+		sourcecraft.GetInput(inputMetadataGce),
+		sourcecraft.GetInput(inputMetadataAwsV1),
+	)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to parse metadata options: %v", err))
+
+		return
+	}
+
 	// Create inputs
 	inputs := &function.ActionInputs{
+		Mode:               sourcecraft.GetInput(inputMode),
+		Version:            sourcecraft.GetInput(inputVersion),
+		SetTags:            sourcecraft.GetMultilineInput(inputSetTags),
+		RemoveTags:         sourcecraft.GetMultilineInput(inputRemoveTags),
+		PromoteFrom:        sourcecraft.GetInput(inputPromoteFrom),
+		PromoteTo:          sourcecraft.GetInput(inputPromoteTo),
 		FolderID:           sourcecraft.GetInput(inputFolderID),
 		FunctionName:       sourcecraft.GetInput(inputFunctionName),
 		Runtime:            sourcecraft.GetInput(inputRuntime),
@@ -535,6 +756,9 @@ func main() {
 		Include:            sourcecraft.GetMultilineInputDefault(inputInclude, "."),
 		ExcludePattern:     sourcecraft.GetMultilineInput(inputExclude),
 		SourceRoot:         sourcecraft.GetInput(inputSourceRoot),
+		CompressionLevel:   sourcecraft.GetIntInput(inputCompressionLevel, flate.DefaultCompression),
+		Build:              sourcecraft.GetBooleanInput(inputBuild),
+		BuildCommand:       sourcecraft.GetInput(inputBuildCommand),
 		ExecutionTimeout:   executionTimeout,
 		Environment:        sourcecraft.GetMultilineInput(inputEnvironment),
 		ServiceAccount:     sourcecraft.GetInput(inputServiceAccount),
@@ -546,7 +770,17 @@ func main() {
 		Tags:               sourcecraft.GetMultilineInput(inputTags),
 		LogsDisabled:       sourcecraft.GetBooleanInput(inputLogsDisabled),
 		LogsGroupID:        sourcecraft.GetInput(inputLogsGroupID),
+		LogsFolderID:       sourcecraft.GetInput(inputLogsFolderID),
 		LogLevel:           logLevel,
+		Concurrency:        sourcecraft.GetInt64Input(inputConcurrency, 0),
+		TmpfsSize:          tmpfsSize,
+		Provisioned:        sourcecraft.GetInt64InputOpt(inputProvisioned),
+		ScalingPolicies:    scalingPolicies,
+		PruneScaling:       sourcecraft.GetBooleanInput(inputScalingPrune),
+		AccessBindings:     accessBindings,
+		StorageMounts:      storageMounts,
+		Mounts:             mounts,
+		MetadataOptions:    metadataOptions,
 		Async:              sourcecraft.GetBooleanInput(inputAsync),
 		AsyncSaID:          sourcecraft.GetInput(inputAsyncSaID),
 		AsyncSaName:        sourcecraft.GetInput(inputAsyncSaName),
@@ -559,6 +793,11 @@ func main() {
 		AsyncFailureSaName: sourcecraft.GetInput(inputAsyncFailureSaName),
 	}
 
+	// Set default mode if not provided
+	if inputs.Mode == "" {
+		inputs.Mode = function.ModeDeploy
+	}
+
 	// Set default source root if not provided
 	if inputs.SourceRoot == "" {
 		inputs.SourceRoot = "."
@@ -579,6 +818,23 @@ func main() {
 		return
 	}
 
+	err = function.ValidateMode(inputs)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid mode: %v", err))
+
+		return
+	}
+
+	// Manage tags of an existing version instead of deploying
+	if inputs.Mode != function.ModeDeploy {
+		err = manageVersion(ctx, sdk, inputs)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to %s function version: %v", inputs.Mode, err))
+		}
+
+		return
+	}
+
 	if inputs.Runtime == "" {
 		sourcecraft.SetFailed("runtime is required")
 
@@ -591,48 +847,81 @@ func main() {
 		return
 	}
 
-	// Validate async configuration
-	err = function.ValidateAsync(inputs)
+	if inputs.CompressionLevel < flate.HuffmanOnly || inputs.CompressionLevel > flate.BestCompression {
+		sourcecraft.SetFailed(fmt.Sprintf("compression-level must be between -2 and 9, got %d", inputs.CompressionLevel))
+
+		return
+	}
+
+	if inputs.LogsGroupID != "" && inputs.LogsFolderID != "" {
+		sourcecraft.SetFailed("both logs-group-id and logs-folder-id are provided, please set only one of them")
+
+		return
+	}
+
+	_, err = function.DeclaredScalingPolicies(inputs)
 	if err != nil {
-		sourcecraft.SetFailed(fmt.Sprintf("Invalid async configuration: %v", err))
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid scaling policies: %v", err))
 
 		return
 	}
 
-	// Zip sources
-	fileContents, err := zipSources(inputs)
+	// Validate async configuration
+	err = function.ValidateAsync(inputs)
 	if err != nil {
-		sourcecraft.SetFailed(fmt.Sprintf("Failed to zip sources: %v", err))
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid async configuration: %v", err))
 
 		return
 	}
 
-	sourcecraft.Info(fmt.Sprintf("Buffer size: %d bytes", len(fileContents)))
+	// Check the build toolchain before the runtime is validated and the sources are packaged
+	if function.IsBuild(inputs) {
+		err = function.CheckBuildTool(inputs)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Invalid build: %v", err))
 
-	// Get or create function ID
-	functionID, err := getOrCreateFunctionID(ctx, sdk, inputs)
+			return
+		}
+	}
+
+	// Validate runtime
+	err = validateRuntime(ctx, sdk, inputs.Runtime)
 	if err != nil {
-		sourcecraft.SetFailed(fmt.Sprintf("Failed to get or create function: %v", err))
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid runtime: %v", err))
 
 		return
 	}
 
-	// Upload to S3 if bucket is provided
-	var bucketObjectName string
-	if inputs.Bucket != "" {
-		bucketObjectName, err = uploadToS3(ctx, inputs.Bucket, functionID, sdk, fileContents)
+	// Parse smoke test
+	var check *smoke.Check
+	if smoke.Enabled() {
+		check, err = smoke.InputCheck()
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to upload to S3: %v", err))
+			sourcecraft.SetFailed(fmt.Sprintf("Invalid smoke test: %v", err))
 
 			return
 		}
 	}
 
-	// Create function version
-	err = createFunctionVersion(ctx, sdk, functionID, fileContents, bucketObjectName, inputs)
+	// Zip sources, building them first if requested
+	var pkg *function.Package
+	if function.IsBuild(inputs) {
+		pkg, err = function.BuildPackage(ctx, inputs)
+	} else {
+		pkg, err = function.ZipSources(inputs)
+	}
+
 	if err != nil {
-		sourcecraft.SetFailed(fmt.Sprintf("Failed to create function version: %v", err))
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to package sources: %v", err))
 
 		return
 	}
+
+	// SetFailed exits without running deferred calls, so the package is removed before failing
+	err = deployPackage(ctx, sdk, pkg, inputs, check)
+	_ = pkg.Remove()
+
+	if err != nil {
+		sourcecraft.SetFailed(err.Error())
+	}
 }
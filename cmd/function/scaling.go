@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/operation"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// listScalingPolicies returns all scaling policies of the function.
+func listScalingPolicies(ctx context.Context, sdk *ycsdk.SDK, functionID string) ([]*functions.ScalingPolicy, error) {
+	var (
+		policies  []*functions.ScalingPolicy
+		pageToken string
+	)
+
+	for {
+		resp, err := sdk.Serverless().Functions().Function().ListScalingPolicies(
+			ctx,
+			&functions.ListScalingPoliciesRequest{
+				FunctionId: functionID,
+				PageToken:  pageToken,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list scaling policies: %w", err)
+		}
+
+		policies = append(policies, resp.ScalingPolicies...)
+
+		pageToken = resp.NextPageToken
+		if pageToken == "" {
+			return policies, nil
+		}
+	}
+}
+
+// newScalingRequests builds the requests reconciling the scaling policies of the function with the
+// declared ones. Policies of undeclared tags are removed only if SCALING_POLICIES or SCALING_POLICIES_PRUNE is set.
+func newScalingRequests(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	functionID string,
+	inputs *function.ActionInputs,
+) ([]*versionRequest, error) {
+	declared, err := function.DeclaredScalingPolicies(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	prune := function.PruneScalingPolicies(inputs)
+	if len(declared) == 0 && !prune {
+		return nil, nil
+	}
+
+	// A function that doesn't exist yet has no policies
+	var existing []*functions.ScalingPolicy
+	if functionID != "" {
+		existing, err = listScalingPolicies(ctx, sdk, functionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	toSet, toRemove := function.ScalingPolicyChanges(functionID, existing, declared, prune)
+
+	functionService := sdk.Serverless().Functions().Function()
+	requests := make([]*versionRequest, 0, len(toSet)+len(toRemove))
+
+	for _, request := range toSet {
+		requests = append(requests, &versionRequest{
+			description: fmt.Sprintf(
+				"Set scaling policy of tag %s: %d provisioned instances, zone limits %d instances and %d requests",
+				request.Tag,
+				request.ProvisionedInstancesCount,
+				request.ZoneInstancesLimit,
+				request.ZoneRequestsLimit,
+			),
+			request: request,
+			call: func(ctx context.Context) (*operation.Operation, error) {
+				return functionService.SetScalingPolicy(ctx, request)
+			},
+		})
+	}
+
+	for _, request := range toRemove {
+		requests = append(requests, &versionRequest{
+			description: fmt.Sprintf("Remove scaling policy of tag %s", request.Tag),
+			request:     request,
+			call: func(ctx context.Context) (*operation.Operation, error) {
+				return functionService.RemoveScalingPolicy(ctx, request)
+			},
+		})
+	}
+
+	return requests, nil
+}
+
+// reconcileScalingPolicies sets the declared scaling policies of the function and removes the undeclared ones.
+func reconcileScalingPolicies(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	functionID string,
+	inputs *function.ActionInputs,
+) error {
+	sourcecraft.StartGroup("Reconcile scaling policies")
+	defer sourcecraft.EndGroup()
+
+	requests, err := newScalingRequests(ctx, sdk, functionID, inputs)
+	if err != nil {
+		return err
+	}
+
+	for _, request := range requests {
+		sourcecraft.Info(request.description)
+
+		op, err := sdk.WrapOperation(request.call(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to start operation %q: %w", request.description, err)
+		}
+
+		err = op.Wait(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for operation: %w", err)
+		}
+	}
+
+	if len(requests) == 0 {
+		sourcecraft.Info("Scaling policies are up to date")
+	}
+
+	return nil
+}
+
+// addScalingPlan adds reconciling the scaling policies to the plan.
+func addScalingPlan(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	p *plan.Plan,
+	functionID string,
+	inputs *function.ActionInputs,
+) error {
+	requests, err := newScalingRequests(ctx, sdk, functionID, inputs)
+	if err != nil {
+		return err
+	}
+
+	for _, request := range requests {
+		err = p.AddRequest(request.description, request.request)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+	"github.com/yc-actions/sourcecraft-actions/pkg/smoke"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// functionsURL is the base URL of function HTTP invocations.
+const functionsURL = "https://functions.yandexcloud.net"
+
+// smokeTestFunction invokes the $latest version of the function and checks the response.
+// If the check fails and rollback is requested, the previous version is restored with the deployed tags.
+func smokeTestFunction(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	check *smoke.Check,
+	functionID string,
+	previous *functions.Version,
+	tags []string,
+) error {
+	sourcecraft.StartGroup("Smoke test")
+	defer sourcecraft.EndGroup()
+
+	target, err := check.URL(functionsURL+"/"+functionID, url.Values{"tag": {function.LatestTag}})
+	if err != nil {
+		return err
+	}
+
+	token, err := check.Token(ctx, sdk)
+	if err != nil {
+		return err
+	}
+
+	err = check.Run(ctx, http.DefaultClient, target, token)
+	if err == nil || !check.Rollback {
+		return err
+	}
+
+	if previous == nil {
+		return fmt.Errorf("%w; there is no previous version to roll back to", err)
+	}
+
+	versionID, rollbackErr := rollbackFunction(ctx, sdk, previous, tags)
+	if rollbackErr != nil {
+		return errors.Join(err, rollbackErr)
+	}
+
+	sourcecraft.SetOutput("VERSION_ID", versionID)
+
+	return fmt.Errorf("%w; rolled back to a copy %s of version %s", err, versionID, previous.Id)
+}
+
+// rollbackFunction makes a copy of the version the $latest one and moves the tags to it.
+func rollbackFunction(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	version *functions.Version,
+	tags []string,
+) (string, error) {
+	sourcecraft.Info(fmt.Sprintf("Rolling back to version %s", version.Id))
+
+	request := function.NewRollbackVersionRequest(version)
+	request.Tag = tags
+
+	op, err := sdk.WrapOperation(sdk.Serverless().Functions().Function().CreateVersion(ctx, request))
+	if err != nil {
+		return "", fmt.Errorf("failed to roll back function version: %w", err)
+	}
+
+	err = op.Wait(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for operation: %w", err)
+	}
+
+	meta, err := op.Metadata()
+	if err != nil {
+		return "", fmt.Errorf("failed to get operation metadata: %w", err)
+	}
+
+	createMeta, ok := meta.(*functions.CreateFunctionVersionMetadata)
+	if !ok {
+		return "", fmt.Errorf("unexpected metadata type: %T", meta)
+	}
+
+	return createMeta.FunctionVersionId, nil
+}
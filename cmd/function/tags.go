@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/operation"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// resolveVersion returns the function version with the given tag or ID.
+func resolveVersion(ctx context.Context, sdk *ycsdk.SDK, functionID, ref string) (*functions.Version, error) {
+	functionService := sdk.Serverless().Functions().Function()
+
+	version, err := functionService.GetVersionByTag(ctx, &functions.GetFunctionVersionByTagRequest{
+		FunctionId: functionID,
+		Tag:        ref,
+	})
+	if err == nil {
+		return version, nil
+	}
+
+	if code := status.Code(err); code != codes.NotFound && code != codes.InvalidArgument {
+		return nil, fmt.Errorf("failed to get function version by tag %q: %w", ref, err)
+	}
+
+	version, err = functionService.GetVersion(ctx, &functions.GetFunctionVersionRequest{
+		FunctionVersionId: ref,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find function version %q by tag or ID: %w", ref, err)
+	}
+
+	if version.FunctionId != functionID {
+		return nil, fmt.Errorf("version %s belongs to another function %s", version.Id, version.FunctionId)
+	}
+
+	return version, nil
+}
+
+// versionRequest is a mutating request of the tag management modes.
+type versionRequest struct {
+	description string
+	request     proto.Message
+	call        func(ctx context.Context) (*operation.Operation, error)
+}
+
+// newTagRequests builds the requests setting and removing tags of the version.
+func newTagRequests(
+	sdk *ycsdk.SDK,
+	version *functions.Version,
+	set, remove []string,
+) ([]*versionRequest, error) {
+	toSet, toRemove, err := function.TagChanges(version, set, remove)
+	if err != nil {
+		return nil, err
+	}
+
+	functionService := sdk.Serverless().Functions().Function()
+	requests := make([]*versionRequest, 0, len(toSet)+len(toRemove))
+
+	for _, tag := range toSet {
+		request := &functions.SetFunctionTagRequest{FunctionVersionId: version.Id, Tag: tag}
+		requests = append(requests, &versionRequest{
+			description: fmt.Sprintf("Set tag %s on version %s", tag, version.Id),
+			request:     request,
+			call: func(ctx context.Context) (*operation.Operation, error) {
+				return functionService.SetTag(ctx, request)
+			},
+		})
+	}
+
+	for _, tag := range toRemove {
+		request := &functions.RemoveFunctionTagRequest{FunctionVersionId: version.Id, Tag: tag}
+		requests = append(requests, &versionRequest{
+			description: fmt.Sprintf("Remove tag %s from version %s", tag, version.Id),
+			request:     request,
+			call: func(ctx context.Context) (*operation.Operation, error) {
+				return functionService.RemoveTag(ctx, request)
+			},
+		})
+	}
+
+	return requests, nil
+}
+
+// newModeRequests resolves the version of the mode and builds its requests.
+func newModeRequests(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	functionID string,
+	inputs *function.ActionInputs,
+) (*functions.Version, []*versionRequest, error) {
+	switch inputs.Mode {
+	case function.ModeTag:
+		ref := inputs.Version
+		if ref == "" {
+			ref = function.LatestTag
+		}
+
+		version, err := resolveVersion(ctx, sdk, functionID, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		requests, err := newTagRequests(sdk, version, inputs.SetTags, inputs.RemoveTags)
+
+		return version, requests, err
+	case function.ModePromote:
+		version, err := resolveVersion(ctx, sdk, functionID, inputs.PromoteFrom)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		requests, err := newTagRequests(sdk, version, []string{inputs.PromoteTo}, nil)
+
+		return version, requests, err
+	case function.ModeRollback:
+		version, err := resolveVersion(ctx, sdk, functionID, inputs.Version)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		request := function.NewRollbackVersionRequest(version)
+
+		return version, []*versionRequest{{
+			description: fmt.Sprintf("Create version copied from %s as %s", version.Id, function.LatestTag),
+			request:     request,
+			call: func(ctx context.Context) (*operation.Operation, error) {
+				return sdk.Serverless().Functions().Function().CreateVersion(ctx, request)
+			},
+		}}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported mode %q", inputs.Mode)
+	}
+}
+
+// manageVersion runs the tag management mode, or publishes its plan in dry-run mode.
+func manageVersion(ctx context.Context, sdk *ycsdk.SDK, inputs *function.ActionInputs) error {
+	sourcecraft.StartGroup(fmt.Sprintf("Function version %s", inputs.Mode))
+	defer sourcecraft.EndGroup()
+
+	functionID, err := findFunctionID(ctx, sdk, inputs)
+	if err != nil {
+		return err
+	}
+
+	if functionID == "" {
+		return fmt.Errorf("there is no function named '%s' in the folder", inputs.FunctionName)
+	}
+
+	sourcecraft.SetOutput("FUNCTION_ID", functionID)
+
+	version, requests, err := newModeRequests(ctx, sdk, functionID, inputs)
+	if err != nil {
+		return err
+	}
+
+	sourcecraft.Info(fmt.Sprintf("Version %s has tags %q", version.Id, version.Tags))
+
+	if plan.DryRun() {
+		p := plan.New("function")
+
+		for _, request := range requests {
+			err = p.AddRequest(request.description, request.request)
+			if err != nil {
+				return err
+			}
+		}
+
+		return p.Publish()
+	}
+
+	versionID := version.Id
+
+	for _, request := range requests {
+		sourcecraft.Info(request.description)
+
+		op, err := sdk.WrapOperation(request.call(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to start operation %q: %w", request.description, err)
+		}
+
+		err = op.Wait(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for operation: %w", err)
+		}
+
+		meta, err := op.Metadata()
+		if err != nil {
+			return fmt.Errorf("failed to get operation metadata: %w", err)
+		}
+
+		// The rollback creates a new version
+		if createMeta, ok := meta.(*functions.CreateFunctionVersionMetadata); ok {
+			versionID = createMeta.FunctionVersionId
+		}
+	}
+
+	if len(requests) == 0 {
+		sourcecraft.Info("No changes")
+	}
+
+	sourcecraft.SetOutput("VERSION_ID", versionID)
+
+	return nil
+}
@@ -3,115 +3,242 @@ package main
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/spf13/afero"
-	ycsdk "github.com/yandex-cloud/go-sdk"
-	"github.com/yandex-cloud/go-sdk/iamkey"
 	"github.com/yc-actions/sourcecraft-actions/internal/objstore"
+	"github.com/yc-actions/sourcecraft-actions/pkg/auth"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
 	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
 	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
 )
 
 // Action inputs.
 const (
-	inputBucket              = "BUCKET"
-	inputPrefix              = "PREFIX"
-	inputRoot                = "ROOT"
-	inputInclude             = "INCLUDE"
-	inputExclude             = "EXCLUDE"
-	inputClear               = "CLEAR"
-	inputCacheControl        = "CACHE_CONTROL"
-	inputYcSaJsonCredentials = "YC_SA_JSON_CREDENTIALS"
-	inputYcIamToken          = "YC_IAM_TOKEN"
+	inputBucket       = "BUCKET"
+	inputPrefix       = "PREFIX"
+	inputRoot         = "ROOT"
+	inputInclude      = "INCLUDE"
+	inputExclude      = "EXCLUDE"
+	inputClear        = "CLEAR"
+	inputClearExclude = "CLEAR_EXCLUDE"
+	inputClearMax     = "CLEAR_MAX_DELETE"
+	inputClearAfter   = "CLEAR_AFTER_UPLOAD"
+	inputCacheControl = "CACHE_CONTROL"
+	inputSync         = "SYNC"
+	inputSyncDelete   = "SYNC_DELETE"
+	inputConcurrency  = "CONCURRENCY"
+
+	inputContentType        = "CONTENT_TYPE"
+	inputContentEncoding    = "CONTENT_ENCODING"
+	inputContentDisposition = "CONTENT_DISPOSITION"
+	inputContentLanguage    = "CONTENT_LANGUAGE"
+	inputExpires            = "EXPIRES"
+	inputStorageClass       = "STORAGE_CLASS"
+	inputACL                = "ACL"
+	inputMetadata           = "METADATA"
+
+	inputCompress        = "COMPRESS"
+	inputCompressMinSize = "COMPRESS_MIN_SIZE"
 )
 
-// clearBucket clears all objects from a bucket.
-func clearBucket(ctx context.Context, storageService storage.StorageService, bucket string) error {
-	sourcecraft.Info(fmt.Sprintf("Clearing bucket %s", bucket))
-
-	// Clear bucket
-	err := storageService.ClearBucket(ctx, bucket)
+// clearObjects deletes the objects listed for clearing.
+func clearObjects(
+	ctx context.Context,
+	storageService storage.StorageService,
+	inputs *objstore.ActionInputs,
+	keys []string,
+) error {
+	sourcecraft.Info(
+		fmt.Sprintf("Clearing %d objects under %q in bucket %s", len(keys), inputs.Prefix, inputs.Bucket),
+	)
+
+	deleted, err := objstore.DeleteKeys(ctx, storageService, inputs.Bucket, keys)
 	if err != nil {
-		return fmt.Errorf("failed to clear bucket: %w", err)
+		return err
 	}
 
-	sourcecraft.Info(fmt.Sprintf("Bucket %s cleared successfully", bucket))
+	sourcecraft.Info(fmt.Sprintf("Deleted %d objects", deleted))
+	sourcecraft.SetOutput("DELETED", strconv.Itoa(deleted))
 
 	return nil
 }
 
-func main() {
-	ctx := context.Background()
-
-	// Parse inputs
-	ycSaJsonCredentials := sourcecraft.GetInput(inputYcSaJsonCredentials)
-	ycIamToken := sourcecraft.GetInput(inputYcIamToken)
-
-	// Create SDK
-	var sdk *ycsdk.SDK
-
-	var err error
+// planUpload publishes the plan of uploading the files.
+func planUpload(
+	ctx context.Context,
+	f afero.Fs,
+	storageService storage.StorageService,
+	inputs *objstore.ActionInputs,
+) error {
+	p := plan.New("obj-storage-upload")
 
-	if ycSaJsonCredentials != "" {
-		// Create credentials
-		var key *iamkey.Key
+	var clearKeys []string
 
-		key, err = iamkey.ReadFromJSONBytes([]byte(ycSaJsonCredentials))
+	if inputs.Clear {
+		keys, err := objstore.ListClear(ctx, f, storageService, inputs)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to read service account JSON: %v", err))
-
-			return
+			return err
 		}
 
-		var credentials ycsdk.Credentials
+		clearKeys = keys
+	}
 
-		credentials, err = ycsdk.ServiceAccountKey(key)
+	if inputs.Clear && !inputs.ClearAfterUpload {
+		err := p.AddValue(fmt.Sprintf("Delete %d objects from bucket %s", len(clearKeys), inputs.Bucket), clearKeys)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create credentials: %v", err))
+			return err
+		}
+	}
 
-			return
+	if inputs.Sync {
+		diff, err := objstore.Diff(ctx, f, storageService, inputs)
+		if err != nil {
+			return err
 		}
 
-		// Create SDK
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: credentials,
-		})
+		err = p.AddValue(
+			fmt.Sprintf("Upload %d changed files to bucket %s", len(diff.Upload), inputs.Bucket),
+			diff.Upload,
+		)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
+			return err
+		}
 
-			return
+		p.Add(fmt.Sprintf("Skip %d unchanged files", len(diff.Skip)))
+
+		if len(diff.Delete) > 0 {
+			err = p.AddValue(
+				fmt.Sprintf("Delete %d removed objects from bucket %s", len(diff.Delete), inputs.Bucket),
+				diff.Delete,
+			)
+			if err != nil {
+				return err
+			}
 		}
 
-		sourcecraft.Info("Parsed Service account JSON")
-	} else if ycIamToken != "" {
-		// Create SDK with IAM token
-		sdk, err = ycsdk.Build(ctx, ycsdk.Config{
-			Credentials: ycsdk.NewIAMTokenCredentials(ycIamToken),
-		})
+		return p.Publish()
+	}
+
+	entries, err := objstore.ListFiles(f, inputs)
+	if err != nil {
+		return err
+	}
+
+	err = p.AddValue(fmt.Sprintf("Upload %d files to bucket %s", len(entries), inputs.Bucket), entries)
+	if err != nil {
+		return err
+	}
+
+	if inputs.Clear && inputs.ClearAfterUpload {
+		err = p.AddValue(
+			fmt.Sprintf("Delete %d stale objects from bucket %s after upload", len(clearKeys), inputs.Bucket),
+			clearKeys,
+		)
 		if err != nil {
-			sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
+			return err
+		}
+	}
 
-			return
+	return p.Publish()
+}
+
+// parseObjectHeaders parses the headers and user metadata set on uploaded objects by glob.
+func parseObjectHeaders() (objstore.ObjectHeaders, error) {
+	metadata, err := objstore.ParseMetadataFormats(sourcecraft.GetMultilineInput(inputMetadata))
+	if err != nil {
+		return objstore.ObjectHeaders{}, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+
+	headers := objstore.ObjectHeaders{Metadata: metadata}
+
+	values := []struct {
+		input string
+		value *objstore.GlobValues
+	}{
+		{inputContentType, &headers.ContentType},
+		{inputContentEncoding, &headers.ContentEncoding},
+		{inputContentDisposition, &headers.ContentDisposition},
+		{inputContentLanguage, &headers.ContentLanguage},
+		{inputExpires, &headers.Expires},
+		{inputStorageClass, &headers.StorageClass},
+		{inputACL, &headers.ACL},
+	}
+
+	for _, v := range values {
+		*v.value, err = objstore.ParseGlobValues(sourcecraft.GetMultilineInput(v.input))
+		if err != nil {
+			return objstore.ObjectHeaders{}, fmt.Errorf("failed to parse %s: %w", v.input, err)
 		}
+	}
+
+	return headers, headers.Validate()
+}
+
+func main() {
+	ctx := context.Background()
+
+	// Create SDK
+	sdk, err := auth.NewSDK(ctx)
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Failed to create SDK: %v", err))
+
+		return
+	}
+
+	// Parse object headers
+	headers, err := parseObjectHeaders()
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid object headers: %v", err))
+
+		return
+	}
+
+	// Parse compression
+	encodings, err := objstore.ParseGlobValues(sourcecraft.GetMultilineInput(inputCompress))
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid compression: %v", err))
+
+		return
+	}
+
+	compression := objstore.CompressionConfig{
+		Encodings: encodings,
+		MinSize:   sourcecraft.GetInt64Input(inputCompressMinSize, objstore.DefaultCompressMinSize),
+	}
+
+	err = compression.Validate()
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid compression: %v", err))
 
-		sourcecraft.Info("Using IAM token")
-	} else {
-		sourcecraft.SetFailed("No credentials provided")
+		return
+	}
+
+	// Parse cache control
+	cacheControl, err := objstore.ParseCacheControlFormats(sourcecraft.GetMultilineInput(inputCacheControl))
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid cache control: %v", err))
 
 		return
 	}
 
 	// Create inputs
 	inputs := &objstore.ActionInputs{
-		Bucket:  sourcecraft.GetInput(inputBucket),
-		Prefix:  sourcecraft.GetInput(inputPrefix),
-		Root:    sourcecraft.GetInput(inputRoot),
-		Include: sourcecraft.GetMultilineInput(inputInclude),
-		Exclude: sourcecraft.GetMultilineInput(inputExclude),
-		Clear:   sourcecraft.GetBooleanInput(inputClear),
-		CacheControl: objstore.ParseCacheControlFormats(
-			sourcecraft.GetMultilineInput(inputCacheControl),
-		),
+		Bucket:           sourcecraft.GetInput(inputBucket),
+		Prefix:           sourcecraft.GetInput(inputPrefix),
+		Root:             sourcecraft.GetInput(inputRoot),
+		Include:          sourcecraft.GetMultilineInput(inputInclude),
+		Exclude:          sourcecraft.GetMultilineInput(inputExclude),
+		Clear:            sourcecraft.GetBooleanInput(inputClear),
+		CacheControl:     cacheControl,
+		ClearExclude:     sourcecraft.GetMultilineInput(inputClearExclude),
+		ClearMaxDelete:   sourcecraft.GetIntInput(inputClearMax, 0),
+		ClearAfterUpload: sourcecraft.GetBooleanInput(inputClearAfter),
+		Headers:          headers,
+		Compression:      compression,
+		Sync:             sourcecraft.GetBooleanInput(inputSync),
+		DeleteRemoved:    sourcecraft.GetBooleanInput(inputSyncDelete),
+		Concurrency:      sourcecraft.GetIntInput(inputConcurrency, 8),
 	}
 
 	// Validate inputs
@@ -127,19 +254,61 @@ func main() {
 		return
 	}
 
+	if inputs.Concurrency < 1 {
+		sourcecraft.SetFailed("concurrency must be positive")
+
+		return
+	}
+
+	if inputs.Sync && inputs.Clear {
+		sourcecraft.SetFailed("clear and sync can't be used together")
+
+		return
+	}
+
 	if len(inputs.Include) == 0 {
 		// Default to include everything
 		inputs.Include = []string{"."}
 	}
 
 	// Create storage service
-	storageService := storage.NewStorageService(sdk)
+	storageOptions, err := storage.InputOptions()
+	if err != nil {
+		sourcecraft.SetFailed(fmt.Sprintf("Invalid multipart upload options: %v", err))
+
+		return
+	}
+
+	// Failed uploads are retried by the action, not by the SDK
+	storageOptions.SingleAttemptUploads = true
+
+	storageService := storage.NewStorageServiceWithOptions(sdk, storageOptions)
 
 	fs := afero.NewOsFs()
 
-	// Clear bucket if requested
+	if plan.DryRun() {
+		err = planUpload(ctx, fs, storageService, inputs)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to plan upload: %v", err))
+		}
+
+		return
+	}
+
+	// List objects to clear if requested, failing early if there are too many
+	var clearKeys []string
+
 	if inputs.Clear {
-		err = clearBucket(ctx, storageService, inputs.Bucket)
+		clearKeys, err = objstore.ListClear(ctx, fs, storageService, inputs)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to list objects to clear: %v", err))
+
+			return
+		}
+	}
+
+	if inputs.Clear && !inputs.ClearAfterUpload {
+		err = clearObjects(ctx, storageService, inputs, clearKeys)
 		if err != nil {
 			sourcecraft.SetFailed(fmt.Sprintf("Failed to clear bucket: %v", err))
 
@@ -147,6 +316,22 @@ func main() {
 		}
 	}
 
+	// Sync files if requested
+	if inputs.Sync {
+		result, err := objstore.Sync(ctx, fs, storageService, inputs)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to sync files: %v", err))
+
+			return
+		}
+
+		sourcecraft.SetOutput("UPLOADED", strconv.Itoa(result.Uploaded))
+		sourcecraft.SetOutput("SKIPPED", strconv.Itoa(result.Skipped))
+		sourcecraft.SetOutput("DELETED", strconv.Itoa(result.Deleted))
+
+		return
+	}
+
 	// Upload files
 	err = objstore.Upload(ctx, fs, storageService, inputs)
 	if err != nil {
@@ -156,4 +341,14 @@ func main() {
 	}
 
 	sourcecraft.Info("Upload complete")
+
+	// Delete stale objects once the new ones are in place
+	if inputs.Clear && inputs.ClearAfterUpload {
+		err = clearObjects(ctx, storageService, inputs, clearKeys)
+		if err != nil {
+			sourcecraft.SetFailed(fmt.Sprintf("Failed to clear bucket: %v", err))
+
+			return
+		}
+	}
 }
@@ -0,0 +1,439 @@
+package container
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/operation"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/containers/v1"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Client is the part of the Containers service used to deploy a container.
+type Client interface {
+	Get(ctx context.Context, in *containers.GetContainerRequest, opts ...grpc.CallOption) (*containers.Container, error)
+	List(
+		ctx context.Context,
+		in *containers.ListContainersRequest,
+		opts ...grpc.CallOption,
+	) (*containers.ListContainersResponse, error)
+	Create(
+		ctx context.Context,
+		in *containers.CreateContainerRequest,
+		opts ...grpc.CallOption,
+	) (*operation.Operation, error)
+	GetRevision(
+		ctx context.Context,
+		in *containers.GetContainerRevisionRequest,
+		opts ...grpc.CallOption,
+	) (*containers.Revision, error)
+	ListRevisions(
+		ctx context.Context,
+		in *containers.ListContainersRevisionsRequest,
+		opts ...grpc.CallOption,
+	) (*containers.ListContainersRevisionsResponse, error)
+	DeployRevision(
+		ctx context.Context,
+		in *containers.DeployContainerRevisionRequest,
+		opts ...grpc.CallOption,
+	) (*operation.Operation, error)
+	Rollback(
+		ctx context.Context,
+		in *containers.RollbackContainerRequest,
+		opts ...grpc.CallOption,
+	) (*operation.Operation, error)
+}
+
+// WaitFunc waits for the operation to complete and returns its response.
+type WaitFunc func(ctx context.Context, op *operation.Operation) (proto.Message, error)
+
+// ResolveServiceAccountFunc resolves a service account ID from either a direct ID or a name.
+type ResolveServiceAccountFunc func(ctx context.Context, folderID, serviceAccountID, serviceAccountName string) (string, error)
+
+// Deployer deploys container revisions.
+type Deployer struct {
+	Client                Client
+	Wait                  WaitFunc
+	ResolveServiceAccount ResolveServiceAccountFunc
+}
+
+// Deployment is what to deploy.
+type Deployment struct {
+	FolderID      string
+	ContainerName string
+	Options       *CreateRevisionOptions
+}
+
+// DeployResult is the outcome of a deployment.
+type DeployResult struct {
+	ContainerID        string
+	RevisionID         string
+	PreviousRevisionID string
+}
+
+// NewRevisionRequest builds the request deploying a new revision.
+func NewRevisionRequest(options *CreateRevisionOptions) *containers.DeployContainerRevisionRequest {
+	// Create the request
+	req := &containers.DeployContainerRevisionRequest{
+		ContainerId: options.ContainerID,
+		Resources: &containers.Resources{
+			Memory:       options.MemoryValue,
+			Cores:        options.Cores,
+			CoreFraction: options.CoreFraction,
+		},
+		ExecutionTimeout: &durationpb.Duration{Seconds: options.ExecutionTimeout},
+		Concurrency:      options.Concurrency,
+		ServiceAccountId: options.ServiceAccountID,
+	}
+
+	// Set image spec
+	req.ImageSpec = &containers.ImageSpec{
+		ImageUrl:   options.ImageURL,
+		WorkingDir: options.WorkingDir,
+	}
+
+	// Set commands if provided
+	if len(options.Commands) > 0 {
+		// Create a Command struct with the commands
+		req.ImageSpec.Command = &containers.Command{
+			Command: options.Commands,
+		}
+	}
+
+	// Set args if provided
+	if len(options.Args) > 0 {
+		// Create an Args struct with the args
+		req.ImageSpec.Args = &containers.Args{
+			Args: options.Args,
+		}
+	}
+
+	// Set environment variables if provided
+	if len(options.Env) > 0 {
+		req.ImageSpec.Environment = options.Env
+	}
+
+	// Set network ID if provided
+	if options.NetworkID != "" {
+		req.Connectivity = &containers.Connectivity{
+			NetworkId: options.NetworkID,
+		}
+	}
+
+	// Set provisioned instances if provided
+	if options.Provisioned != nil {
+		req.ProvisionPolicy = &containers.ProvisionPolicy{
+			MinInstances: *options.Provisioned,
+		}
+	}
+
+	// Set secrets if provided
+	if len(options.Secrets) > 0 {
+		req.Secrets = make([]*containers.Secret, 0, len(options.Secrets))
+		for _, secret := range options.Secrets {
+			req.Secrets = append(req.Secrets, &containers.Secret{
+				Id:        secret.SecretID,
+				VersionId: secret.VersionID,
+				Key:       secret.Key,
+				Reference: &containers.Secret_EnvironmentVariable{
+					EnvironmentVariable: secret.EnvironmentVariable,
+				},
+			})
+		}
+	}
+
+	// Set log options if provided
+	if options.LogOptions != nil {
+		req.LogOptions = &containers.LogOptions{
+			Disabled: options.LogOptions.Disabled,
+			MinLevel: options.LogOptions.MinLevel,
+		}
+
+		if options.LogOptions.LogGroupID != "" {
+			req.LogOptions.Destination = &containers.LogOptions_LogGroupId{
+				LogGroupId: options.LogOptions.LogGroupID,
+			}
+		} else if options.LogOptions.FolderID != "" {
+			req.LogOptions.Destination = &containers.LogOptions_FolderId{
+				FolderId: options.LogOptions.FolderID,
+			}
+		}
+	}
+
+	// Set storage mounts if provided
+	if len(options.StorageMounts) > 0 {
+		req.StorageMounts = make([]*containers.StorageMount, 0, len(options.StorageMounts))
+		for _, mount := range options.StorageMounts {
+			req.StorageMounts = append(req.StorageMounts, &containers.StorageMount{
+				BucketId:       mount.BucketID,
+				Prefix:         mount.Prefix,
+				MountPointPath: mount.MountPointPath,
+				ReadOnly:       mount.ReadOnly,
+			})
+		}
+	}
+
+	// Set mounts if provided
+	if len(options.Mounts) > 0 {
+		req.Mounts = make([]*containers.Mount, 0, len(options.Mounts))
+		for _, mount := range options.Mounts {
+			req.Mounts = append(req.Mounts, newMount(mount))
+		}
+	}
+
+	// Set runtime
+	switch options.Runtime {
+	case RuntimeHTTP:
+		req.Runtime = &containers.Runtime{Type: &containers.Runtime_Http_{Http: &containers.Runtime_Http{}}}
+	case RuntimeTask:
+		req.Runtime = &containers.Runtime{Type: &containers.Runtime_Task_{Task: &containers.Runtime_Task{}}}
+	}
+
+	// Set metadata options if provided
+	if !options.MetadataOptions.IsEmpty() {
+		req.MetadataOptions = &containers.MetadataOptions{
+			GceHttpEndpoint:   metadataOption(options.MetadataOptions.GceHTTPEndpoint),
+			AwsV1HttpEndpoint: metadataOption(options.MetadataOptions.AwsV1HTTPEndpoint),
+		}
+	}
+
+	// Set scaling policy if provided
+	if options.ScalingPolicy != nil {
+		req.ScalingPolicy = &containers.ScalingPolicy{
+			ZoneInstancesLimit: options.ScalingPolicy.ZoneInstancesLimit,
+			ZoneRequestsLimit:  options.ScalingPolicy.ZoneRequestsLimit,
+		}
+	}
+
+	return req
+}
+
+// newMount converts the parsed mount to the revision mount.
+func newMount(mount *Mount) *containers.Mount {
+	m := &containers.Mount{
+		MountPointPath: mount.MountPoint,
+		Mode:           containers.Mount_READ_WRITE,
+	}
+
+	if mount.ReadOnly {
+		m.Mode = containers.Mount_READ_ONLY
+	}
+
+	if mount.IsEphemeralDisk() {
+		m.Target = &containers.Mount_EphemeralDiskSpec{
+			EphemeralDiskSpec: &containers.Mount_DiskSpec{Size: mount.DiskSize},
+		}
+	} else {
+		m.Target = &containers.Mount_ObjectStorage_{
+			ObjectStorage: &containers.Mount_ObjectStorage{
+				BucketId: mount.BucketID,
+				Prefix:   mount.Prefix,
+			},
+		}
+	}
+
+	return m
+}
+
+// metadataOption converts a parsed metadata option to the revision one.
+func metadataOption(option MetadataOption) containers.MetadataOption {
+	switch option {
+	case MetadataOptionEnabled:
+		return containers.MetadataOption_ENABLED
+	case MetadataOptionDisabled:
+		return containers.MetadataOption_DISABLED
+	default:
+		return containers.MetadataOption_METADATA_OPTION_UNSPECIFIED
+	}
+}
+
+// NewCreateContainerRequest builds the request creating the container.
+func NewCreateContainerRequest(folderID, name string) *containers.CreateContainerRequest {
+	// Get repository info for description
+	repoOwner := sourcecraft.GetSourcecraftRepositoryOwner()
+	repoName := sourcecraft.GetSourcecraftRepository()
+
+	return &containers.CreateContainerRequest{
+		FolderId:    folderID,
+		Name:        name,
+		Description: fmt.Sprintf("Created from: %s/%s", repoOwner, repoName),
+	}
+}
+
+// NewRollbackRequest builds the request making the revision the serving one.
+func NewRollbackRequest(containerID, revisionID string) *containers.RollbackContainerRequest {
+	return &containers.RollbackContainerRequest{
+		ContainerId: containerID,
+		RevisionId:  revisionID,
+	}
+}
+
+// FindContainer returns the ID of the container with the given name, or an empty string if there is none.
+func (d *Deployer) FindContainer(ctx context.Context, folderID, name string) (string, error) {
+	resp, err := d.Client.List(ctx, &containers.ListContainersRequest{
+		FolderId: folderID,
+		Filter:   fmt.Sprintf("name = \"%s\"", name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	if len(resp.Containers) == 0 {
+		return "", nil
+	}
+
+	return resp.Containers[0].Id, nil
+}
+
+// CreateContainer creates a container and returns its ID.
+func (d *Deployer) CreateContainer(ctx context.Context, folderID, name string) (string, error) {
+	op, err := d.Client.Create(ctx, NewCreateContainerRequest(folderID, name))
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+
+	result, err := d.Wait(ctx, op)
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for operation: %w", err)
+	}
+
+	c, ok := result.(*containers.Container)
+	if !ok {
+		return "", fmt.Errorf("unexpected response type: %T", result)
+	}
+
+	return c.Id, nil
+}
+
+// ResolveServiceAccounts replaces the service account names of the options with their IDs.
+func (d *Deployer) ResolveServiceAccounts(ctx context.Context, folderID string, options *CreateRevisionOptions) error {
+	serviceAccountID, err := d.ResolveServiceAccount(
+		ctx,
+		folderID,
+		options.ServiceAccountID,
+		options.ServiceAccountName,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve revision service account: %w", err)
+	}
+
+	options.ServiceAccountID = serviceAccountID
+
+	return nil
+}
+
+// FindActiveRevision returns the ID of the serving revision of the container, or an empty string.
+func (d *Deployer) FindActiveRevision(ctx context.Context, containerID string) (string, error) {
+	resp, err := d.Client.ListRevisions(ctx, &containers.ListContainersRevisionsRequest{
+		Id:     &containers.ListContainersRevisionsRequest_ContainerId{ContainerId: containerID},
+		Filter: `status="ACTIVE"`,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	var latest *containers.Revision
+
+	for _, revision := range resp.Revisions {
+		if latest == nil || revision.CreatedAt.AsTime().After(latest.CreatedAt.AsTime()) {
+			latest = revision
+		}
+	}
+
+	if latest == nil {
+		return "", nil
+	}
+
+	return latest.Id, nil
+}
+
+// DeployRevision deploys the revision and returns its ID.
+func (d *Deployer) DeployRevision(ctx context.Context, req *containers.DeployContainerRevisionRequest) (string, error) {
+	op, err := d.Client.DeployRevision(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create revision: %w", err)
+	}
+
+	result, err := d.Wait(ctx, op)
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for operation: %w", err)
+	}
+
+	revision, ok := result.(*containers.Revision)
+	if !ok {
+		return "", fmt.Errorf("unexpected response type: %T", result)
+	}
+
+	return revision.Id, nil
+}
+
+// Rollback makes the revision the serving one again.
+func (d *Deployer) Rollback(ctx context.Context, containerID, revisionID string) error {
+	sourcecraft.Info(fmt.Sprintf("Rolling back to revision %s", revisionID))
+
+	op, err := d.Client.Rollback(ctx, NewRollbackRequest(containerID, revisionID))
+	if err != nil {
+		return fmt.Errorf("failed to roll back container: %w", err)
+	}
+
+	_, err = d.Wait(ctx, op)
+	if err != nil {
+		return fmt.Errorf("failed to wait for operation: %w", err)
+	}
+
+	return nil
+}
+
+// Deploy resolves the container and the service accounts, builds the revision request and deploys it.
+// The new revision serves all of the traffic.
+func (d *Deployer) Deploy(ctx context.Context, deployment *Deployment) (*DeployResult, error) {
+	containerID, err := d.FindContainer(ctx, deployment.FolderID, deployment.ContainerName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeployResult{ContainerID: containerID}
+
+	if containerID != "" {
+		result.PreviousRevisionID, err = d.FindActiveRevision(ctx, containerID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if containerID == "" {
+		sourcecraft.Info(fmt.Sprintf("There is no container with name: %s. Creating a new one.", deployment.ContainerName))
+
+		containerID, err = d.CreateContainer(ctx, deployment.FolderID, deployment.ContainerName)
+		if err != nil {
+			return nil, err
+		}
+
+		sourcecraft.Info(fmt.Sprintf("Container successfully created. Id: %s", containerID))
+
+		result.ContainerID = containerID
+	} else {
+		sourcecraft.Info(
+			fmt.Sprintf("Container with name: %s already exists and has id: %s", deployment.ContainerName, containerID),
+		)
+	}
+
+	err = d.ResolveServiceAccounts(ctx, deployment.FolderID, deployment.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment.Options.ContainerID = containerID
+
+	result.RevisionID, err = d.DeployRevision(ctx, NewRevisionRequest(deployment.Options))
+	if err != nil {
+		return nil, err
+	}
+
+	sourcecraft.Info(fmt.Sprintf("Revision created successfully. Id: %s", result.RevisionID))
+
+	return result, nil
+}
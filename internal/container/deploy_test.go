@@ -0,0 +1,307 @@
+package container_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/operation"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/containers/v1"
+	"github.com/yc-actions/sourcecraft-actions/internal/container"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// fakeClient is an in-memory Containers service recording the mutating requests.
+type fakeClient struct {
+	containers []*containers.Container
+	revisions  []*containers.Revision
+
+	created   []*containers.CreateContainerRequest
+	deployed  []*containers.DeployContainerRevisionRequest
+	rollbacks []*containers.RollbackContainerRequest
+}
+
+func (f *fakeClient) Get(
+	_ context.Context,
+	in *containers.GetContainerRequest,
+	_ ...grpc.CallOption,
+) (*containers.Container, error) {
+	for _, c := range f.containers {
+		if c.Id == in.ContainerId {
+			return c, nil
+		}
+	}
+
+	return nil, errors.New("container not found")
+}
+
+func (f *fakeClient) List(
+	_ context.Context,
+	in *containers.ListContainersRequest,
+	_ ...grpc.CallOption,
+) (*containers.ListContainersResponse, error) {
+	resp := &containers.ListContainersResponse{}
+
+	for _, c := range f.containers {
+		if c.FolderId == in.FolderId && in.Filter == `name = "`+c.Name+`"` {
+			resp.Containers = append(resp.Containers, c)
+		}
+	}
+
+	return resp, nil
+}
+
+func (f *fakeClient) Create(
+	_ context.Context,
+	in *containers.CreateContainerRequest,
+	_ ...grpc.CallOption,
+) (*operation.Operation, error) {
+	f.created = append(f.created, in)
+
+	c := &containers.Container{Id: "created-container", FolderId: in.FolderId, Name: in.Name}
+	f.containers = append(f.containers, c)
+
+	return doneOperation(c)
+}
+
+func (f *fakeClient) GetRevision(
+	_ context.Context,
+	in *containers.GetContainerRevisionRequest,
+	_ ...grpc.CallOption,
+) (*containers.Revision, error) {
+	for _, r := range f.revisions {
+		if r.Id == in.ContainerRevisionId {
+			return r, nil
+		}
+	}
+
+	return nil, errors.New("revision not found")
+}
+
+func (f *fakeClient) ListRevisions(
+	_ context.Context,
+	in *containers.ListContainersRevisionsRequest,
+	_ ...grpc.CallOption,
+) (*containers.ListContainersRevisionsResponse, error) {
+	resp := &containers.ListContainersRevisionsResponse{}
+
+	for _, r := range f.revisions {
+		if r.ContainerId == in.GetContainerId() && r.Status == containers.Revision_ACTIVE {
+			resp.Revisions = append(resp.Revisions, r)
+		}
+	}
+
+	return resp, nil
+}
+
+func (f *fakeClient) DeployRevision(
+	_ context.Context,
+	in *containers.DeployContainerRevisionRequest,
+	_ ...grpc.CallOption,
+) (*operation.Operation, error) {
+	f.deployed = append(f.deployed, in)
+
+	return doneOperation(&containers.Revision{Id: "new-revision", ContainerId: in.ContainerId})
+}
+
+func (f *fakeClient) Rollback(
+	_ context.Context,
+	in *containers.RollbackContainerRequest,
+	_ ...grpc.CallOption,
+) (*operation.Operation, error) {
+	f.rollbacks = append(f.rollbacks, in)
+
+	return doneOperation(&containers.Container{Id: in.ContainerId})
+}
+
+func doneOperation(response proto.Message) (*operation.Operation, error) {
+	result, err := anypb.New(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &operation.Operation{Done: true, Result: &operation.Operation_Response{Response: result}}, nil
+}
+
+func waitDone(_ context.Context, op *operation.Operation) (proto.Message, error) {
+	return op.GetResponse().UnmarshalNew()
+}
+
+func newTestDeployer(client *fakeClient, accounts map[string]string) *container.Deployer {
+	return &container.Deployer{
+		Client: client,
+		Wait:   waitDone,
+		ResolveServiceAccount: func(_ context.Context, _, serviceAccountID, serviceAccountName string) (string, error) {
+			if serviceAccountID != "" || serviceAccountName == "" {
+				return serviceAccountID, nil
+			}
+
+			id, ok := accounts[serviceAccountName]
+			if !ok {
+				return "", errors.New("service account not found")
+			}
+
+			return id, nil
+		},
+	}
+}
+
+func TestDeployUsesExistingContainer(t *testing.T) {
+	client := &fakeClient{
+		containers: []*containers.Container{
+			{Id: "other-container", FolderId: "folder", Name: "other"},
+			{Id: "container-id", FolderId: "folder", Name: "app"},
+		},
+		revisions: []*containers.Revision{
+			{Id: "old-revision", ContainerId: "container-id", Status: containers.Revision_ACTIVE},
+			{Id: "inactive-revision", ContainerId: "container-id", Status: containers.Revision_OBSOLETE},
+		},
+	}
+
+	result, err := newTestDeployer(client, nil).Deploy(context.Background(), &container.Deployment{
+		FolderID:      "folder",
+		ContainerName: "app",
+		Options:       &container.CreateRevisionOptions{ImageURL: "cr.yandex/registry/app:1"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(client.created) != 0 {
+		t.Errorf("Expected no container to be created, got %v", client.created)
+	}
+
+	if len(client.deployed) != 1 {
+		t.Fatalf("Expected one deployed revision, got %d", len(client.deployed))
+	}
+
+	if client.deployed[0].ContainerId != "container-id" {
+		t.Errorf("Expected revision of container-id, got %q", client.deployed[0].ContainerId)
+	}
+
+	if client.deployed[0].ImageSpec.ImageUrl != "cr.yandex/registry/app:1" {
+		t.Errorf("Expected image cr.yandex/registry/app:1, got %q", client.deployed[0].ImageSpec.ImageUrl)
+	}
+
+	if len(client.rollbacks) != 0 {
+		t.Errorf("Expected no rollback, got %v", client.rollbacks)
+	}
+
+	expected := container.DeployResult{
+		ContainerID:        "container-id",
+		RevisionID:         "new-revision",
+		PreviousRevisionID: "old-revision",
+	}
+	if *result != expected {
+		t.Errorf("Expected result %+v, got %+v", expected, *result)
+	}
+}
+
+func TestDeployCreatesContainer(t *testing.T) {
+	client := &fakeClient{}
+
+	result, err := newTestDeployer(client, nil).Deploy(context.Background(), &container.Deployment{
+		FolderID:      "folder",
+		ContainerName: "app",
+		Options:       &container.CreateRevisionOptions{ImageURL: "cr.yandex/registry/app:1"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(client.created) != 1 || client.created[0].Name != "app" || client.created[0].FolderId != "folder" {
+		t.Fatalf("Expected container app to be created in folder, got %v", client.created)
+	}
+
+	if len(client.deployed) != 1 || client.deployed[0].ContainerId != "created-container" {
+		t.Fatalf("Expected revision of created-container, got %v", client.deployed)
+	}
+
+	if result.ContainerID != "created-container" || result.PreviousRevisionID != "" {
+		t.Errorf("Unexpected result %+v", *result)
+	}
+}
+
+func TestDeployResolvesServiceAccountName(t *testing.T) {
+	client := &fakeClient{
+		containers: []*containers.Container{{Id: "container-id", FolderId: "folder", Name: "app"}},
+	}
+	deployer := newTestDeployer(client, map[string]string{"deployer": "sa-id"})
+
+	_, err := deployer.Deploy(context.Background(), &container.Deployment{
+		FolderID:      "folder",
+		ContainerName: "app",
+		Options:       &container.CreateRevisionOptions{ServiceAccountName: "deployer"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if client.deployed[0].ServiceAccountId != "sa-id" {
+		t.Errorf("Expected service account sa-id, got %q", client.deployed[0].ServiceAccountId)
+	}
+
+	_, err = deployer.Deploy(context.Background(), &container.Deployment{
+		FolderID:      "folder",
+		ContainerName: "app",
+		Options:       &container.CreateRevisionOptions{ServiceAccountName: "missing"},
+	})
+	if err == nil {
+		t.Error("Expected error for unknown service account")
+	}
+
+	if len(client.deployed) != 1 {
+		t.Errorf("Expected no revision deployed with unknown service account, got %d", len(client.deployed))
+	}
+}
+
+func TestNewRevisionRequestSpec(t *testing.T) {
+	req := container.NewRevisionRequest(&container.CreateRevisionOptions{
+		ContainerID: "container-id",
+		Runtime:     container.RuntimeTask,
+		MetadataOptions: container.MetadataOptions{
+			GceHTTPEndpoint: container.MetadataOptionDisabled,
+		},
+		Mounts: []*container.Mount{
+			{MountPoint: "/tmp/disk", DiskSize: 1024},
+			{MountPoint: "/data", BucketID: "bucket", Prefix: "prefix", ReadOnly: true},
+		},
+		ScalingPolicy: &container.ScalingPolicy{ZoneInstancesLimit: 3, ZoneRequestsLimit: 10},
+	})
+
+	if req.GetRuntime().GetTask() == nil {
+		t.Errorf("Expected task runtime, got %v", req.GetRuntime())
+	}
+
+	if req.GetMetadataOptions().GetGceHttpEndpoint() != containers.MetadataOption_DISABLED ||
+		req.GetMetadataOptions().GetAwsV1HttpEndpoint() != containers.MetadataOption_METADATA_OPTION_UNSPECIFIED {
+		t.Errorf("Unexpected metadata options %v", req.GetMetadataOptions())
+	}
+
+	if len(req.Mounts) != 2 {
+		t.Fatalf("Expected 2 mounts, got %d", len(req.Mounts))
+	}
+
+	disk := req.Mounts[0]
+	if disk.MountPointPath != "/tmp/disk" || disk.Mode != containers.Mount_READ_WRITE ||
+		disk.GetEphemeralDiskSpec().GetSize() != 1024 {
+		t.Errorf("Unexpected ephemeral disk mount %v", disk)
+	}
+
+	bucket := req.Mounts[1]
+	if bucket.MountPointPath != "/data" || bucket.Mode != containers.Mount_READ_ONLY ||
+		bucket.GetObjectStorage().GetBucketId() != "bucket" || bucket.GetObjectStorage().GetPrefix() != "prefix" {
+		t.Errorf("Unexpected object storage mount %v", bucket)
+	}
+
+	if req.GetScalingPolicy().GetZoneInstancesLimit() != 3 || req.GetScalingPolicy().GetZoneRequestsLimit() != 10 {
+		t.Errorf("Unexpected scaling policy %v", req.GetScalingPolicy())
+	}
+
+	http := container.NewRevisionRequest(&container.CreateRevisionOptions{Runtime: container.RuntimeHTTP})
+	if http.GetRuntime().GetHttp() == nil || http.MetadataOptions != nil || http.ScalingPolicy != nil {
+		t.Errorf("Unexpected request %v", http)
+	}
+}
@@ -0,0 +1,58 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MetadataOption represents access to a metadata endpoint from inside an instance
+type MetadataOption int
+
+const (
+	// MetadataOptionUnspecified leaves the default access of the platform
+	MetadataOptionUnspecified MetadataOption = iota
+	// MetadataOptionEnabled enables access to the endpoint
+	MetadataOptionEnabled
+	// MetadataOptionDisabled disables access to the endpoint
+	MetadataOptionDisabled
+)
+
+// MetadataOptions represents access to the metadata endpoints
+type MetadataOptions struct {
+	GceHTTPEndpoint   MetadataOption
+	AwsV1HTTPEndpoint MetadataOption
+}
+
+// IsEmpty reports whether no metadata endpoint option is set
+func (m MetadataOptions) IsEmpty() bool {
+	return m.GceHTTPEndpoint == MetadataOptionUnspecified && m.AwsV1HTTPEndpoint == MetadataOptionUnspecified
+}
+
+// ParseMetadataOption parses a metadata endpoint option: "enabled", "disabled" or empty for unspecified
+func ParseMetadataOption(input string) (MetadataOption, error) {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "":
+		return MetadataOptionUnspecified, nil
+	case "enabled", "enable", "true":
+		return MetadataOptionEnabled, nil
+	case "disabled", "disable", "false":
+		return MetadataOptionDisabled, nil
+	default:
+		return MetadataOptionUnspecified, fmt.Errorf("invalid metadata option: %s", input)
+	}
+}
+
+// ParseMetadataOptions parses the options of the GCE and AWS v1 metadata endpoints
+func ParseMetadataOptions(gceHTTPEndpoint, awsV1HTTPEndpoint string) (MetadataOptions, error) {
+	gce, err := ParseMetadataOption(gceHTTPEndpoint)
+	if err != nil {
+		return MetadataOptions{}, fmt.Errorf("gce http endpoint: %w", err)
+	}
+
+	aws, err := ParseMetadataOption(awsV1HTTPEndpoint)
+	if err != nil {
+		return MetadataOptions{}, fmt.Errorf("aws v1 http endpoint: %w", err)
+	}
+
+	return MetadataOptions{GceHTTPEndpoint: gce, AwsV1HTTPEndpoint: aws}, nil
+}
@@ -0,0 +1,116 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yc-actions/sourcecraft-actions/pkg/memory"
+)
+
+const (
+	// Mount types
+	mountTypeObjectStorage = "object-storage"
+	mountTypeEphemeralDisk = "ephemeral-disk"
+)
+
+// Mount represents a mount of an object storage bucket or an ephemeral disk
+type Mount struct {
+	MountPoint string
+	ReadOnly   bool
+
+	// Object storage target
+	BucketID string
+	Prefix   string
+
+	// Ephemeral disk target, size in bytes
+	DiskSize int64
+}
+
+// IsEphemeralDisk reports whether the mount is an ephemeral disk
+func (m *Mount) IsEphemeralDisk() bool {
+	return m.DiskSize > 0
+}
+
+// ParseMount parses a mount string in one of the formats
+// "object-storage:BUCKET[/PREFIX]:MOUNT_POINT[:ACCESS_MODE]" (defaults to read-only) or
+// "ephemeral-disk:SIZE:MOUNT_POINT[:ACCESS_MODE]" (defaults to read-write)
+func ParseMount(input string) (*Mount, error) {
+	parts := strings.Split(input, storageDelimiter)
+
+	if len(parts) < 3 || len(parts) > 4 {
+		return nil, fmt.Errorf("mount has wrong format: %s", input)
+	}
+
+	mountType := strings.ToLower(strings.TrimSpace(parts[0]))
+	target := strings.TrimSpace(parts[1])
+	mountPoint := strings.TrimSpace(parts[2])
+
+	if target == "" {
+		return nil, fmt.Errorf("mount has empty target: %s", input)
+	}
+
+	if mountPoint == "" {
+		return nil, fmt.Errorf("mount has empty mount point: %s", input)
+	}
+
+	mount := &Mount{
+		MountPoint: mountPoint,
+	}
+
+	switch mountType {
+	case mountTypeObjectStorage:
+		targetParts := strings.Split(target, pathDelimiter)
+		mount.BucketID = targetParts[0]
+		mount.Prefix = strings.Join(targetParts[1:], pathDelimiter)
+		mount.ReadOnly = true
+	case mountTypeEphemeralDisk:
+		size, err := memory.ParseMemory(target)
+		if err != nil {
+			return nil, fmt.Errorf("mount has invalid disk size %q: %w", target, err)
+		}
+
+		if size == 0 {
+			return nil, fmt.Errorf("mount has zero disk size: %s", input)
+		}
+
+		mount.DiskSize = size
+	default:
+		return nil, fmt.Errorf("mount has unknown type %q, expected %s or %s",
+			mountType, mountTypeObjectStorage, mountTypeEphemeralDisk)
+	}
+
+	if len(parts) == 4 {
+		var err error
+
+		mount.ReadOnly, err = parseAccessMode(parts[3], mount.ReadOnly)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mount, nil
+}
+
+// ParseMounts parses multiple mount strings
+func ParseMounts(inputs []string) ([]*Mount, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	mounts := make([]*Mount, 0, len(inputs))
+
+	for _, input := range inputs {
+		if input == "" {
+			continue
+		}
+
+		mount, err := ParseMount(input)
+		if err != nil {
+			return nil, err
+		}
+
+		mounts = append(mounts, mount)
+	}
+
+	return mounts, nil
+}
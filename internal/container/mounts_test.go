@@ -0,0 +1,123 @@
+package container_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yc-actions/sourcecraft-actions/internal/container"
+	"github.com/yc-actions/sourcecraft-actions/pkg/memory"
+)
+
+func TestParseMounts(t *testing.T) {
+	t.Run("empty input", func(t *testing.T) {
+		mounts, err := container.ParseMounts(nil)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if mounts != nil {
+			t.Errorf("Expected nil mounts, got %v", mounts)
+		}
+	})
+
+	t.Run("valid inputs", func(t *testing.T) {
+		inputs := []string{
+			"object-storage:bucket1/folder/sub:/data",
+			"",
+			"object-storage:bucket2:/data2:rw",
+			"ephemeral-disk:5Gb:/tmp/disk",
+			"ephemeral-disk:512Mb:/tmp/ro:ro",
+		}
+
+		expected := []*container.Mount{
+			{MountPoint: "/data", ReadOnly: true, BucketID: "bucket1", Prefix: "folder/sub"},
+			{MountPoint: "/data2", ReadOnly: false, BucketID: "bucket2"},
+			{MountPoint: "/tmp/disk", ReadOnly: false, DiskSize: 5 * memory.GB},
+			{MountPoint: "/tmp/ro", ReadOnly: true, DiskSize: 512 * memory.MB},
+		}
+
+		mounts, err := container.ParseMounts(inputs)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if !reflect.DeepEqual(mounts, expected) {
+			t.Errorf("Expected %v, got %v", expected, mounts)
+		}
+
+		if mounts[0].IsEphemeralDisk() || !mounts[2].IsEphemeralDisk() {
+			t.Errorf("Expected only disk mounts to be ephemeral disks")
+		}
+	})
+
+	t.Run("invalid inputs", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			input string
+		}{
+			{name: "wrong format", input: "object-storage:bucket1"},
+			{name: "too many parts", input: "object-storage:bucket1:/data:ro:extra"},
+			{name: "unknown type", input: "nfs:server:/data"},
+			{name: "empty target", input: "object-storage::/data"},
+			{name: "empty mount point", input: "object-storage:bucket1:"},
+			{name: "invalid disk size", input: "ephemeral-disk:5Tb:/data"},
+			{name: "zero disk size", input: "ephemeral-disk:0Gb:/data"},
+			{name: "invalid access mode", input: "ephemeral-disk:5Gb:/data:invalid-mode"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mounts, err := container.ParseMounts([]string{tc.input})
+
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+
+				if mounts != nil {
+					t.Errorf("Expected nil mounts, got %v", mounts)
+				}
+			})
+		}
+	})
+}
+
+func TestParseMetadataOptions(t *testing.T) {
+	t.Run("valid inputs", func(t *testing.T) {
+		options, err := container.ParseMetadataOptions("Enabled", "disabled")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		expected := container.MetadataOptions{
+			GceHTTPEndpoint:   container.MetadataOptionEnabled,
+			AwsV1HTTPEndpoint: container.MetadataOptionDisabled,
+		}
+
+		if options != expected {
+			t.Errorf("Expected %v, got %v", expected, options)
+		}
+	})
+
+	t.Run("empty inputs", func(t *testing.T) {
+		options, err := container.ParseMetadataOptions("", "")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if !options.IsEmpty() {
+			t.Errorf("Expected empty options, got %v", options)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := container.ParseMetadataOptions("", "sometimes")
+
+		if err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package container
 
 import (
 	"fmt"
+	"path"
 
 	"github.com/yc-actions/sourcecraft-actions/pkg/env"
 	"github.com/yc-actions/sourcecraft-actions/pkg/loglevel"
@@ -11,27 +12,33 @@ import (
 
 // CreateRevisionOptions contains all the options for creating a revision.
 type CreateRevisionOptions struct {
-	ContainerID      string
-	ImageURL         string
-	MemoryValue      int64
-	Cores            int64
-	CoreFraction     int64
-	Concurrency      int64
-	ExecutionTimeout int64
-	WorkingDir       string
-	Commands         []string
-	Args             []string
-	Env              map[string]string
-	Secrets          []*Secret
-	Provisioned      *int64
-	NetworkID        string
-	ServiceAccountID string
-	LogOptions       *LogOptions
-	StorageMounts    []*StorageMount
+	ContainerID        string
+	ImageURL           string
+	MemoryValue        int64
+	Cores              int64
+	CoreFraction       int64
+	Concurrency        int64
+	ExecutionTimeout   int64
+	WorkingDir         string
+	Commands           []string
+	Args               []string
+	Env                map[string]string
+	Secrets            []*Secret
+	Provisioned        *int64
+	NetworkID          string
+	ServiceAccountID   string
+	ServiceAccountName string
+	LogOptions         *LogOptions
+	StorageMounts      []*StorageMount
+	Mounts             []*Mount
+	Runtime            string
+	MetadataOptions    MetadataOptions
+	ScalingPolicy      *ScalingPolicy
 }
 
 const (
 	inputRevisionServiceAccountID     = "REVISION_SERVICE_ACCOUNT_ID"
+	inputRevisionServiceAccountName   = "REVISION_SERVICE_ACCOUNT_NAME"
 	inputRevisionCores                = "REVISION_CORES"
 	inputRevisionMemory               = "REVISION_MEMORY"
 	inputRevisionCoreFraction         = "REVISION_CORE_FRACTION"
@@ -50,6 +57,12 @@ const (
 	inputRevisionLogOptionsFolderID   = "REVISION_LOG_OPTIONS_FOLDER_ID"
 	inputRevisionLogOptionsMinLevel   = "REVISION_LOG_OPTIONS_MIN_LEVEL"
 	inputRevisionStorageMounts        = "REVISION_STORAGE_MOUNTS"
+	inputRevisionMounts               = "REVISION_MOUNTS"
+	inputRevisionRuntime              = "REVISION_RUNTIME"
+	inputRevisionMetadataGce          = "REVISION_METADATA_GCE_HTTP_ENDPOINT"
+	inputRevisionMetadataAwsV1        = "REVISION_METADATA_AWS_V1_HTTP_ENDPOINT"
+	inputRevisionZoneInstancesLimit   = "REVISION_ZONE_INSTANCES_LIMIT"
+	inputRevisionZoneRequestsLimit    = "REVISION_ZONE_REQUESTS_LIMIT"
 )
 
 func (r CreateRevisionOptions) Log() {
@@ -91,6 +104,8 @@ func (r CreateRevisionOptions) Log() {
 
 	if r.ServiceAccountID != "" {
 		sourcecraft.Info(fmt.Sprintf("Service account ID: %s", r.ServiceAccountID))
+	} else if r.ServiceAccountName != "" {
+		sourcecraft.Info(fmt.Sprintf("Service account name: %s", r.ServiceAccountName))
 	}
 
 	if r.LogOptions.Disabled {
@@ -107,6 +122,15 @@ func (r CreateRevisionOptions) Log() {
 		sourcecraft.Info(fmt.Sprintf("Log level: %v", r.LogOptions.MinLevel))
 	}
 
+	if r.Runtime != "" {
+		sourcecraft.Info(fmt.Sprintf("Runtime: %s", r.Runtime))
+	}
+
+	if r.ScalingPolicy != nil {
+		sourcecraft.Info(fmt.Sprintf("Zone instances limit: %d, zone requests limit: %d",
+			r.ScalingPolicy.ZoneInstancesLimit, r.ScalingPolicy.ZoneRequestsLimit))
+	}
+
 	if r.StorageMounts != nil {
 		sourcecraft.Info(fmt.Sprintf("Storage mounts: %d", len(r.StorageMounts)))
 
@@ -122,10 +146,39 @@ func (r CreateRevisionOptions) Log() {
 			)
 		}
 	}
+
+	if r.Mounts != nil {
+		sourcecraft.Info(fmt.Sprintf("Mounts: %d", len(r.Mounts)))
+
+		for i, mount := range r.Mounts {
+			target := mount.BucketID
+			if mount.IsEphemeralDisk() {
+				target = fmt.Sprintf("ephemeral disk of %d bytes", mount.DiskSize)
+			}
+
+			sourcecraft.Info(
+				fmt.Sprintf("  Mount %d: %s -> %s (read-only: %v)", i+1, target, mount.MountPoint, mount.ReadOnly),
+			)
+		}
+	}
 }
 
 func ParseRevOptions() (*CreateRevisionOptions, error) {
-	imageURL := sourcecraft.GetInput(inputRevisionImageURL)
+	return ParseRevOptionsWithImage("")
+}
+
+// ParseRevOptionsWithImage parses the revision options with the image URL of an image built by the action
+// instead of the revision-image-url input. An empty image URL requires the input.
+func ParseRevOptionsWithImage(imageURL string) (*CreateRevisionOptions, error) {
+	inputImageURL := sourcecraft.GetInput(inputRevisionImageURL)
+
+	switch {
+	case imageURL != "" && inputImageURL != "":
+		return nil, fmt.Errorf("revision-image-url can't be set when the image is built")
+	case imageURL == "":
+		imageURL = inputImageURL
+	}
+
 	if imageURL == "" {
 		return nil, fmt.Errorf("revision-image-url is required")
 	}
@@ -164,6 +217,9 @@ func ParseRevOptions() (*CreateRevisionOptions, error) {
 
 		// Parse service account ID
 		ServiceAccountID: sourcecraft.GetInput(inputRevisionServiceAccountID),
+
+		// Parse service account name
+		ServiceAccountName: sourcecraft.GetInput(inputRevisionServiceAccountName),
 	}
 	// Parse memory
 
@@ -223,5 +279,69 @@ func ParseRevOptions() (*CreateRevisionOptions, error) {
 		return nil, fmt.Errorf("failed to parse revision-storage-mounts: %w", err)
 	}
 
+	// Parse mounts
+	res.Mounts, err = ParseMounts(sourcecraft.GetMultilineInput(inputRevisionMounts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse revision-mounts: %w", err)
+	}
+
+	err = validateMountPoints(res.StorageMounts, res.Mounts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse runtime
+	res.Runtime, err = ParseRuntime(sourcecraft.GetInput(inputRevisionRuntime))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse revision-runtime: %w", err)
+	}
+
+	// Parse metadata options
+	res.MetadataOptions, err = ParseMetadataOptions(
+		sourcecraft.GetInput(inputRevisionMetadataGce),
+		sourcecraft.GetInput(inputRevisionMetadataAwsV1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse revision metadata options: %w", err)
+	}
+
+	// Parse scaling policy
+	res.ScalingPolicy, err = ParseScalingPolicy(
+		sourcecraft.GetInput(inputRevisionZoneInstancesLimit),
+		sourcecraft.GetInput(inputRevisionZoneRequestsLimit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse revision scaling policy: %w", err)
+	}
+
 	return res, nil
 }
+
+// validateMountPoints checks that the mount points are absolute paths and are not used twice.
+func validateMountPoints(storageMounts []*StorageMount, mounts []*Mount) error {
+	mountPoints := make([]string, 0, len(storageMounts)+len(mounts))
+	for _, mount := range storageMounts {
+		mountPoints = append(mountPoints, mount.MountPointPath)
+	}
+
+	for _, mount := range mounts {
+		if !path.IsAbs(mount.MountPoint) {
+			return fmt.Errorf("mount point must be an absolute path, got %q", mount.MountPoint)
+		}
+
+		mountPoints = append(mountPoints, mount.MountPoint)
+	}
+
+	seen := make(map[string]bool, len(mountPoints))
+
+	for _, mountPoint := range mountPoints {
+		mountPoint = path.Clean(mountPoint)
+		if seen[mountPoint] {
+			return fmt.Errorf("mount point %s is used twice", mountPoint)
+		}
+
+		seen[mountPoint] = true
+	}
+
+	return nil
+}
@@ -6,23 +6,24 @@ import (
 
 func TestParseRevOptions(t *testing.T) {
 	// Set up environment variables for testing
-	t.Setenv("revision-image-url", "test-image-url")
-	t.Setenv("revision-cores", "2")
-	t.Setenv("revision-core-fraction", "50")
-	t.Setenv("revision-concurrency", "5")
-	t.Setenv("revision-execution-timeout", "10")
-	t.Setenv("revision-memory", "256Mb")
-	t.Setenv("revision-working-dir", "/app")
-	t.Setenv("revision-commands", "cmd1\ncmd2")
-	t.Setenv("revision-args", "arg1\narg2")
-	t.Setenv("revision-env", "KEY1=value1\nKEY2=value2")
-	t.Setenv("revision-secrets", "ENV_VAR1=secret1/version1/key1\nENV_VAR2=secret2/version2/key2")
-	t.Setenv("revision-network-id", "network-id")
-	t.Setenv("revision-service-account-id", "sa-id")
-	t.Setenv("revision-log-options-disabled", "false")
-	t.Setenv("revision-log-options-log-group-id", "log-group-id")
-	t.Setenv("revision-log-options-min-level", "INFO")
-	t.Setenv("revision-storage-mounts", "bucket1/folder:mountpoint1\nbucket2:/mountpoint2:read-only")
+	t.Setenv("REVISION_IMAGE_URL", "test-image-url")
+	t.Setenv("REVISION_CORES", "2")
+	t.Setenv("REVISION_CORE_FRACTION", "50")
+	t.Setenv("REVISION_CONCURRENCY", "5")
+	t.Setenv("REVISION_EXECUTION_TIMEOUT", "10")
+	t.Setenv("REVISION_MEMORY", "256Mb")
+	t.Setenv("REVISION_WORKING_DIR", "/app")
+	t.Setenv("REVISION_COMMANDS", "cmd1\ncmd2")
+	t.Setenv("REVISION_ARGS", "arg1\narg2")
+	t.Setenv("REVISION_ENV", "KEY1=value1\nKEY2=value2")
+	t.Setenv("REVISION_SECRETS", "ENV_VAR1=secret1/version1/key1\nENV_VAR2=secret2/version2/key2")
+	t.Setenv("REVISION_NETWORK_ID", "network-id")
+	t.Setenv("REVISION_SERVICE_ACCOUNT_ID", "sa-id")
+	t.Setenv("REVISION_SERVICE_ACCOUNT_NAME", "sa-name")
+	t.Setenv("REVISION_LOG_OPTIONS_DISABLED", "false")
+	t.Setenv("REVISION_LOG_OPTIONS_LOG_GROUP_ID", "log-group-id")
+	t.Setenv("REVISION_LOG_OPTIONS_MIN_LEVEL", "INFO")
+	t.Setenv("REVISION_STORAGE_MOUNTS", "bucket1/folder:mountpoint1\nbucket2:/mountpoint2:read-only")
 
 	// Test the function
 	got, err := ParseRevOptions()
@@ -78,6 +79,9 @@ func TestParseRevOptions(t *testing.T) {
 	if got.ServiceAccountID != "sa-id" {
 		t.Errorf("ParseRevOptions() ServiceAccountID = %v, want %v", got.ServiceAccountID, "sa-id")
 	}
+	if got.ServiceAccountName != "sa-name" {
+		t.Errorf("ParseRevOptions() ServiceAccountName = %v, want %v", got.ServiceAccountName, "sa-name")
+	}
 	if got.LogOptions.Disabled {
 		t.Errorf("ParseRevOptions() LogOptions.Disabled = %v, want %v", got.LogOptions.Disabled, false)
 	}
@@ -95,3 +99,130 @@ func TestParseRevOptions(t *testing.T) {
 		}
 	}
 }
+
+func TestParseRevOptionsWithImage(t *testing.T) {
+	t.Setenv("REVISION_IMAGE_URL", "")
+	t.Setenv("REVISION_MEMORY", "128Mb")
+
+	got, err := ParseRevOptionsWithImage("cr.yandex/registry/app:abc123")
+	if err != nil {
+		t.Fatalf("ParseRevOptionsWithImage() error = %v", err)
+	}
+	if got.ImageURL != "cr.yandex/registry/app:abc123" {
+		t.Errorf("ParseRevOptionsWithImage() ImageURL = %v, want %v", got.ImageURL, "cr.yandex/registry/app:abc123")
+	}
+
+	_, err = ParseRevOptions()
+	if err == nil {
+		t.Error("ParseRevOptions() expected error without image URL")
+	}
+
+	t.Setenv("REVISION_IMAGE_URL", "cr.yandex/registry/other:1")
+
+	_, err = ParseRevOptionsWithImage("cr.yandex/registry/app:abc123")
+	if err == nil {
+		t.Error("ParseRevOptionsWithImage() expected error with both image URLs")
+	}
+}
+
+func TestParseRevOptionsSpec(t *testing.T) {
+	t.Setenv("REVISION_IMAGE_URL", "test-image-url")
+	t.Setenv("REVISION_MEMORY", "128Mb")
+	t.Setenv("REVISION_RUNTIME", "Task")
+	t.Setenv("REVISION_METADATA_GCE_HTTP_ENDPOINT", "disabled")
+	t.Setenv("REVISION_METADATA_AWS_V1_HTTP_ENDPOINT", "enabled")
+	t.Setenv("REVISION_MOUNTS", "ephemeral-disk:5Gb:/tmp/disk\nobject-storage:bucket/prefix:/data:rw")
+	t.Setenv("REVISION_STORAGE_MOUNTS", "bucket2:/mountpoint2")
+	t.Setenv("REVISION_ZONE_INSTANCES_LIMIT", "3")
+	t.Setenv("REVISION_ZONE_REQUESTS_LIMIT", "")
+	t.Setenv("REVISION_LOG_OPTIONS_FOLDER_ID", "log-folder-id")
+
+	got, err := ParseRevOptions()
+	if err != nil {
+		t.Fatalf("ParseRevOptions() error = %v", err)
+	}
+
+	if got.Runtime != RuntimeTask {
+		t.Errorf("ParseRevOptions() Runtime = %v, want %v", got.Runtime, RuntimeTask)
+	}
+	wantMetadata := MetadataOptions{GceHTTPEndpoint: MetadataOptionDisabled, AwsV1HTTPEndpoint: MetadataOptionEnabled}
+	if got.MetadataOptions != wantMetadata {
+		t.Errorf("ParseRevOptions() MetadataOptions = %v, want %v", got.MetadataOptions, wantMetadata)
+	}
+	if len(got.Mounts) != 2 {
+		t.Fatalf("ParseRevOptions() Mounts length = %v, want %v", len(got.Mounts), 2)
+	}
+	if got.Mounts[0].DiskSize != 5*1024*1024*1024 || got.Mounts[0].MountPoint != "/tmp/disk" || got.Mounts[0].ReadOnly {
+		t.Errorf("ParseRevOptions() Mounts[0] = %v, want DiskSize=5Gb, MountPoint=/tmp/disk, ReadOnly=false", got.Mounts[0])
+	}
+	if got.Mounts[1].BucketID != "bucket" || got.Mounts[1].Prefix != "prefix" || got.Mounts[1].MountPoint != "/data" || got.Mounts[1].ReadOnly {
+		t.Errorf("ParseRevOptions() Mounts[1] = %v, want BucketID=bucket, Prefix=prefix, MountPoint=/data, ReadOnly=false", got.Mounts[1])
+	}
+	if got.ScalingPolicy == nil || got.ScalingPolicy.ZoneInstancesLimit != 3 || got.ScalingPolicy.ZoneRequestsLimit != 0 {
+		t.Errorf("ParseRevOptions() ScalingPolicy = %v, want ZoneInstancesLimit=3, ZoneRequestsLimit=0", got.ScalingPolicy)
+	}
+	if got.LogOptions.FolderID != "log-folder-id" {
+		t.Errorf("ParseRevOptions() LogOptions.FolderID = %v, want %v", got.LogOptions.FolderID, "log-folder-id")
+	}
+}
+
+func TestParseRevOptionsSpecDefaults(t *testing.T) {
+	t.Setenv("REVISION_IMAGE_URL", "test-image-url")
+	t.Setenv("REVISION_MEMORY", "128Mb")
+
+	got, err := ParseRevOptions()
+	if err != nil {
+		t.Fatalf("ParseRevOptions() error = %v", err)
+	}
+
+	if got.Runtime != RuntimeHTTP {
+		t.Errorf("ParseRevOptions() Runtime = %v, want %v", got.Runtime, RuntimeHTTP)
+	}
+	if !got.MetadataOptions.IsEmpty() {
+		t.Errorf("ParseRevOptions() MetadataOptions = %v, want empty", got.MetadataOptions)
+	}
+	if got.Mounts != nil {
+		t.Errorf("ParseRevOptions() Mounts = %v, want nil", got.Mounts)
+	}
+	if got.ScalingPolicy != nil {
+		t.Errorf("ParseRevOptions() ScalingPolicy = %v, want nil", got.ScalingPolicy)
+	}
+}
+
+func TestParseRevOptionsSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"unknown runtime", map[string]string{"REVISION_RUNTIME": "grpc"}},
+		{"invalid metadata option", map[string]string{"REVISION_METADATA_GCE_HTTP_ENDPOINT": "sometimes"}},
+		{"invalid mount", map[string]string{"REVISION_MOUNTS": "ephemeral-disk:0:/tmp/disk"}},
+		{"relative mount point", map[string]string{"REVISION_MOUNTS": "object-storage:bucket:data"}},
+		{"duplicate mount point", map[string]string{
+			"REVISION_MOUNTS":         "object-storage:bucket:/data",
+			"REVISION_STORAGE_MOUNTS": "bucket2:/data/",
+		}},
+		{"negative zone instances limit", map[string]string{"REVISION_ZONE_INSTANCES_LIMIT": "-1"}},
+		{"invalid zone requests limit", map[string]string{"REVISION_ZONE_REQUESTS_LIMIT": "many"}},
+		{"log group and folder", map[string]string{
+			"REVISION_LOG_OPTIONS_LOG_GROUP_ID": "log-group-id",
+			"REVISION_LOG_OPTIONS_FOLDER_ID":    "log-folder-id",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REVISION_IMAGE_URL", "test-image-url")
+			t.Setenv("REVISION_MEMORY", "128Mb")
+
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := ParseRevOptions()
+			if err == nil {
+				t.Errorf("ParseRevOptions() expected error")
+			}
+		})
+	}
+}
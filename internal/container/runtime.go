@@ -0,0 +1,26 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Runtimes of a revision.
+const (
+	// RuntimeHTTP serves HTTP requests.
+	RuntimeHTTP = "http"
+	// RuntimeTask runs a task per invocation.
+	RuntimeTask = "task"
+)
+
+// ParseRuntime parses the runtime of the revision, http by default.
+func ParseRuntime(input string) (string, error) {
+	switch runtime := strings.ToLower(strings.TrimSpace(input)); runtime {
+	case "":
+		return RuntimeHTTP, nil
+	case RuntimeHTTP, RuntimeTask:
+		return runtime, nil
+	default:
+		return "", fmt.Errorf("unknown runtime %q, expected one of %s, %s", input, RuntimeHTTP, RuntimeTask)
+	}
+}
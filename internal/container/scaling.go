@@ -0,0 +1,48 @@
+package container
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ScalingPolicy limits the instances and the concurrent requests of the revision in each zone,
+// a zero limit means no limit.
+type ScalingPolicy struct {
+	ZoneInstancesLimit int64
+	ZoneRequestsLimit  int64
+}
+
+// parseLimit parses a non-negative limit, zero if empty.
+func parseLimit(input string) (int64, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.ParseInt(input, 10, 64)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("limit must be a non-negative number, got %q", input)
+	}
+
+	return limit, nil
+}
+
+// ParseScalingPolicy parses the zone instances and requests limits, or returns nil if neither is set.
+func ParseScalingPolicy(zoneInstancesLimit, zoneRequestsLimit string) (*ScalingPolicy, error) {
+	instances, err := parseLimit(zoneInstancesLimit)
+	if err != nil {
+		return nil, fmt.Errorf("zone instances limit: %w", err)
+	}
+
+	requests, err := parseLimit(zoneRequestsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("zone requests limit: %w", err)
+	}
+
+	if instances == 0 && requests == 0 {
+		return nil, nil
+	}
+
+	return &ScalingPolicy{ZoneInstancesLimit: instances, ZoneRequestsLimit: requests}, nil
+}
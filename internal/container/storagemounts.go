@@ -64,16 +64,11 @@ func ParseStorageMount(input string) (*StorageMount, error) {
 
 	// Parse access mode if provided
 	if len(parts) == 3 {
-		accessMode := strings.TrimSpace(parts[2])
-		if accessMode != "" {
-			switch strings.ToLower(accessMode) {
-			case "read-only", "ro", "readonly", "read_only":
-				readOnly = true
-			case "read-write", "rw", "readwrite", "read_write":
-				readOnly = false
-			default:
-				return nil, fmt.Errorf("invalid access mode: %s", accessMode)
-			}
+		var err error
+
+		readOnly, err = parseAccessMode(parts[2], readOnly)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -85,6 +80,23 @@ func ParseStorageMount(input string) (*StorageMount, error) {
 	}, nil
 }
 
+// parseAccessMode parses an access mode, returning whether it is read-only.
+// An empty access mode gives the default.
+func parseAccessMode(input string, defaultReadOnly bool) (bool, error) {
+	accessMode := strings.TrimSpace(input)
+
+	switch strings.ToLower(accessMode) {
+	case "":
+		return defaultReadOnly, nil
+	case "read-only", "ro", "readonly", "read_only":
+		return true, nil
+	case "read-write", "rw", "readwrite", "read_write":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid access mode: %s", accessMode)
+	}
+}
+
 // ParseStorageMounts parses multiple storage mount strings
 func ParseStorageMounts(inputs []string) ([]*StorageMount, error) {
 	if len(inputs) == 0 {
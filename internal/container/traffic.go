@@ -0,0 +1,60 @@
+package container
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Modes of the container action.
+const (
+	// ModeDeploy deploys a new revision.
+	ModeDeploy = "deploy"
+	// ModeRollback makes a previous revision the serving one.
+	ModeRollback = "rollback"
+)
+
+// TrafficAll is the only percentage of traffic a new revision can get: a container serves a single revision,
+// and the Serverless Containers API can neither split the traffic between revisions nor deploy a revision
+// that gets none.
+const TrafficAll = 100
+
+// ValidateTraffic checks the percentage of traffic for the new revision, which can only be 100.
+func ValidateTraffic(input string) error {
+	input = strings.TrimSuffix(strings.TrimSpace(input), "%")
+	if input == "" {
+		return nil
+	}
+
+	traffic, err := strconv.Atoi(input)
+	if err != nil {
+		return fmt.Errorf("traffic has wrong format: %s", input)
+	}
+
+	if traffic != TrafficAll {
+		return fmt.Errorf(
+			"traffic of %d%% is not supported: the Serverless Containers API can't split traffic between revisions, "+
+				"a new revision always gets %d%%",
+			traffic,
+			TrafficAll,
+		)
+	}
+
+	return nil
+}
+
+// ValidateMode validates the mode and the revision to roll back to.
+func ValidateMode(mode, rollbackRevisionID string) error {
+	switch mode {
+	case ModeDeploy:
+		return nil
+	case ModeRollback:
+		if rollbackRevisionID == "" {
+			return fmt.Errorf("rollback-revision-id is required in %s mode", mode)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %q, expected one of %s, %s", mode, ModeDeploy, ModeRollback)
+	}
+}
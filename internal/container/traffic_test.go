@@ -0,0 +1,39 @@
+package container_test
+
+import (
+	"testing"
+
+	"github.com/yc-actions/sourcecraft-actions/internal/container"
+)
+
+func TestValidateTraffic(t *testing.T) {
+	for _, input := range []string{"", "100", " 100% "} {
+		if err := container.ValidateTraffic(input); err != nil {
+			t.Errorf("Expected no error for %q, got %v", input, err)
+		}
+	}
+
+	for _, input := range []string{"half", "0", "-1", "101", "50"} {
+		if err := container.ValidateTraffic(input); err == nil {
+			t.Errorf("Expected error for %q, got nil", input)
+		}
+	}
+}
+
+func TestValidateMode(t *testing.T) {
+	if err := container.ValidateMode(container.ModeDeploy, ""); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if err := container.ValidateMode(container.ModeRollback, "rev-id"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if err := container.ValidateMode(container.ModeRollback, ""); err == nil {
+		t.Errorf("Expected error for rollback without revision, got nil")
+	}
+
+	if err := container.ValidateMode("promote", ""); err == nil {
+		t.Errorf("Expected error for unknown mode, got nil")
+	}
+}
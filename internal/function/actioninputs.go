@@ -1,9 +1,19 @@
 package function
 
-import "github.com/yandex-cloud/go-genproto/yandex/cloud/logging/v1"
+import (
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/access"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/logging/v1"
+	"github.com/yc-actions/sourcecraft-actions/internal/container"
+)
 
 // ActionInputs represents the input parameters for the GitHub Action.
 type ActionInputs struct {
+	Mode               string
+	Version            string
+	SetTags            []string
+	RemoveTags         []string
+	PromoteFrom        string
+	PromoteTo          string
 	FolderID           string
 	FunctionName       string
 	Runtime            string
@@ -12,6 +22,9 @@ type ActionInputs struct {
 	Include            []string
 	ExcludePattern     []string
 	SourceRoot         string
+	CompressionLevel   int
+	Build              bool
+	BuildCommand       string
 	ExecutionTimeout   int
 	Environment        []string
 	ServiceAccount     string
@@ -23,7 +36,17 @@ type ActionInputs struct {
 	Tags               []string
 	LogsDisabled       bool
 	LogsGroupID        string
+	LogsFolderID       string
 	LogLevel           logging.LogLevel_Level
+	Concurrency        int64
+	TmpfsSize          int64
+	Provisioned        *int64
+	ScalingPolicies    []*ScalingPolicy
+	PruneScaling       bool
+	AccessBindings     []*access.AccessBinding
+	StorageMounts      []*container.StorageMount
+	Mounts             []*container.Mount
+	MetadataOptions    container.MetadataOptions
 
 	Async              bool
 	AsyncSaID          string
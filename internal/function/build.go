@@ -0,0 +1,182 @@
+package function
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// IsBuild checks if the sources are built before packaging.
+func IsBuild(inputs *ActionInputs) bool {
+	return inputs.Build || inputs.BuildCommand != ""
+}
+
+// BuildCommand returns the command installing the dependencies of the sources in the directory
+// for the runtime, or nil if there is nothing to install. A custom command is run by the shell.
+func BuildCommand(runtime, custom, dir string) []string {
+	if custom != "" {
+		return []string{"sh", "-c", custom}
+	}
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+
+		return err == nil
+	}
+
+	switch {
+	case strings.HasPrefix(runtime, "python") && exists("requirements.txt"):
+		return []string{"pip", "install", "-r", "requirements.txt", "-t", "."}
+	case strings.HasPrefix(runtime, "nodejs") && exists("package-lock.json"):
+		return []string{"npm", "ci", "--omit=dev"}
+	case strings.HasPrefix(runtime, "nodejs") && exists("package.json"):
+		return []string{"npm", "install", "--omit=dev"}
+	case strings.HasPrefix(runtime, "golang") && exists("go.mod"):
+		return []string{"go", "mod", "vendor"}
+	default:
+		return nil
+	}
+}
+
+// CheckBuildTool fails if the program of the build command of the sources isn't installed. The action image
+// has no toolchains, so the build relies on the ones of the image the action runs in.
+func CheckBuildTool(inputs *ActionInputs) error {
+	command := BuildCommand(inputs.Runtime, inputs.BuildCommand, sourceRoot(inputs))
+	if command == nil {
+		return nil
+	}
+
+	return lookupBuildTool(command)
+}
+
+// lookupBuildTool checks that the program of the command is in PATH.
+func lookupBuildTool(command []string) error {
+	_, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf(
+			"%s is required to run %q but isn't installed: run the action in an image with the toolchain "+
+				"of the runtime, or install the dependencies in an earlier step and package them without BUILD",
+			command[0],
+			strings.Join(command, " "),
+		)
+	}
+
+	return nil
+}
+
+// BuildPackage copies the source files to a temporary staging directory, runs the build command
+// there and zips the whole staged directory, so the workspace is left untouched.
+func BuildPackage(ctx context.Context, inputs *ActionInputs) (*Package, error) {
+	sourcecraft.StartGroup("Build")
+	defer sourcecraft.EndGroup()
+
+	files, err := listSources(inputs, sourceRoot(inputs))
+	if err != nil {
+		return nil, err
+	}
+
+	staging, err := os.MkdirTemp("", "function-build-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	for name, path := range files {
+		err = copyFile(path, filepath.Join(staging, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	command := BuildCommand(inputs.Runtime, inputs.BuildCommand, staging)
+	if command == nil {
+		sourcecraft.Info(fmt.Sprintf("Nothing to build for runtime %s", inputs.Runtime))
+	} else {
+		err = lookupBuildTool(command)
+		if err != nil {
+			return nil, err
+		}
+
+		sourcecraft.Info(fmt.Sprintf("Run: %s", strings.Join(command, " ")))
+
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+		cmd.Dir = staging
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		err = cmd.Run()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run build command: %w", err)
+		}
+	}
+
+	staged, err := listFiles(staging)
+	if err != nil {
+		return nil, err
+	}
+
+	return zipFiles(staged, inputs.CompressionLevel)
+}
+
+// listFiles returns all files under the root keyed by their path in the archive.
+func listFiles(root string) (map[string]string, error) {
+	files := make(map[string]string)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+
+		files[filepath.ToSlash(relPath)] = path
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk staging directory: %w", err)
+	}
+
+	return files, nil
+}
+
+// copyFile copies the file keeping its mode, creating the parent directories.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	return nil
+}
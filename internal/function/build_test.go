@@ -0,0 +1,96 @@
+package function_test
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+)
+
+func TestBuildCommand(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"requirements.txt", "package.json", "go.mod"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+
+	assert.Equal(t,
+		[]string{"pip", "install", "-r", "requirements.txt", "-t", "."},
+		function.BuildCommand("python312", "", dir),
+	)
+	assert.Equal(t, []string{"npm", "install", "--omit=dev"}, function.BuildCommand("nodejs18", "", dir))
+	assert.Equal(t, []string{"go", "mod", "vendor"}, function.BuildCommand("golang121", "", dir))
+	assert.Equal(t, []string{"sh", "-c", "make deps"}, function.BuildCommand("golang121", "make deps", dir))
+	assert.Nil(t, function.BuildCommand("java21", "", dir))
+	assert.Nil(t, function.BuildCommand("python312", "", t.TempDir()))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package-lock.json"), nil, 0o644))
+	assert.Equal(t, []string{"npm", "ci", "--omit=dev"}, function.BuildCommand("nodejs18", "", dir))
+}
+
+func TestBuildPackage(t *testing.T) {
+	workspace := writeSources(t, map[string]string{
+		"index.js":          "exports.handler = () => 'ok';",
+		"node_modules/a.js": "stale",
+	})
+
+	inputs := newInputs()
+	inputs.ExcludePattern = []string{"node_modules/"}
+	inputs.BuildCommand = "mkdir -p node_modules && echo fresh > node_modules/b.js"
+
+	pkg, err := function.BuildPackage(context.Background(), inputs)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pkg.Remove() })
+
+	reader, err := zip.OpenReader(pkg.Path)
+	require.NoError(t, err)
+
+	defer reader.Close()
+
+	names := make([]string, 0, len(reader.File))
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+
+	// The staged build output is packaged, the excluded sources are not
+	assert.Equal(t, []string{"index.js", "node_modules/b.js"}, names)
+
+	// The workspace is untouched
+	assert.NoFileExists(t, filepath.Join(workspace, "node_modules", "b.js"))
+
+	// A failing build fails packaging
+	inputs.BuildCommand = "exit 1"
+
+	_, err = function.BuildPackage(context.Background(), inputs)
+	assert.ErrorContains(t, err, "failed to run build command")
+}
+
+func TestCheckBuildTool(t *testing.T) {
+	workspace := writeSources(t, map[string]string{"go.mod": "module app"})
+
+	// No toolchain is installed, as in the action image
+	t.Setenv("PATH", t.TempDir())
+
+	inputs := newInputs()
+	inputs.Runtime = "golang121"
+	inputs.Build = true
+
+	assert.ErrorContains(t, function.CheckBuildTool(inputs), `go is required to run "go mod vendor"`)
+
+	inputs.BuildCommand = "make deps"
+	assert.ErrorContains(t, function.CheckBuildTool(inputs), "sh is required")
+
+	_, err := function.BuildPackage(context.Background(), inputs)
+	assert.ErrorContains(t, err, "sh is required")
+
+	// Nothing is run without dependency files
+	require.NoError(t, os.Remove(filepath.Join(workspace, "go.mod")))
+
+	inputs.BuildCommand = ""
+	assert.NoError(t, function.CheckBuildTool(inputs))
+}
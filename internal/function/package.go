@@ -0,0 +1,226 @@
+package function
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/yc-actions/sourcecraft-actions/pkg/fileset"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// packageModTime is the modification time of all package entries, so that the same
+// sources always produce the same archive. It is the earliest time ZIP can store.
+var packageModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Package is a function ZIP package written to a temporary file.
+type Package struct {
+	Path string
+	Size int64
+	// SHA256 is the hex SHA-256 of the archive.
+	SHA256 string
+}
+
+// Open opens the package file for reading.
+func (p *Package) Open() (*os.File, error) {
+	return os.Open(p.Path)
+}
+
+// Remove deletes the package file.
+func (p *Package) Remove() error {
+	return os.Remove(p.Path)
+}
+
+// parseIgnoreGlobPatterns parses ignore glob patterns from a string slice.
+func parseIgnoreGlobPatterns(patterns []string) []string {
+	var result []string
+
+	for _, pattern := range patterns {
+		if pattern != "" {
+			result = append(result, pattern)
+		}
+	}
+
+	sourcecraft.Info(fmt.Sprintf("Source ignore pattern: %q", result))
+
+	return result
+}
+
+// listSources returns the source files to package keyed by their path in the archive.
+func listSources(inputs *ActionInputs, root string) (map[string]string, error) {
+	include, err := fileset.NewAnchored(inputs.Include)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse include patterns: %w", err)
+	}
+
+	// Parse ignore patterns, after the ones from the ignore files in the root
+	ignore, err := fileset.Load(
+		afero.NewOsFs(),
+		root,
+		parseIgnoreGlobPatterns(inputs.ExcludePattern),
+		fileset.FuncIgnoreFile,
+		fileset.YCIgnoreFile,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ignore patterns: %w", err)
+	}
+
+	files := make(map[string]string)
+
+	err = fileset.Walk(afero.NewOsFs(), root, include, ignore, func(filePath, relPath string) error {
+		sourcecraft.Debug(fmt.Sprintf("match: file %s", filePath))
+
+		files[filepath.ToSlash(relPath)] = filePath
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return files, nil
+}
+
+// ZipSources zips the source files into a temporary file. The archive is reproducible:
+// entries are sorted, have a fixed modification time and keep only the executable bit
+// of the file mode. The caller must remove the package file.
+func ZipSources(inputs *ActionInputs) (*Package, error) {
+	sourcecraft.StartGroup("ZipDirectory")
+	defer sourcecraft.EndGroup()
+
+	files, err := listSources(inputs, sourceRoot(inputs))
+	if err != nil {
+		return nil, err
+	}
+
+	return zipFiles(files, inputs.CompressionLevel)
+}
+
+// sourceRoot returns the absolute source root directory.
+func sourceRoot(inputs *ActionInputs) string {
+	// Get workspace directory
+	workspace := sourcecraft.GetSourcecraftWorkspace()
+
+	// Get source root
+	return filepath.Join(workspace, inputs.SourceRoot)
+}
+
+// zipFiles zips the files keyed by their path in the archive into a temporary file.
+func zipFiles(files map[string]string, level int) (*Package, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	// Create a temporary file to write the zip file to
+	file, err := os.CreateTemp("", "function-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create package file: %w", err)
+	}
+	defer file.Close()
+
+	pkg := &Package{Path: file.Name()}
+
+	err = writeZip(file, files, names, level, pkg)
+	if err != nil {
+		_ = pkg.Remove()
+
+		return nil, err
+	}
+
+	sourcecraft.Info("Archive finalized")
+	sourcecraft.Info(fmt.Sprintf("Package size: %d bytes, SHA-256: %s", pkg.Size, pkg.SHA256))
+
+	return pkg, nil
+}
+
+// writeZip writes the files in the order of names to the writer and sets the size and hash of the package.
+func writeZip(w io.Writer, files map[string]string, names []string, level int, pkg *Package) error {
+	hash := sha256.New()
+	counter := &countingWriter{}
+
+	zipWriter := zip.NewWriter(io.MultiWriter(w, hash, counter))
+	zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, level)
+	})
+
+	for _, name := range names {
+		err := addFileToZip(zipWriter, files[name], name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Close the zip writer
+	err := zipWriter.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close zip writer: %w", err)
+	}
+
+	pkg.Size = counter.n
+	pkg.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	return nil
+}
+
+// addFileToZip adds a file to a zip writer.
+func addFileToZip(zipWriter *zip.Writer, filePath, zipPath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	sourcecraft.Info(fmt.Sprintf("add: %s", zipPath))
+
+	header := &zip.FileHeader{
+		Name:     zipPath,
+		Method:   zip.Deflate,
+		Modified: packageModTime,
+	}
+
+	mode := os.FileMode(0o644)
+	if info.Mode()&0o111 != 0 {
+		mode = 0o755
+	}
+
+	header.SetMode(mode)
+
+	writer, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to create entry in zip: %w", err)
+	}
+
+	_, err = io.Copy(writer, file)
+	if err != nil {
+		return fmt.Errorf("failed to write file to zip: %w", err)
+	}
+
+	return nil
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+
+	return len(p), nil
+}
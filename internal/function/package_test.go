@@ -0,0 +1,140 @@
+package function_test
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+)
+
+// writeSources creates the source files under the workspace and sets it as the Sourcecraft workspace.
+func writeSources(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	workspace := t.TempDir()
+	t.Setenv("SOURCECRAFT_WORKSPACE", workspace)
+
+	for name, content := range files {
+		path := filepath.Join(workspace, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	return workspace
+}
+
+func zipSources(t *testing.T, inputs *function.ActionInputs) *function.Package {
+	t.Helper()
+
+	pkg, err := function.ZipSources(inputs)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pkg.Remove() })
+
+	return pkg
+}
+
+func newInputs() *function.ActionInputs {
+	return &function.ActionInputs{
+		Include:          []string{"."},
+		SourceRoot:       ".",
+		CompressionLevel: flate.DefaultCompression,
+	}
+}
+
+func TestZipSources(t *testing.T) {
+	workspace := writeSources(t, map[string]string{
+		"index.js":          "exports.handler = () => 'ok';",
+		"lib/util.js":       "module.exports = {};",
+		"bin/run.sh":        "#!/bin/sh\necho ok\n",
+		"node_modules/a.js": "ignored",
+	})
+	require.NoError(t, os.Chmod(filepath.Join(workspace, "bin", "run.sh"), 0o700))
+
+	inputs := newInputs()
+	inputs.ExcludePattern = []string{"node_modules/"}
+
+	pkg := zipSources(t, inputs)
+
+	info, err := os.Stat(pkg.Path)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), pkg.Size)
+	assert.Len(t, pkg.SHA256, 64)
+
+	reader, err := zip.OpenReader(pkg.Path)
+	require.NoError(t, err)
+
+	defer reader.Close()
+
+	names := make([]string, 0, len(reader.File))
+	modes := make(map[string]os.FileMode)
+
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+		modes[file.Name] = file.Mode()
+
+		assert.True(t, file.Modified.Equal(time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)))
+	}
+
+	assert.Equal(t, []string{"bin/run.sh", "index.js", "lib/util.js"}, names)
+	assert.Equal(t, os.FileMode(0o755), modes["bin/run.sh"])
+	assert.Equal(t, os.FileMode(0o644), modes["index.js"])
+}
+
+func TestZipSourcesReproducible(t *testing.T) {
+	workspace := writeSources(t, map[string]string{
+		"index.js":    strings.Repeat("exports.handler = () => 'ok';\n", 100),
+		"lib/util.js": "module.exports = {};",
+	})
+
+	first := zipSources(t, newInputs())
+
+	// Touching the files must not change the package
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(workspace, "index.js"), later, later))
+
+	second := zipSources(t, newInputs())
+	assert.Equal(t, first.SHA256, second.SHA256)
+	assert.NotEqual(t, first.Path, second.Path)
+
+	// Changing the compression level does
+	inputs := newInputs()
+	inputs.CompressionLevel = flate.NoCompression
+
+	stored := zipSources(t, inputs)
+	assert.NotEqual(t, first.SHA256, stored.SHA256)
+	assert.Greater(t, stored.Size, first.Size)
+}
+
+func TestZipSourcesIncludeDoubleStar(t *testing.T) {
+	writeSources(t, map[string]string{
+		"index.js":         "exports.handler = () => 'ok';",
+		"lib/util.js":      "module.exports = {};",
+		"lib/deep/data.js": "module.exports = [];",
+		"lib/README.md":    "docs",
+		"test/index.js":    "test",
+	})
+
+	inputs := newInputs()
+	inputs.Include = []string{"*.js", "lib/**/*.js"}
+
+	pkg := zipSources(t, inputs)
+
+	reader, err := zip.OpenReader(pkg.Path)
+	require.NoError(t, err)
+
+	defer reader.Close()
+
+	names := make([]string, 0, len(reader.File))
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+
+	assert.Equal(t, []string{"index.js", "lib/deep/data.js", "lib/util.js"}, names)
+}
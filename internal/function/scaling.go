@@ -0,0 +1,155 @@
+package function
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+)
+
+// ScalingPolicy is the declared scaling policy of a version tag.
+type ScalingPolicy struct {
+	Tag                  string
+	ProvisionedInstances int64
+	ZoneInstancesLimit   int64
+	ZoneRequestsLimit    int64
+}
+
+// ParseScalingPolicy parses a scaling policy in the format
+// "TAG:PROVISIONED[:ZONE_INSTANCES_LIMIT[:ZONE_REQUESTS_LIMIT]]", where a zero limit means no limit.
+func ParseScalingPolicy(input string) (*ScalingPolicy, error) {
+	parts := strings.Split(input, ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		return nil, fmt.Errorf("scaling policy has wrong format: %s", input)
+	}
+
+	policy := &ScalingPolicy{Tag: strings.TrimSpace(parts[0])}
+	if policy.Tag == "" {
+		return nil, fmt.Errorf("scaling policy has empty tag: %s", input)
+	}
+
+	values := []*int64{&policy.ProvisionedInstances, &policy.ZoneInstancesLimit, &policy.ZoneRequestsLimit}
+	for i, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("scaling policy has invalid number %q: %s", part, input)
+		}
+
+		*values[i] = value
+	}
+
+	return policy, nil
+}
+
+// ParseScalingPolicies parses multiple scaling policies, one per tag.
+func ParseScalingPolicies(inputs []string) ([]*ScalingPolicy, error) {
+	var policies []*ScalingPolicy
+
+	tags := make(map[string]bool)
+
+	for _, input := range inputs {
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+
+		policy, err := ParseScalingPolicy(input)
+		if err != nil {
+			return nil, err
+		}
+
+		if tags[policy.Tag] {
+			return nil, fmt.Errorf("scaling policy for tag %s is declared twice", policy.Tag)
+		}
+
+		tags[policy.Tag] = true
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+// DeclaredScalingPolicies returns the scaling policies of the inputs, with the provisioned
+// instances shorthand as the policy of the $latest tag.
+func DeclaredScalingPolicies(inputs *ActionInputs) ([]*ScalingPolicy, error) {
+	if inputs.Provisioned == nil {
+		return inputs.ScalingPolicies, nil
+	}
+
+	for _, policy := range inputs.ScalingPolicies {
+		if policy.Tag == LatestTag {
+			return nil, fmt.Errorf("provisioned and the scaling policy of tag %s are both set", LatestTag)
+		}
+	}
+
+	return append(
+		[]*ScalingPolicy{{Tag: LatestTag, ProvisionedInstances: *inputs.Provisioned}},
+		inputs.ScalingPolicies...,
+	), nil
+}
+
+// PruneScalingPolicies reports whether the policies of the tags that aren't declared are removed: when
+// SCALING_POLICIES declares any policy or SCALING_POLICIES_PRUNE is set. With SCALING_POLICIES_PRUNE,
+// an input declaring nothing removes every policy, so that the last declared policy can be removed too.
+func PruneScalingPolicies(inputs *ActionInputs) bool {
+	return len(inputs.ScalingPolicies) > 0 || inputs.PruneScaling
+}
+
+// ScalingPolicyChanges returns the requests setting the declared policies that differ from the
+// existing ones and, with prune, removing the existing policies of the tags that aren't declared.
+func ScalingPolicyChanges(
+	functionID string,
+	existing []*functions.ScalingPolicy,
+	declared []*ScalingPolicy,
+	prune bool,
+) ([]*functions.SetScalingPolicyRequest, []*functions.RemoveScalingPolicyRequest) {
+	var (
+		toSet    []*functions.SetScalingPolicyRequest
+		toRemove []*functions.RemoveScalingPolicyRequest
+	)
+
+	existingByTag := make(map[string]*functions.ScalingPolicy, len(existing))
+	for _, policy := range existing {
+		existingByTag[policy.GetTag()] = policy
+	}
+
+	declaredTags := make(map[string]bool, len(declared))
+
+	for _, policy := range declared {
+		declaredTags[policy.Tag] = true
+
+		current, ok := existingByTag[policy.Tag]
+		if ok &&
+			current.GetProvisionedInstancesCount() == policy.ProvisionedInstances &&
+			current.GetZoneInstancesLimit() == policy.ZoneInstancesLimit &&
+			current.GetZoneRequestsLimit() == policy.ZoneRequestsLimit {
+			continue
+		}
+
+		toSet = append(toSet, &functions.SetScalingPolicyRequest{
+			FunctionId:                functionID,
+			Tag:                       policy.Tag,
+			ProvisionedInstancesCount: policy.ProvisionedInstances,
+			ZoneInstancesLimit:        policy.ZoneInstancesLimit,
+			ZoneRequestsLimit:         policy.ZoneRequestsLimit,
+		})
+	}
+
+	if prune {
+		for _, policy := range existing {
+			if !declaredTags[policy.GetTag()] {
+				toRemove = append(toRemove, &functions.RemoveScalingPolicyRequest{
+					FunctionId: functionID,
+					Tag:        policy.GetTag(),
+				})
+			}
+		}
+	}
+
+	return toSet, toRemove
+}
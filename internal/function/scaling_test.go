@@ -0,0 +1,124 @@
+package function_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+)
+
+func TestParseScalingPolicies(t *testing.T) {
+	policies, err := function.ParseScalingPolicies([]string{
+		"stable:2",
+		"",
+		"canary:0:3:100",
+		"$latest:1::50",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []*function.ScalingPolicy{
+		{Tag: "stable", ProvisionedInstances: 2},
+		{Tag: "canary", ZoneInstancesLimit: 3, ZoneRequestsLimit: 100},
+		{Tag: "$latest", ProvisionedInstances: 1, ZoneRequestsLimit: 50},
+	}, policies)
+
+	invalid := map[string]string{
+		"wrong format":    "stable",
+		"too many parts":  "stable:1:2:3:4",
+		"empty tag":       ":1",
+		"not a number":    "stable:many",
+		"negative number": "stable:-1",
+	}
+
+	for name, input := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := function.ParseScalingPolicies([]string{input})
+			assert.Error(t, err)
+		})
+	}
+
+	_, err = function.ParseScalingPolicies([]string{"stable:1", "stable:2"})
+	assert.ErrorContains(t, err, "declared twice")
+}
+
+func TestDeclaredScalingPolicies(t *testing.T) {
+	provisioned := int64(2)
+	stable := &function.ScalingPolicy{Tag: "stable", ProvisionedInstances: 1}
+
+	policies, err := function.DeclaredScalingPolicies(&function.ActionInputs{
+		Provisioned:     &provisioned,
+		ScalingPolicies: []*function.ScalingPolicy{stable},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*function.ScalingPolicy{{Tag: function.LatestTag, ProvisionedInstances: 2}, stable}, policies)
+
+	_, err = function.DeclaredScalingPolicies(&function.ActionInputs{
+		Provisioned:     &provisioned,
+		ScalingPolicies: []*function.ScalingPolicy{{Tag: function.LatestTag}},
+	})
+	assert.Error(t, err)
+}
+
+func TestScalingPolicyChanges(t *testing.T) {
+	existing := []*functions.ScalingPolicy{
+		{Tag: "stable", ProvisionedInstancesCount: 2},
+		{Tag: "canary", ProvisionedInstancesCount: 1},
+		{Tag: "old", ZoneInstancesLimit: 5},
+	}
+	declared := []*function.ScalingPolicy{
+		{Tag: "stable", ProvisionedInstances: 2},
+		{Tag: "canary", ProvisionedInstances: 3},
+		{Tag: "$latest", ZoneRequestsLimit: 10},
+	}
+
+	toSet, toRemove := function.ScalingPolicyChanges("fn-id", existing, declared, true)
+
+	require.Len(t, toSet, 2)
+	assert.Equal(t, "canary", toSet[0].Tag)
+	assert.Equal(t, int64(3), toSet[0].ProvisionedInstancesCount)
+	assert.Equal(t, "$latest", toSet[1].Tag)
+	assert.Equal(t, int64(10), toSet[1].ZoneRequestsLimit)
+	assert.Equal(t, "fn-id", toSet[1].FunctionId)
+
+	require.Len(t, toRemove, 1)
+	assert.Equal(t, "old", toRemove[0].Tag)
+
+	_, toRemove = function.ScalingPolicyChanges("fn-id", existing, declared, false)
+	assert.Empty(t, toRemove)
+}
+
+func TestPruneScalingPolicies(t *testing.T) {
+	stable := &function.ScalingPolicy{Tag: "stable", ProvisionedInstances: 1}
+	provisioned := int64(1)
+
+	assert.True(t, function.PruneScalingPolicies(&function.ActionInputs{
+		ScalingPolicies: []*function.ScalingPolicy{stable},
+	}))
+	assert.True(t, function.PruneScalingPolicies(&function.ActionInputs{PruneScaling: true}))
+	assert.False(t, function.PruneScalingPolicies(&function.ActionInputs{Provisioned: &provisioned}))
+	assert.False(t, function.PruneScalingPolicies(&function.ActionInputs{}))
+}
+
+func TestScalingPolicyChangesRemoveLast(t *testing.T) {
+	existing := []*functions.ScalingPolicy{{Tag: "stable", ProvisionedInstancesCount: 2}}
+
+	// The last policy was removed from SCALING_POLICIES, and SCALING_POLICIES_PRUNE is set
+	inputs := &function.ActionInputs{PruneScaling: true}
+
+	declared, err := function.DeclaredScalingPolicies(inputs)
+	require.NoError(t, err)
+
+	toSet, toRemove := function.ScalingPolicyChanges(
+		"fn-id",
+		existing,
+		declared,
+		function.PruneScalingPolicies(inputs),
+	)
+
+	assert.Empty(t, toSet)
+	require.Len(t, toRemove, 1)
+	assert.Equal(t, "stable", toRemove[0].Tag)
+	assert.Equal(t, "fn-id", toRemove[0].FunctionId)
+}
@@ -0,0 +1,106 @@
+package function
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	"github.com/yc-actions/sourcecraft-actions/internal/container"
+)
+
+// storageMountRoot is the directory where the storage of a function version is mounted.
+const storageMountRoot = "/function/storage/"
+
+// mountPointName returns the mount point name of a mount, which may be given as a name or
+// as a path under /function/storage.
+func mountPointName(mountPoint string) (string, error) {
+	name := strings.Trim(strings.TrimPrefix(mountPoint, storageMountRoot), "/")
+	if name == "" || strings.Contains(name, "/") {
+		return "", fmt.Errorf("mount point must be a name or a directory in %s, got %q", storageMountRoot, mountPoint)
+	}
+
+	return name, nil
+}
+
+// StorageMounts converts the parsed storage mounts to the function version storage mounts.
+func StorageMounts(mounts []*container.StorageMount) ([]*functions.StorageMount, error) {
+	result := make([]*functions.StorageMount, 0, len(mounts))
+
+	for _, mount := range mounts {
+		name, err := mountPointName(mount.MountPointPath)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &functions.StorageMount{
+			BucketId:       mount.BucketID,
+			Prefix:         mount.Prefix,
+			MountPointName: name,
+			ReadOnly:       mount.ReadOnly,
+		})
+	}
+
+	return result, nil
+}
+
+// Mounts converts the parsed mounts to the function version mounts.
+func Mounts(mounts []*container.Mount) ([]*functions.Mount, error) {
+	result := make([]*functions.Mount, 0, len(mounts))
+
+	for _, mount := range mounts {
+		name, err := mountPointName(mount.MountPoint)
+		if err != nil {
+			return nil, err
+		}
+
+		m := &functions.Mount{
+			Name: name,
+			Mode: functions.Mount_READ_WRITE,
+		}
+
+		if mount.ReadOnly {
+			m.Mode = functions.Mount_READ_ONLY
+		}
+
+		if mount.IsEphemeralDisk() {
+			m.Target = &functions.Mount_EphemeralDiskSpec{
+				EphemeralDiskSpec: &functions.Mount_DiskSpec{Size: mount.DiskSize},
+			}
+		} else {
+			m.Target = &functions.Mount_ObjectStorage_{
+				ObjectStorage: &functions.Mount_ObjectStorage{
+					BucketId: mount.BucketID,
+					Prefix:   mount.Prefix,
+				},
+			}
+		}
+
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+// metadataOption converts a parsed metadata option to the function version one.
+func metadataOption(option container.MetadataOption) functions.MetadataOption {
+	switch option {
+	case container.MetadataOptionEnabled:
+		return functions.MetadataOption_ENABLED
+	case container.MetadataOptionDisabled:
+		return functions.MetadataOption_DISABLED
+	default:
+		return functions.MetadataOption_METADATA_OPTION_UNSPECIFIED
+	}
+}
+
+// MetadataOptions converts the parsed metadata options to the function version ones, or nil if none is set.
+func MetadataOptions(options container.MetadataOptions) *functions.MetadataOptions {
+	if options.IsEmpty() {
+		return nil
+	}
+
+	return &functions.MetadataOptions{
+		GceHttpEndpoint:   metadataOption(options.GceHTTPEndpoint),
+		AwsV1HttpEndpoint: metadataOption(options.AwsV1HTTPEndpoint),
+	}
+}
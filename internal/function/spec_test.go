@@ -0,0 +1,56 @@
+package function_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	"github.com/yc-actions/sourcecraft-actions/internal/container"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+)
+
+func TestStorageMounts(t *testing.T) {
+	mounts, err := function.StorageMounts([]*container.StorageMount{
+		{BucketID: "bucket", Prefix: "prefix", MountPointPath: "data", ReadOnly: true},
+		{BucketID: "bucket", MountPointPath: "/function/storage/cache/"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "data", mounts[0].MountPointName)
+	assert.Equal(t, "prefix", mounts[0].Prefix)
+	assert.True(t, mounts[0].ReadOnly)
+	assert.Equal(t, "cache", mounts[1].MountPointName)
+	assert.False(t, mounts[1].ReadOnly)
+
+	_, err = function.StorageMounts([]*container.StorageMount{{BucketID: "bucket", MountPointPath: "/mnt/data"}})
+	assert.Error(t, err)
+}
+
+func TestMounts(t *testing.T) {
+	mounts, err := function.Mounts([]*container.Mount{
+		{MountPoint: "data", ReadOnly: true, BucketID: "bucket", Prefix: "prefix"},
+		{MountPoint: "disk", DiskSize: 1024},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "data", mounts[0].Name)
+	assert.Equal(t, functions.Mount_READ_ONLY, mounts[0].Mode)
+	assert.Equal(t, "bucket", mounts[0].GetObjectStorage().GetBucketId())
+	assert.Equal(t, "disk", mounts[1].Name)
+	assert.Equal(t, functions.Mount_READ_WRITE, mounts[1].Mode)
+	assert.Equal(t, int64(1024), mounts[1].GetEphemeralDiskSpec().GetSize())
+
+	_, err = function.Mounts([]*container.Mount{{MountPoint: "a/b", DiskSize: 1024}})
+	assert.Error(t, err)
+}
+
+func TestMetadataOptions(t *testing.T) {
+	assert.Nil(t, function.MetadataOptions(container.MetadataOptions{}))
+
+	options := function.MetadataOptions(container.MetadataOptions{
+		AwsV1HTTPEndpoint: container.MetadataOptionDisabled,
+	})
+	assert.Equal(t, functions.MetadataOption_METADATA_OPTION_UNSPECIFIED, options.GceHttpEndpoint)
+	assert.Equal(t, functions.MetadataOption_DISABLED, options.AwsV1HttpEndpoint)
+}
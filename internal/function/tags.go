@@ -0,0 +1,138 @@
+package function
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+)
+
+// Modes of the function action.
+const (
+	// ModeDeploy deploys a new function version.
+	ModeDeploy = "deploy"
+	// ModeTag sets and removes tags of an existing version.
+	ModeTag = "tag"
+	// ModePromote sets the target tag on the version with the source tag.
+	ModePromote = "promote"
+	// ModeRollback makes a copy of a previous version the $latest one.
+	ModeRollback = "rollback"
+)
+
+// ValidateMode validates the mode and its inputs.
+func ValidateMode(inputs *ActionInputs) error {
+	switch inputs.Mode {
+	case ModeDeploy:
+		return nil
+	case ModeTag:
+		if len(inputs.SetTags) == 0 && len(inputs.RemoveTags) == 0 {
+			return fmt.Errorf("set-tags or remove-tags is required in %s mode", inputs.Mode)
+		}
+	case ModePromote:
+		if inputs.PromoteFrom == "" || inputs.PromoteTo == "" {
+			return fmt.Errorf("promote-from and promote-to are required in %s mode", inputs.Mode)
+		}
+
+		if inputs.PromoteTo == LatestTag {
+			return fmt.Errorf("can't promote to %s, use %s mode", LatestTag, ModeRollback)
+		}
+	case ModeRollback:
+		if inputs.Version == "" {
+			return fmt.Errorf("version is required in %s mode", inputs.Mode)
+		}
+	default:
+		return fmt.Errorf(
+			"unknown mode %q, expected one of %s, %s, %s, %s",
+			inputs.Mode,
+			ModeDeploy,
+			ModeTag,
+			ModePromote,
+			ModeRollback,
+		)
+	}
+
+	return nil
+}
+
+// TagChanges returns the tags to set on and remove from the version, skipping the ones
+// that are already set or absent.
+func TagChanges(version *functions.Version, set, remove []string) ([]string, []string, error) {
+	var toSet, toRemove []string
+
+	set = trimTags(set)
+	remove = trimTags(remove)
+
+	for _, tag := range set {
+		if tag == LatestTag {
+			return nil, nil, fmt.Errorf("tag %s can't be set, use %s mode", LatestTag, ModeRollback)
+		}
+
+		if slices.Contains(remove, tag) {
+			return nil, nil, fmt.Errorf("tag %s is both set and removed", tag)
+		}
+
+		if !slices.Contains(version.GetTags(), tag) && !slices.Contains(toSet, tag) {
+			toSet = append(toSet, tag)
+		}
+	}
+
+	for _, tag := range remove {
+		if tag == LatestTag {
+			return nil, nil, fmt.Errorf("tag %s can't be removed", LatestTag)
+		}
+
+		if slices.Contains(version.GetTags(), tag) && !slices.Contains(toRemove, tag) {
+			toRemove = append(toRemove, tag)
+		}
+	}
+
+	return toSet, toRemove, nil
+}
+
+// trimTags returns the tags without surrounding spaces, dropping the empty ones.
+func trimTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// NewRollbackVersionRequest builds the request creating a copy of the version, which becomes $latest.
+// The package tag of the version is moved to the copy, which has the same package.
+func NewRollbackVersionRequest(version *functions.Version) *functions.CreateFunctionVersionRequest {
+	var tags []string
+	if packageTag := FindPackageTag(version.GetTags()); packageTag != "" {
+		tags = []string{packageTag}
+	}
+
+	return &functions.CreateFunctionVersionRequest{
+		Tag:                   tags,
+		FunctionId:            version.GetFunctionId(),
+		Runtime:               version.GetRuntime(),
+		Description:           version.GetDescription(),
+		Entrypoint:            version.GetEntrypoint(),
+		Resources:             version.GetResources(),
+		ExecutionTimeout:      version.GetExecutionTimeout(),
+		ServiceAccountId:      version.GetServiceAccountId(),
+		Environment:           version.GetEnvironment(),
+		Connectivity:          version.GetConnectivity(),
+		NamedServiceAccounts:  version.GetNamedServiceAccounts(),
+		Secrets:               version.GetSecrets(),
+		LogOptions:            version.GetLogOptions(),
+		StorageMounts:         version.GetStorageMounts(),
+		AsyncInvocationConfig: version.GetAsyncInvocationConfig(),
+		TmpfsSize:             version.GetTmpfsSize(),
+		Concurrency:           version.GetConcurrency(),
+		Mounts:                version.GetMounts(),
+		MetadataOptions:       version.GetMetadataOptions(),
+		PackageSource: &functions.CreateFunctionVersionRequest_VersionId{
+			VersionId: version.GetId(),
+		},
+	}
+}
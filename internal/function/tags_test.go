@@ -0,0 +1,88 @@
+package function_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+)
+
+func TestValidateMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  function.ActionInputs
+		wantErr string
+	}{
+		{name: "deploy", inputs: function.ActionInputs{Mode: function.ModeDeploy}},
+		{name: "tag", inputs: function.ActionInputs{Mode: function.ModeTag, SetTags: []string{"stable"}}},
+		{
+			name:    "tag without tags",
+			inputs:  function.ActionInputs{Mode: function.ModeTag},
+			wantErr: "set-tags or remove-tags is required",
+		},
+		{
+			name:   "promote",
+			inputs: function.ActionInputs{Mode: function.ModePromote, PromoteFrom: "canary", PromoteTo: "stable"},
+		},
+		{
+			name:    "promote to latest",
+			inputs:  function.ActionInputs{Mode: function.ModePromote, PromoteFrom: "canary", PromoteTo: "$latest"},
+			wantErr: "can't promote to $latest",
+		},
+		{
+			name:    "rollback without version",
+			inputs:  function.ActionInputs{Mode: function.ModeRollback},
+			wantErr: "version is required",
+		},
+		{name: "unknown", inputs: function.ActionInputs{Mode: "release"}, wantErr: `unknown mode "release"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := function.ValidateMode(&tt.inputs)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTagChanges(t *testing.T) {
+	version := &functions.Version{Id: "ver-id", Tags: []string{function.LatestTag, "canary", "stable"}}
+
+	toSet, toRemove, err := function.TagChanges(
+		version,
+		[]string{"stable", " beta ", "", "beta"},
+		[]string{"canary", "old"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"beta"}, toSet)
+	assert.Equal(t, []string{"canary"}, toRemove)
+
+	_, _, err = function.TagChanges(version, []string{"$latest"}, nil)
+	assert.ErrorContains(t, err, "can't be set")
+
+	_, _, err = function.TagChanges(version, nil, []string{"$latest"})
+	assert.ErrorContains(t, err, "can't be removed")
+
+	_, _, err = function.TagChanges(version, []string{"beta"}, []string{"beta"})
+	assert.ErrorContains(t, err, "both set and removed")
+}
+
+func TestNewRollbackVersionRequest(t *testing.T) {
+	version := newVersion()
+
+	request := function.NewRollbackVersionRequest(version)
+	assert.Equal(t, "fn-id", request.FunctionId)
+	assert.Equal(t, "ver-id", request.GetVersionId())
+	assert.Equal(t, version.Runtime, request.Runtime)
+	assert.Equal(t, version.Environment, request.Environment)
+	// Only the package tag moves to the copy, the user tags stay on the version
+	assert.Equal(t, []string{function.PackageTag(packageSHA256)}, request.Tag)
+}
@@ -0,0 +1,144 @@
+package function
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ValidateRuntime checks that the runtime is one of the available runtimes.
+func ValidateRuntime(runtime string, available []string) error {
+	if slices.Contains(available, runtime) {
+		return nil
+	}
+
+	sorted := slices.Clone(available)
+	slices.Sort(sorted)
+
+	return fmt.Errorf("unknown runtime %q, available runtimes: %s", runtime, strings.Join(sorted, ", "))
+}
+
+// entrypointCheck describes how the entrypoint of a runtime maps to the package.
+type entrypointCheck struct {
+	// extensions of the entrypoint file, in the order of preference
+	extensions []string
+	// modulePath converts the module part of the entrypoint to the file path without extension
+	modulePath func(module string) string
+	// handlerPatterns return the patterns of which one must match the file to define the handler
+	handlerPatterns func(handler string) []string
+}
+
+// entrypointChecks are the entrypoint checks of the known runtimes by runtime prefix.
+var entrypointChecks = map[string]entrypointCheck{
+	"nodejs": {
+		extensions: []string{".js", ".cjs", ".mjs"},
+		modulePath: func(module string) string { return module },
+		handlerPatterns: func(handler string) []string {
+			return []string{
+				`exports\.` + handler + `\b`,
+				`module\.exports\s*=[\s\S]*\b` + handler + `\b`,
+				`export\s+(async\s+)?function\s*\*?\s*` + handler + `\b`,
+				`export\s+(const|let|var)\s+` + handler + `\b`,
+				`export\s*\{[^}]*\b` + handler + `\b[^}]*\}`,
+			}
+		},
+	},
+	"python": {
+		extensions: []string{".py"},
+		modulePath: func(module string) string { return strings.ReplaceAll(module, ".", "/") },
+		handlerPatterns: func(handler string) []string {
+			return []string{
+				`(?m)^(async\s+)?def\s+` + handler + `\s*\(`,
+				`(?m)^` + handler + `\s*=`,
+			}
+		},
+	},
+	"golang": {
+		extensions: []string{".go"},
+		modulePath: func(module string) string { return module },
+		handlerPatterns: func(handler string) []string {
+			return []string{
+				`(?m)^func\s+` + handler + `\s*\(`,
+				`(?m)^var\s+` + handler + `\b`,
+			}
+		},
+	},
+}
+
+// ValidateEntrypoint checks that the package has the entrypoint file of the runtime and that the file
+// defines the handler. Entrypoints of runtimes without a known layout are not checked.
+func ValidateEntrypoint(pkg *Package, runtime, entrypoint string) error {
+	var check *entrypointCheck
+
+	for prefix, c := range entrypointChecks {
+		if strings.HasPrefix(runtime, prefix) {
+			check = &c
+
+			break
+		}
+	}
+
+	if check == nil {
+		return nil
+	}
+
+	module, handler, ok := cutLast(entrypoint, ".")
+	if !ok || module == "" || handler == "" {
+		return fmt.Errorf("entrypoint %q must have the <file>.<handler> format", entrypoint)
+	}
+
+	reader, err := zip.OpenReader(pkg.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open package: %w", err)
+	}
+	defer reader.Close()
+
+	base := check.modulePath(module)
+	candidates := make([]string, 0, len(check.extensions))
+
+	for _, extension := range check.extensions {
+		candidates = append(candidates, path.Clean(base+extension))
+	}
+
+	for _, candidate := range candidates {
+		file, err := reader.Open(candidate)
+		if err != nil {
+			continue
+		}
+
+		content, err := io.ReadAll(file)
+		_ = file.Close()
+
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", candidate, err)
+		}
+
+		for _, pattern := range check.handlerPatterns(regexp.QuoteMeta(handler)) {
+			if regexp.MustCompile(pattern).Match(content) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("entrypoint file %s doesn't define the handler %s", candidate, handler)
+	}
+
+	return fmt.Errorf(
+		"entrypoint file for %q is not in the package, expected %s",
+		entrypoint,
+		strings.Join(candidates, " or "),
+	)
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,70 @@
+package function_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+)
+
+func TestValidateRuntime(t *testing.T) {
+	available := []string{"python312", "nodejs18", "golang121"}
+
+	assert.NoError(t, function.ValidateRuntime("nodejs18", available))
+	assert.EqualError(
+		t,
+		function.ValidateRuntime("nodejs99", available),
+		`unknown runtime "nodejs99", available runtimes: golang121, nodejs18, python312`,
+	)
+}
+
+func TestValidateEntrypoint(t *testing.T) {
+	writeSources(t, map[string]string{
+		"index.js":        "exports.handler = async (event) => ({ statusCode: 200 });",
+		"esm/app.mjs":     "export async function handler(event) {}",
+		"lib/handlers.py": "import json\n\nasync def handler(event, context):\n    pass\n",
+		"main.go":         "package main\n\nfunc Handler(ctx context.Context) error {\n\treturn nil\n}\n",
+	})
+
+	pkg := zipSources(t, newInputs())
+
+	tests := []struct {
+		runtime    string
+		entrypoint string
+		wantErr    string
+	}{
+		{runtime: "nodejs18", entrypoint: "index.handler"},
+		{runtime: "nodejs18", entrypoint: "esm/app.handler"},
+		{runtime: "python312", entrypoint: "lib.handlers.handler"},
+		{runtime: "golang121", entrypoint: "main.Handler"},
+		{runtime: "java21", entrypoint: "Handler"},
+		{
+			runtime:    "nodejs18",
+			entrypoint: "index.main",
+			wantErr:    "entrypoint file index.js doesn't define the handler main",
+		},
+		{
+			runtime:    "python312",
+			entrypoint: "index.handler",
+			wantErr:    `entrypoint file for "index.handler" is not in the package, expected index.py`,
+		},
+		{
+			runtime:    "nodejs18",
+			entrypoint: "handler",
+			wantErr:    "must have the <file>.<handler> format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.runtime+"/"+tt.entrypoint, func(t *testing.T) {
+			err := function.ValidateEntrypoint(pkg, tt.runtime, tt.entrypoint)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
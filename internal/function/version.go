@@ -0,0 +1,123 @@
+package function
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// LatestTag is the tag of the latest function version.
+const LatestTag = "$latest"
+
+// packageTagPrefix starts the version tag recording the SHA-256 of the version package, since the API
+// doesn't return the package of a version. Tags are lowercase, so the hash is base32 encoded.
+const packageTagPrefix = "package-"
+
+// packageTagEncoding encodes the package hash in the package tag.
+var packageTagEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// PackageTag returns the version tag recording the hex SHA-256 of the package, or an empty string
+// if the hash is invalid.
+func PackageTag(packageSHA256 string) string {
+	sum, err := hex.DecodeString(packageSHA256)
+	if err != nil || len(sum) != sha256.Size {
+		return ""
+	}
+
+	return packageTagPrefix + strings.ToLower(packageTagEncoding.EncodeToString(sum))
+}
+
+// isPackageTag reports whether the tag records a package hash.
+func isPackageTag(tag string) bool {
+	encoded, ok := strings.CutPrefix(tag, packageTagPrefix)
+	if !ok {
+		return false
+	}
+
+	sum, err := packageTagEncoding.DecodeString(strings.ToUpper(encoded))
+
+	return err == nil && len(sum) == sha256.Size
+}
+
+// FindPackageTag returns the package tag among the tags, or an empty string if there is none.
+func FindPackageTag(tags []string) string {
+	for _, tag := range tags {
+		if isPackageTag(tag) {
+			return tag
+		}
+	}
+
+	return ""
+}
+
+// VersionUnchanged reports whether creating a version with the request would give the same
+// version as the existing one: the package tags and the spec match.
+func VersionUnchanged(version *functions.Version, request *functions.CreateFunctionVersionRequest) bool {
+	if version == nil {
+		return false
+	}
+
+	packageTag := FindPackageTag(request.GetTag())
+	if packageTag == "" || !slices.Contains(version.GetTags(), packageTag) {
+		return false
+	}
+
+	return version.GetRuntime() == request.GetRuntime() &&
+		version.GetEntrypoint() == request.GetEntrypoint() &&
+		version.GetDescription() == request.GetDescription() &&
+		version.GetResources().GetMemory() == request.GetResources().GetMemory() &&
+		version.GetExecutionTimeout().AsDuration() == request.GetExecutionTimeout().AsDuration() &&
+		version.GetServiceAccountId() == request.GetServiceAccountId() &&
+		maps.Equal(version.GetEnvironment(), request.GetEnvironment()) &&
+		slices.EqualFunc(version.GetSecrets(), request.GetSecrets(), func(a, b *functions.Secret) bool {
+			return proto.Equal(a, b)
+		}) &&
+		slices.Equal(userTags(version.GetTags()), userTags(request.GetTag())) &&
+		version.GetConnectivity().GetNetworkId() == request.GetConnectivity().GetNetworkId() &&
+		logOptionsEqual(version.GetLogOptions(), request.GetLogOptions()) &&
+		proto.Equal(version.GetAsyncInvocationConfig(), request.GetAsyncInvocationConfig()) &&
+		concurrency(version.GetConcurrency()) == concurrency(request.GetConcurrency()) &&
+		version.GetTmpfsSize() == request.GetTmpfsSize() &&
+		slices.EqualFunc(version.GetStorageMounts(), request.GetStorageMounts(), func(a, b *functions.StorageMount) bool {
+			return proto.Equal(a, b)
+		}) &&
+		slices.EqualFunc(version.GetMounts(), request.GetMounts(), func(a, b *functions.Mount) bool {
+			return proto.Equal(a, b)
+		}) &&
+		version.GetMetadataOptions().GetGceHttpEndpoint() == request.GetMetadataOptions().GetGceHttpEndpoint() &&
+		version.GetMetadataOptions().GetAwsV1HttpEndpoint() == request.GetMetadataOptions().GetAwsV1HttpEndpoint()
+}
+
+// concurrency returns the concurrency of a version, where zero means the default of one request.
+func concurrency(value int64) int64 {
+	return max(value, 1)
+}
+
+// userTags returns the sorted tags without the empty, $latest and package ones.
+func userTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if tag != "" && tag != LatestTag && !isPackageTag(tag) {
+			result = append(result, tag)
+		}
+	}
+
+	slices.Sort(result)
+
+	return result
+}
+
+// logOptionsEqual compares log options, treating an empty log group as no destination.
+func logOptionsEqual(a, b *functions.LogOptions) bool {
+	return a.GetDisabled() == b.GetDisabled() &&
+		a.GetLogGroupId() == b.GetLogGroupId() &&
+		a.GetFolderId() == b.GetFolderId() &&
+		a.GetMinLevel() == b.GetMinLevel()
+}
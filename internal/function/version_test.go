@@ -0,0 +1,114 @@
+package function_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/functions/v1"
+	"github.com/yc-actions/sourcecraft-actions/internal/function"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// packageSHA256 is the hex SHA-256 of the package of the test versions.
+var packageSHA256 = strings.Repeat("a", 64)
+
+func newVersionRequest() *functions.CreateFunctionVersionRequest {
+	return &functions.CreateFunctionVersionRequest{
+		FunctionId:       "fn-id",
+		Runtime:          "nodejs18",
+		Entrypoint:       "index.handler",
+		Resources:        &functions.Resources{Memory: 128 * 1024 * 1024},
+		ServiceAccountId: "sa-id",
+		Description:      "release",
+		ExecutionTimeout: durationpb.New(5 * time.Second),
+		Environment:      map[string]string{"KEY": "value"},
+		Tag:              []string{"stable", function.PackageTag(packageSHA256)},
+		Connectivity:     &functions.Connectivity{},
+		LogOptions: &functions.LogOptions{
+			Destination: &functions.LogOptions_LogGroupId{},
+		},
+		PackageSource: &functions.CreateFunctionVersionRequest_Content{Content: []byte("zip")},
+	}
+}
+
+func newVersion() *functions.Version {
+	return &functions.Version{
+		Id:               "ver-id",
+		FunctionId:       "fn-id",
+		Runtime:          "nodejs18",
+		Entrypoint:       "index.handler",
+		Resources:        &functions.Resources{Memory: 128 * 1024 * 1024},
+		ServiceAccountId: "sa-id",
+		Description:      "release",
+		ExecutionTimeout: durationpb.New(5 * time.Second),
+		Environment:      map[string]string{"KEY": "value"},
+		Tags:             []string{function.LatestTag, "stable", function.PackageTag(packageSHA256)},
+	}
+}
+
+func TestVersionUnchanged(t *testing.T) {
+	assert.True(t, function.VersionUnchanged(newVersion(), newVersionRequest()))
+	assert.False(t, function.VersionUnchanged(nil, newVersionRequest()))
+
+	t.Run("default concurrency", func(t *testing.T) {
+		version := newVersion()
+		version.Concurrency = 1
+
+		assert.True(t, function.VersionUnchanged(version, newVersionRequest()))
+	})
+
+	changes := map[string]func(*functions.CreateFunctionVersionRequest){
+		"package": func(r *functions.CreateFunctionVersionRequest) {
+			r.Tag = []string{"stable", function.PackageTag(strings.Repeat("b", 64))}
+		},
+		"description":     func(r *functions.CreateFunctionVersionRequest) { r.Description = "other" },
+		"no package hash": func(r *functions.CreateFunctionVersionRequest) { r.Tag = []string{"stable"} },
+		"runtime":         func(r *functions.CreateFunctionVersionRequest) { r.Runtime = "nodejs22" },
+		"memory":          func(r *functions.CreateFunctionVersionRequest) { r.Resources.Memory *= 2 },
+		"timeout":         func(r *functions.CreateFunctionVersionRequest) { r.ExecutionTimeout.Seconds = 10 },
+		"environment":     func(r *functions.CreateFunctionVersionRequest) { r.Environment["KEY"] = "other" },
+		"tags":            func(r *functions.CreateFunctionVersionRequest) { r.Tag = append(r.Tag, "canary") },
+		"network": func(r *functions.CreateFunctionVersionRequest) {
+			r.Connectivity.NetworkId = "net-id"
+		},
+		"log level": func(r *functions.CreateFunctionVersionRequest) { r.LogOptions.MinLevel = 3 },
+		"secrets": func(r *functions.CreateFunctionVersionRequest) {
+			r.Secrets = []*functions.Secret{{Id: "secret-id", Key: "key"}}
+		},
+		"async": func(r *functions.CreateFunctionVersionRequest) {
+			r.AsyncInvocationConfig = &functions.AsyncInvocationConfig{RetriesCount: 3}
+		},
+		"concurrency": func(r *functions.CreateFunctionVersionRequest) { r.Concurrency = 4 },
+		"tmpfs":       func(r *functions.CreateFunctionVersionRequest) { r.TmpfsSize = 1024 },
+		"storage mounts": func(r *functions.CreateFunctionVersionRequest) {
+			r.StorageMounts = []*functions.StorageMount{{BucketId: "bucket", MountPointName: "data"}}
+		},
+		"mounts": func(r *functions.CreateFunctionVersionRequest) {
+			r.Mounts = []*functions.Mount{{Name: "disk", Mode: functions.Mount_READ_WRITE}}
+		},
+		"metadata options": func(r *functions.CreateFunctionVersionRequest) {
+			r.MetadataOptions = &functions.MetadataOptions{GceHttpEndpoint: functions.MetadataOption_DISABLED}
+		},
+	}
+
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			request := newVersionRequest()
+			change(request)
+
+			assert.False(t, function.VersionUnchanged(newVersion(), request))
+		})
+	}
+}
+
+func TestPackageTag(t *testing.T) {
+	tag := function.PackageTag(packageSHA256)
+
+	assert.Regexp(t, `^[a-z][-_0-9a-z]*$`, tag)
+	assert.LessOrEqual(t, len(tag), 63)
+	assert.Equal(t, tag, function.FindPackageTag([]string{function.LatestTag, "stable", tag}))
+	assert.Empty(t, function.FindPackageTag([]string{"stable", "package-v2"}))
+	assert.Empty(t, function.PackageTag("not-a-hash"))
+}
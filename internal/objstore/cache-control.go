@@ -1,13 +1,11 @@
 package objstore
 
-import (
-	"path/filepath"
-	"strings"
-)
+import "strings"
 
-// ParseCacheControlFormats parses cache control formats from a string slice.
-func ParseCacheControlFormats(formats []string) CacheControlConfig {
-	result := make(map[string]string)
+// ParseCacheControlFormats parses cache control formats from a string slice. The patterns keep their
+// declaration order, so that the last matching one wins.
+func ParseCacheControlFormats(formats []string) (CacheControlConfig, error) {
+	var config CacheControlConfig
 
 	for _, format := range formats {
 		parts := strings.Split(format, ":")
@@ -21,59 +19,32 @@ func ParseCacheControlFormats(formats []string) CacheControlConfig {
 		// Special handling for default value to preserve spaces
 		if strings.TrimSpace(keysPart) == "*" {
 			// Don't trim spaces for default value
-			result["*"] = valuePart
-		} else {
-			// For regular keys, trim spaces
-			valuePart = strings.TrimSpace(valuePart)
-			keys := strings.Split(keysPart, ",")
+			config.Default = valuePart
 
-			for _, key := range keys {
-				result[strings.TrimSpace(key)] = valuePart
-			}
-		}
-	}
-
-	// Handle default value
-	defaultValue, hasDefault := result["*"]
-	if hasDefault {
-		delete(result, "*")
-	}
-
-	// In TypeScript, we only set default to undefined if it's an empty string
-	// In Go, we'll keep the value as is, even if it's just a space
-
-	return CacheControlConfig{
-		Mapping: result,
-		Default: defaultValue,
-	}
-}
-
-// GetCacheControlValue returns the cache control value for the given key.
-func GetCacheControlValue(config CacheControlConfig, key string) string {
-	for pattern, value := range config.Mapping {
-		matched, err := filepath.Match(pattern, key)
-		if err != nil {
 			continue
 		}
 
-		if matched {
-			return value
-		}
-
-		// Try to match with base name for paths
-		if strings.Contains(key, "/") {
-			base := filepath.Base(key)
+		// For regular keys, trim spaces
+		valuePart = strings.TrimSpace(valuePart)
 
-			matched, err := filepath.Match(pattern, base)
+		for _, key := range strings.Split(keysPart, ",") {
+			rule, err := NewGlobRule(strings.TrimSpace(key), valuePart)
 			if err != nil {
-				continue
+				return CacheControlConfig{}, err
 			}
 
-			if matched {
-				return value
-			}
+			config.Rules = append(config.Rules, rule)
 		}
 	}
 
-	return config.Default
+	// In TypeScript, we only set default to undefined if it's an empty string
+	// In Go, we'll keep the value as is, even if it's just a space
+
+	return config, nil
+}
+
+// GetCacheControlValue returns the cache control value for the given key: the value of the last
+// matching pattern, or the default.
+func GetCacheControlValue(config CacheControlConfig, key string) string {
+	return lookupRules(config.Rules, config.Default, key)
 }
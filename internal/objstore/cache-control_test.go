@@ -6,33 +6,58 @@ import (
 	"github.com/yc-actions/sourcecraft-actions/internal/objstore"
 )
 
+// parseCacheControl parses the cache control formats of a test.
+func parseCacheControl(t *testing.T, formats ...string) objstore.CacheControlConfig {
+	t.Helper()
+
+	config, err := objstore.ParseCacheControlFormats(formats)
+	if err != nil {
+		t.Fatalf("Failed to parse cache control formats: %v", err)
+	}
+
+	return config
+}
+
+// rulePatterns returns the patterns of the rules in declaration order, all with the same expected value.
+func rulePatterns(t *testing.T, config objstore.CacheControlConfig, value string) []string {
+	t.Helper()
+
+	patterns := make([]string, 0, len(config.Rules))
+
+	for _, rule := range config.Rules {
+		if rule.Value != value {
+			t.Errorf("Expected value of %s to be '%s', got '%s'", rule.Pattern, value, rule.Value)
+		}
+
+		patterns = append(patterns, rule.Pattern)
+	}
+
+	return patterns
+}
+
+func equalPatterns(t *testing.T, expected, actual []string) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("Expected patterns %q, got %q", expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Errorf("Expected patterns %q, got %q", expected, actual)
+		}
+	}
+}
+
 func TestParseCacheControlFormats(t *testing.T) {
 	t.Run("should parse cache control formats", func(t *testing.T) {
-		formats := []string{
+		result := parseCacheControl(t,
 			"*.html:public, max-age=3600",
 			"*.css:public, max-age=3600",
 			"*:public, max-age=3600",
-		}
-		result := objstore.ParseCacheControlFormats(formats)
-
-		// Check mapping
-		if len(result.Mapping) != 2 {
-			t.Errorf("Expected mapping to have 2 entries, got %d", len(result.Mapping))
-		}
-
-		if result.Mapping["*.html"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.html] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.html"],
-			)
-		}
+		)
 
-		if result.Mapping["*.css"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.css] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.css"],
-			)
-		}
+		equalPatterns(t, []string{"*.html", "*.css"}, rulePatterns(t, result, "public, max-age=3600"))
 
 		// Check default
 		if result.Default != "public, max-age=3600" {
@@ -41,27 +66,9 @@ func TestParseCacheControlFormats(t *testing.T) {
 	})
 
 	t.Run("should handle empty value", func(t *testing.T) {
-		formats := []string{"*.html:public, max-age=3600", "*.css:public, max-age=3600", "*:"}
-		result := objstore.ParseCacheControlFormats(formats)
+		result := parseCacheControl(t, "*.html:public, max-age=3600", "*.css:public, max-age=3600", "*:")
 
-		// Check mapping
-		if len(result.Mapping) != 2 {
-			t.Errorf("Expected mapping to have 2 entries, got %d", len(result.Mapping))
-		}
-
-		if result.Mapping["*.html"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.html] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.html"],
-			)
-		}
-
-		if result.Mapping["*.css"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.css] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.css"],
-			)
-		}
+		equalPatterns(t, []string{"*.html", "*.css"}, rulePatterns(t, result, "public, max-age=3600"))
 
 		// Check default
 		if result.Default != "" {
@@ -70,27 +77,9 @@ func TestParseCacheControlFormats(t *testing.T) {
 	})
 
 	t.Run("should handle empty string", func(t *testing.T) {
-		formats := []string{"*.html:public, max-age=3600", "*.css:public, max-age=3600", "*: "}
-		result := objstore.ParseCacheControlFormats(formats)
-
-		// Check mapping
-		if len(result.Mapping) != 2 {
-			t.Errorf("Expected mapping to have 2 entries, got %d", len(result.Mapping))
-		}
-
-		if result.Mapping["*.html"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.html] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.html"],
-			)
-		}
+		result := parseCacheControl(t, "*.html:public, max-age=3600", "*.css:public, max-age=3600", "*: ")
 
-		if result.Mapping["*.css"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.css] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.css"],
-			)
-		}
+		equalPatterns(t, []string{"*.html", "*.css"}, rulePatterns(t, result, "public, max-age=3600"))
 
 		// Check default
 		if result.Default != " " {
@@ -99,12 +88,10 @@ func TestParseCacheControlFormats(t *testing.T) {
 	})
 
 	t.Run("should handle empty input", func(t *testing.T) {
-		var formats []string
-		result := objstore.ParseCacheControlFormats(formats)
+		result := parseCacheControl(t)
 
-		// Check mapping
-		if len(result.Mapping) != 0 {
-			t.Errorf("Expected mapping to be empty, got %d entries", len(result.Mapping))
+		if len(result.Rules) != 0 {
+			t.Errorf("Expected rules to be empty, got %d rules", len(result.Rules))
 		}
 
 		// Check default
@@ -114,56 +101,31 @@ func TestParseCacheControlFormats(t *testing.T) {
 	})
 
 	t.Run("should handle multiple keys separated by comma", func(t *testing.T) {
-		formats := []string{
+		result := parseCacheControl(t,
 			"*.html, *.htm:public, max-age=3600",
 			"*.css:public, max-age=3600",
 			"*:public, max-age=3600",
-		}
-		result := objstore.ParseCacheControlFormats(formats)
-
-		// Check mapping
-		if len(result.Mapping) != 3 {
-			t.Errorf("Expected mapping to have 3 entries, got %d", len(result.Mapping))
-		}
-
-		if result.Mapping["*.html"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.html] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.html"],
-			)
-		}
-
-		if result.Mapping["*.htm"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.htm] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.htm"],
-			)
-		}
+		)
 
-		if result.Mapping["*.css"] != "public, max-age=3600" {
-			t.Errorf(
-				"Expected mapping[*.css] to be 'public, max-age=3600', got '%s'",
-				result.Mapping["*.css"],
-			)
-		}
+		equalPatterns(t, []string{"*.html", "*.htm", "*.css"}, rulePatterns(t, result, "public, max-age=3600"))
 
 		// Check default
 		if result.Default != "public, max-age=3600" {
 			t.Errorf("Expected default to be 'public, max-age=3600', got '%s'", result.Default)
 		}
 	})
+
+	t.Run("should reject invalid pattern", func(t *testing.T) {
+		_, err := objstore.ParseCacheControlFormats([]string{"[*.html:no-cache"})
+		if err == nil {
+			t.Error("Expected an error for an invalid pattern")
+		}
+	})
 }
 
 func TestGetCacheControlValue(t *testing.T) {
 	t.Run("should return value for key", func(t *testing.T) {
-		mapping := map[string]string{
-			"*.html": "html-value",
-			"*.css":  "css-value",
-		}
-		cacheControl := objstore.CacheControlConfig{
-			Mapping: mapping,
-			Default: "default-value",
-		}
+		cacheControl := parseCacheControl(t, "*.html:html-value", "*.css:css-value", "*:default-value")
 		key := "file.html"
 		result := objstore.GetCacheControlValue(cacheControl, key)
 
@@ -173,14 +135,7 @@ func TestGetCacheControlValue(t *testing.T) {
 	})
 
 	t.Run("should return default value", func(t *testing.T) {
-		mapping := map[string]string{
-			"*.html": "html-value",
-			"*.css":  "css-value",
-		}
-		cacheControl := objstore.CacheControlConfig{
-			Mapping: mapping,
-			Default: "default-value",
-		}
+		cacheControl := parseCacheControl(t, "*.html:html-value", "*.css:css-value", "*:default-value")
 		key := "file.js"
 		result := objstore.GetCacheControlValue(cacheControl, key)
 
@@ -190,14 +145,7 @@ func TestGetCacheControlValue(t *testing.T) {
 	})
 
 	t.Run("should match long path", func(t *testing.T) {
-		mapping := map[string]string{
-			"*.html": "html-value",
-			"*.css":  "css-value",
-		}
-		cacheControl := objstore.CacheControlConfig{
-			Mapping: mapping,
-			Default: "default-value",
-		}
+		cacheControl := parseCacheControl(t, "*.html:html-value", "*.css:css-value", "*:default-value")
 		key := "path/to/file.html"
 		result := objstore.GetCacheControlValue(cacheControl, key)
 
@@ -205,4 +153,35 @@ func TestGetCacheControlValue(t *testing.T) {
 			t.Errorf("Expected result to be 'html-value', got '%s'", result)
 		}
 	})
+
+	t.Run("should match double star pattern", func(t *testing.T) {
+		cacheControl := parseCacheControl(t, "assets/**/*.js:immutable", "*:default-value")
+
+		if result := objstore.GetCacheControlValue(cacheControl, "assets/js/vendor/app.js"); result != "immutable" {
+			t.Errorf("Expected result to be 'immutable', got '%s'", result)
+		}
+
+		if result := objstore.GetCacheControlValue(cacheControl, "static/assets/app.js"); result != "default-value" {
+			t.Errorf("Expected result to be 'default-value', got '%s'", result)
+		}
+	})
+
+	t.Run("should return value of last matching pattern", func(t *testing.T) {
+		cacheControl := parseCacheControl(t, "*.js:short", "assets/**:long", "assets/sw.js:no-cache")
+
+		// Run repeatedly, so that an order depending on map iteration would show up
+		for range 20 {
+			if result := objstore.GetCacheControlValue(cacheControl, "assets/sw.js"); result != "no-cache" {
+				t.Fatalf("Expected result to be 'no-cache', got '%s'", result)
+			}
+
+			if result := objstore.GetCacheControlValue(cacheControl, "assets/app.js"); result != "long" {
+				t.Fatalf("Expected result to be 'long', got '%s'", result)
+			}
+
+			if result := objstore.GetCacheControlValue(cacheControl, "app.js"); result != "short" {
+				t.Fatalf("Expected result to be 'short', got '%s'", result)
+			}
+		}
+	})
 }
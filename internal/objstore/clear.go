@@ -0,0 +1,114 @@
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/yc-actions/sourcecraft-actions/pkg/fileset"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+)
+
+// newClearExclude compiles the clear exclude patterns once for all the object keys.
+func newClearExclude(inputs *ActionInputs) (*fileset.Matcher, error) {
+	matcher, err := fileset.New(inputs.ClearExclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clear exclude: %w", err)
+	}
+
+	return matcher, nil
+}
+
+// isProtected reports whether the object key, relative to the prefix, matches a clear exclude pattern.
+func isProtected(key string, inputs *ActionInputs, exclude *fileset.Matcher) bool {
+	return exclude.Match(strings.TrimPrefix(key, remotePrefix(inputs.Prefix)), false)
+}
+
+// checkDeleteLimit fails if more objects than ClearMaxDelete would be deleted.
+func checkDeleteLimit(keys []string, inputs *ActionInputs) error {
+	if inputs.ClearMaxDelete > 0 && len(keys) > inputs.ClearMaxDelete {
+		return fmt.Errorf(
+			"refusing to delete %d objects, the limit is %d",
+			len(keys),
+			inputs.ClearMaxDelete,
+		)
+	}
+
+	return nil
+}
+
+// ListClear returns the keys of the objects under the prefix deleted by clearing, leaving out
+// the excluded ones. With ClearAfterUpload the keys overwritten by the upload are left out too.
+// It fails if more objects than ClearMaxDelete would be deleted.
+func ListClear(
+	ctx context.Context,
+	f afero.Fs,
+	storageService storage.StorageService,
+	inputs *ActionInputs,
+) ([]string, error) {
+	remote, err := listRemote(ctx, storageService, inputs.Bucket, remotePrefix(inputs.Prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	if inputs.ClearAfterUpload {
+		entries, err := ListFiles(f, inputs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			delete(remote, entry.Key)
+		}
+	}
+
+	exclude, err := newClearExclude(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+
+	for key := range remote {
+		if !isProtected(key, inputs, exclude) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	err = checkDeleteLimit(keys, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// DeleteKeys deletes the objects from the bucket in batches and returns the number of deleted objects.
+func DeleteKeys(
+	ctx context.Context,
+	storageService storage.StorageService,
+	bucket string,
+	keys []string,
+) (int, error) {
+	total := 0
+
+	for start := 0; start < len(keys); start += maxKeysPerRequest {
+		batch := keys[start:min(start+maxKeysPerRequest, len(keys))]
+
+		sourcecraft.Info(fmt.Sprintf("Deleting %q from %s", batch, bucket))
+
+		deleted, err := storageService.DeleteObjects(ctx, bucket, batch)
+		if err != nil {
+			return total, fmt.Errorf("failed to delete objects: %w", err)
+		}
+
+		total += deleted
+	}
+
+	return total, nil
+}
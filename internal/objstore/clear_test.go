@@ -0,0 +1,113 @@
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage/mocks"
+)
+
+// TestListClear tests that clearing is scoped to the prefix and protects excluded objects.
+func TestListClear(t *testing.T) {
+	remote := []storage.ObjectInfo{
+		{Key: "assets/index.html"},
+		{Key: "assets/main.css"},
+		{Key: "assets/old.js"},
+		{Key: "assets/uploads/photo.png"},
+	}
+
+	testCases := []struct {
+		name         string
+		inputs       ActionInputs
+		expectedKeys []string
+		expectedErr  string
+	}{
+		{
+			name:         "Clear prefix",
+			inputs:       ActionInputs{},
+			expectedKeys: []string{"assets/index.html", "assets/main.css", "assets/old.js", "assets/uploads/photo.png"},
+		},
+		{
+			name:         "Clear with exclude",
+			inputs:       ActionInputs{ClearExclude: []string{"uploads/*"}},
+			expectedKeys: []string{"assets/index.html", "assets/main.css", "assets/old.js"},
+		},
+		{
+			name:         "Clear after upload",
+			inputs:       ActionInputs{ClearAfterUpload: true, ClearExclude: []string{"*.png"}},
+			expectedKeys: []string{"assets/old.js"},
+		},
+		{
+			name:        "Clear over limit",
+			inputs:      ActionInputs{ClearMaxDelete: 3},
+			expectedErr: "refusing to delete 4 objects, the limit is 3",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStorage := mocks.NewMockStorageService(t)
+			mockStorage.EXPECT().
+				ListObjects(mock.Anything, "test-bucket", "assets/", int32(1000), "").
+				Return(remote, "", false, nil)
+
+			inputs := tc.inputs
+			inputs.Bucket = "test-bucket"
+			inputs.Root = "src"
+			inputs.Include = []string{"."}
+			inputs.Exclude = []string{"*.js"}
+			inputs.Prefix = "assets"
+			inputs.Clear = true
+
+			keys, err := ListClear(context.Background(), setupTest(t), mockStorage, &inputs)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedKeys, keys)
+		})
+	}
+}
+
+// TestDeleteKeys tests that keys are deleted in batches of at most 1000.
+func TestDeleteKeys(t *testing.T) {
+	keys := make([]string, 2001)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	mockStorage := mocks.NewMockStorageService(t)
+	mockStorage.EXPECT().DeleteObjects(mock.Anything, "test-bucket", keys[:1000]).Return(1000, nil)
+	mockStorage.EXPECT().DeleteObjects(mock.Anything, "test-bucket", keys[1000:2000]).Return(1000, nil)
+	mockStorage.EXPECT().DeleteObjects(mock.Anything, "test-bucket", keys[2000:]).Return(1, nil)
+
+	deleted, err := DeleteKeys(context.Background(), mockStorage, "test-bucket", keys)
+	require.NoError(t, err)
+	assert.Equal(t, 2001, deleted)
+}
+
+// TestSyncDeleteLimit tests that sync fails before uploading when too many objects would be deleted.
+func TestSyncDeleteLimit(t *testing.T) {
+	mockStorage := mocks.NewMockStorageService(t)
+	mockStorage.EXPECT().
+		ListObjects(mock.Anything, "test-bucket", "", int32(1000), "").
+		Return([]storage.ObjectInfo{{Key: "old.js"}, {Key: "older.js"}}, "", false, nil)
+
+	_, err := Sync(context.Background(), setupTest(t), mockStorage, &ActionInputs{
+		Bucket:         "test-bucket",
+		Root:           "src",
+		Include:        []string{"."},
+		Sync:           true,
+		DeleteRemoved:  true,
+		ClearMaxDelete: 1,
+	})
+	assert.EqualError(t, err, "refusing to delete 2 objects, the limit is 1")
+}
@@ -0,0 +1,120 @@
+package objstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/spf13/afero"
+)
+
+// Supported content encodings of pre-compressed objects.
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+)
+
+// DefaultCompressMinSize is the default size from which files are compressed.
+const DefaultCompressMinSize = 1024
+
+// SourceMD5Metadata is the user metadata entry with the hex MD5 of the source file of an object
+// that is compressed on upload, so that sync can compare the file without compressing it again.
+const SourceMD5Metadata = "source-md5"
+
+// CompressionConfig selects the files compressed before upload.
+type CompressionConfig struct {
+	// Encodings maps glob patterns of object keys to gzip or br.
+	Encodings GlobValues
+	// MinSize is the file size in bytes from which files are compressed.
+	MinSize int64
+}
+
+// Validate checks that all encodings are supported.
+func (c CompressionConfig) Validate() error {
+	values := []string{c.Encodings.Default}
+	for _, rule := range c.Encodings.Rules {
+		values = append(values, rule.Value)
+	}
+
+	for _, value := range values {
+		switch value {
+		case "", EncodingGzip, EncodingBrotli:
+		default:
+			return fmt.Errorf("unsupported compression %q, use %s or %s", value, EncodingGzip, EncodingBrotli)
+		}
+	}
+
+	return nil
+}
+
+// compress returns the data compressed with the encoding.
+func compress(data []byte, encoding string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	var writer io.WriteCloser
+
+	switch encoding {
+	case EncodingGzip:
+		gzipWriter, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+		}
+
+		writer = gzipWriter
+	case EncodingBrotli:
+		writer = brotli.NewWriterLevel(buf, brotli.BestCompression)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", encoding)
+	}
+
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// compressionEncoding returns the encoding the file is compressed with, or an empty string if it
+// doesn't match or is smaller than MinSize.
+func compressionEncoding(entry FileEntry, size int64, config CompressionConfig) string {
+	if size < config.MinSize {
+		return ""
+	}
+
+	return config.Encodings.Lookup(entry.Key)
+}
+
+// compressFile compresses the file with the encoding matched by glob. It returns nil data
+// if the file doesn't match, is smaller than MinSize or doesn't get smaller when compressed,
+// so it must be uploaded as is. Compression is deterministic, so the result can be compared
+// with the ETag of a previously uploaded object.
+func compressFile(f afero.Fs, entry FileEntry, size int64, config CompressionConfig) ([]byte, string, error) {
+	encoding := compressionEncoding(entry, size, config)
+	if encoding == "" {
+		return nil, "", nil
+	}
+
+	data, err := afero.ReadFile(f, entry.Path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	compressed, err := compress(data, encoding)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(compressed) >= len(data) {
+		return nil, "", nil
+	}
+
+	return compressed, encoding, nil
+}
@@ -0,0 +1,213 @@
+package objstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage/mocks"
+)
+
+// setupCompressTest creates a file system with compressible, incompressible and small files.
+func setupCompressTest(t *testing.T) (afero.Fs, []byte) {
+	appFS := afero.NewMemMapFs()
+	html := []byte(strings.Repeat("<p>hello</p>", 200))
+
+	random := make([]byte, 4096)
+	_, err := rand.New(rand.NewSource(1)).Read(random)
+	require.NoError(t, err)
+
+	require.NoError(t, afero.WriteFile(appFS, "src/index.html", html, 0o644))
+	require.NoError(t, afero.WriteFile(appFS, "src/image.png", random, 0o644))
+	require.NoError(t, afero.WriteFile(appFS, "src/small.css", []byte("body{}"), 0o644))
+
+	return appFS, html
+}
+
+func TestCompressFile(t *testing.T) {
+	f, html := setupCompressTest(t)
+	config := CompressionConfig{
+		Encodings: parseGlobValues(t, "*.html:gzip", "*:br"),
+		MinSize:   DefaultCompressMinSize,
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		data, encoding, err := compressFile(f, FileEntry{Path: "src/index.html", Key: "index.html"}, 2400, config)
+		require.NoError(t, err)
+		assert.Equal(t, EncodingGzip, encoding)
+
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+
+		decompressed, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, html, decompressed)
+	})
+
+	t.Run("brotli", func(t *testing.T) {
+		config := CompressionConfig{Encodings: parseGlobValues(t, "*:br")}
+
+		data, encoding, err := compressFile(f, FileEntry{Path: "src/index.html", Key: "index.html"}, 2400, config)
+		require.NoError(t, err)
+		assert.Equal(t, EncodingBrotli, encoding)
+
+		decompressed, err := io.ReadAll(brotli.NewReader(bytes.NewReader(data)))
+		require.NoError(t, err)
+		assert.Equal(t, html, decompressed)
+	})
+
+	t.Run("skip incompressible", func(t *testing.T) {
+		data, encoding, err := compressFile(f, FileEntry{Path: "src/image.png", Key: "image.png"}, 4096, config)
+		require.NoError(t, err)
+		assert.Nil(t, data)
+		assert.Empty(t, encoding)
+	})
+
+	t.Run("skip small", func(t *testing.T) {
+		data, _, err := compressFile(f, FileEntry{Path: "src/small.css", Key: "small.css"}, 6, config)
+		require.NoError(t, err)
+		assert.Nil(t, data)
+	})
+}
+
+func TestCompressionConfigValidate(t *testing.T) {
+	assert.NoError(t, CompressionConfig{Encodings: parseGlobValues(t, "*.js:br", "*:gzip")}.Validate())
+	assert.ErrorContains(
+		t,
+		CompressionConfig{Encodings: parseGlobValues(t, "*.js:zstd")}.Validate(),
+		`unsupported compression "zstd"`,
+	)
+}
+
+// TestUploadCompressed tests that compressed files are uploaded with Content-Encoding and the MD5 of the source,
+// and skipped by sync when unchanged.
+func TestUploadCompressed(t *testing.T) {
+	f, html := setupCompressTest(t)
+	inputs := &ActionInputs{
+		Bucket:  "test-bucket",
+		Root:    "src",
+		Include: []string{"."},
+		Compression: CompressionConfig{
+			Encodings: parseGlobValues(t, "*:gzip"),
+			MinSize:   DefaultCompressMinSize,
+		},
+	}
+
+	compressed, err := compress(html, EncodingGzip)
+	require.NoError(t, err)
+
+	sum := md5.Sum(compressed)
+	sourceSum := md5.Sum(html)
+	remote := storage.ObjectInfo{Key: "index.html", ETag: hex.EncodeToString(sum[:]), Size: int64(len(compressed))}
+
+	t.Run("upload", func(t *testing.T) {
+		mockStorage := mocks.NewMockStorageService(t)
+		mockStorage.EXPECT().
+			PutObject(mock.Anything, mock.MatchedBy(func(obj *storage.StorageObject) bool {
+				return obj.ObjectName == "index.html"
+			})).
+			RunAndReturn(func(_ context.Context, obj *storage.StorageObject) error {
+				assert.Equal(t, EncodingGzip, obj.ContentEncoding)
+				assert.Equal(t, "text/html; charset=utf-8", obj.ContentType)
+				assert.Equal(t, string(compressed), obj.GetData())
+				assert.Equal(t, hex.EncodeToString(sourceSum[:]), obj.Metadata[SourceMD5Metadata])
+
+				return nil
+			}).
+			Once()
+		mockStorage.EXPECT().
+			PutObject(mock.Anything, mock.MatchedBy(func(obj *storage.StorageObject) bool {
+				return obj.ObjectName == "image.png"
+			})).
+			RunAndReturn(func(_ context.Context, obj *storage.StorageObject) error {
+				// Uploaded as is, because compression doesn't make it smaller, but still matched
+				assert.Empty(t, obj.ContentEncoding)
+				assert.Contains(t, obj.Metadata, SourceMD5Metadata)
+
+				return nil
+			}).
+			Once()
+		mockStorage.EXPECT().
+			PutObject(mock.Anything, mock.MatchedBy(func(obj *storage.StorageObject) bool {
+				return obj.ObjectName == "small.css"
+			})).
+			RunAndReturn(func(_ context.Context, obj *storage.StorageObject) error {
+				assert.NotContains(t, obj.Metadata, SourceMD5Metadata)
+
+				return nil
+			}).
+			Once()
+
+		err := Upload(context.Background(), f, mockStorage, inputs)
+		require.NoError(t, err)
+	})
+
+	testCases := []struct {
+		name      string
+		head      storage.ObjectInfo
+		unchanged bool
+	}{
+		{
+			name: "source md5 matches",
+			head: storage.ObjectInfo{
+				ContentEncoding: EncodingGzip,
+				Metadata:        map[string]string{SourceMD5Metadata: hex.EncodeToString(sourceSum[:])},
+			},
+			unchanged: true,
+		},
+		{
+			name: "source md5 differs",
+			head: storage.ObjectInfo{
+				ContentEncoding: EncodingGzip,
+				Metadata:        map[string]string{SourceMD5Metadata: strings.Repeat("0", 32)},
+			},
+		},
+		{
+			name: "encoding differs",
+			head: storage.ObjectInfo{
+				ContentEncoding: EncodingBrotli,
+				Metadata:        map[string]string{SourceMD5Metadata: hex.EncodeToString(sourceSum[:])},
+			},
+		},
+		{
+			// Uploaded before the source MD5 was stored: the file is compressed again and compared with the ETag
+			name:      "no source md5",
+			head:      storage.ObjectInfo{ContentEncoding: EncodingGzip},
+			unchanged: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStorage := mocks.NewMockStorageService(t)
+			mockStorage.EXPECT().
+				ListObjects(mock.Anything, "test-bucket", "", int32(1000), "").
+				Return([]storage.ObjectInfo{remote}, "", false, nil)
+			mockStorage.EXPECT().
+				HeadObject(mock.Anything, "test-bucket", "index.html").
+				Return(&tc.head, nil).
+				Once()
+
+			diff, err := Diff(context.Background(), f, mockStorage, inputs)
+			require.NoError(t, err)
+
+			if tc.unchanged {
+				assert.Equal(t, []FileEntry{{Path: "src/index.html", Key: "index.html"}}, diff.Skip)
+			} else {
+				assert.Empty(t, diff.Skip)
+			}
+		})
+	}
+}
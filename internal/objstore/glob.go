@@ -0,0 +1,80 @@
+package objstore
+
+import "github.com/yc-actions/sourcecraft-actions/pkg/fileset"
+
+// GlobRule maps a gitignore-style pattern of object keys to a value. The pattern is compiled once,
+// when the input is parsed: a pattern without a slash matches the name at any depth, a pattern with one
+// is matched from the start of the key.
+type GlobRule struct {
+	Pattern string
+	Value   string
+	matcher *fileset.Matcher
+}
+
+// NewGlobRule compiles the pattern of the rule.
+func NewGlobRule(pattern, value string) (GlobRule, error) {
+	matcher, err := fileset.New([]string{pattern})
+	if err != nil {
+		return GlobRule{}, err
+	}
+
+	return GlobRule{Pattern: pattern, Value: value, matcher: matcher}, nil
+}
+
+// Match reports whether the pattern matches the object key.
+func (r GlobRule) Match(key string) bool {
+	return r.matcher.Match(key, false)
+}
+
+// GlobValues maps glob patterns of object keys to values, e.g. of a header.
+type GlobValues struct {
+	// Rules are the patterns in declaration order, the last matching one wins.
+	Rules   []GlobRule
+	Default string
+}
+
+// Lookup returns the value of the last pattern matching the key, or the default.
+func (v GlobValues) Lookup(key string) string {
+	return lookupRules(v.Rules, v.Default, key)
+}
+
+// lookupRules returns the value of the last rule matching the key, or the default.
+func lookupRules(rules []GlobRule, defaultValue, key string) string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].Match(key) {
+			return rules[i].Value
+		}
+	}
+
+	return defaultValue
+}
+
+// ParseGlobValues parses "pattern[,pattern]:value" formats. The "*" pattern sets the default value,
+// and of the other patterns the last matching one wins.
+func ParseGlobValues(formats []string) (GlobValues, error) {
+	var values GlobValues
+
+	for _, format := range formats {
+		patterns, value, ok := splitGlobFormat(format)
+		if !ok {
+			continue
+		}
+
+		for _, pattern := range patterns {
+			if pattern == "*" {
+				values.Default = value
+
+				continue
+			}
+
+			rule, err := NewGlobRule(pattern, value)
+			if err != nil {
+				return GlobValues{}, err
+			}
+
+			values.Rules = append(values.Rules, rule)
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,159 @@
+package objstore
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/yc-actions/sourcecraft-actions/pkg/fileset"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+)
+
+// MetadataRule sets the user metadata entry on objects matching any of the patterns.
+type MetadataRule struct {
+	Patterns []string
+	Name     string
+	Value    string
+	matcher  *fileset.Matcher
+}
+
+// ObjectHeaders holds the headers and user metadata set on uploaded objects by glob.
+type ObjectHeaders struct {
+	ContentType        GlobValues
+	ContentEncoding    GlobValues
+	ContentDisposition GlobValues
+	ContentLanguage    GlobValues
+	Expires            GlobValues
+	StorageClass       GlobValues
+	ACL                GlobValues
+	Metadata           []MetadataRule
+}
+
+// splitGlobFormat splits a "pattern[,pattern]:value" format at the first colon,
+// so that the value may contain colons.
+func splitGlobFormat(format string) ([]string, string, bool) {
+	keysPart, value, ok := strings.Cut(format, ":")
+	if !ok {
+		return nil, "", false
+	}
+
+	var patterns []string
+
+	for _, pattern := range strings.Split(keysPart, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns, strings.TrimSpace(value), len(patterns) > 0
+}
+
+// ParseMetadataFormats parses "pattern[,pattern]:name=value" formats of user metadata.
+func ParseMetadataFormats(formats []string) ([]MetadataRule, error) {
+	var rules []MetadataRule
+
+	for _, format := range formats {
+		if strings.TrimSpace(format) == "" {
+			continue
+		}
+
+		patterns, entry, ok := splitGlobFormat(format)
+		if !ok {
+			return nil, fmt.Errorf("metadata %q must have format pattern:name=value", format)
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("metadata %q must have format pattern:name=value", format)
+		}
+
+		// "*" matches every key, like the default of the other headers
+		matcher, err := fileset.New(patterns)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, MetadataRule{
+			Patterns: patterns,
+			Name:     strings.TrimSpace(name),
+			Value:    strings.TrimSpace(value),
+			matcher:  matcher,
+		})
+	}
+
+	return rules, nil
+}
+
+// GetMetadata returns the user metadata of the object key, or nil if no rule matches.
+func GetMetadata(rules []MetadataRule, key string) map[string]string {
+	var metadata map[string]string
+
+	for _, rule := range rules {
+		if !rule.matcher.Match(key, false) {
+			continue
+		}
+
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+
+		metadata[rule.Name] = rule.Value
+	}
+
+	return metadata
+}
+
+// Validate checks that all Expires values are HTTP dates.
+func (h ObjectHeaders) Validate() error {
+	values := []string{h.Expires.Default}
+	for _, rule := range h.Expires.Rules {
+		values = append(values, rule.Value)
+	}
+
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
+		_, err := http.ParseTime(value)
+		if err != nil {
+			return fmt.Errorf("expires %q is not an HTTP date: %w", value, err)
+		}
+	}
+
+	return nil
+}
+
+// setHeaders sets the headers and user metadata of the object matched by the input globs.
+// Content-Type defaults to the type registered for the key extension.
+func setHeaders(object *storage.StorageObject, inputs *ActionInputs) error {
+	key := object.ObjectName
+	headers := inputs.Headers
+
+	object.CacheControl = GetCacheControlValue(inputs.CacheControl, key)
+
+	object.ContentType = headers.ContentType.Lookup(key)
+	if object.ContentType == "" {
+		object.ContentType = mime.TypeByExtension(filepath.Ext(key))
+	}
+
+	object.ContentEncoding = headers.ContentEncoding.Lookup(key)
+	object.ContentDisposition = headers.ContentDisposition.Lookup(key)
+	object.ContentLanguage = headers.ContentLanguage.Lookup(key)
+	object.StorageClass = headers.StorageClass.Lookup(key)
+	object.ACL = headers.ACL.Lookup(key)
+	object.Metadata = GetMetadata(headers.Metadata, key)
+
+	if expires := headers.Expires.Lookup(key); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return fmt.Errorf("failed to parse expires: %w", err)
+		}
+
+		object.Expires = &t
+	}
+
+	return nil
+}
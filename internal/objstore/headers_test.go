@@ -0,0 +1,67 @@
+package objstore_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/internal/objstore"
+)
+
+func TestParseGlobValues(t *testing.T) {
+	config, err := objstore.ParseGlobValues([]string{
+		"*.html, *.htm: text/html; charset=utf-8",
+		"*.pdf:Wed, 21 Oct 2015 07:28:00 GMT",
+		"*:application/octet-stream",
+		"invalid",
+	})
+	require.NoError(t, err)
+
+	patterns := make([]string, 0, len(config.Rules))
+	for _, rule := range config.Rules {
+		patterns = append(patterns, rule.Pattern)
+	}
+
+	assert.Equal(t, []string{"*.html", "*.htm", "*.pdf"}, patterns)
+	assert.Equal(t, "text/html; charset=utf-8", config.Lookup("index.htm"))
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", config.Lookup("docs/a.pdf"))
+	assert.Equal(t, "application/octet-stream", config.Default)
+
+	_, err = objstore.ParseGlobValues([]string{"[.html:text/html"})
+	assert.Error(t, err)
+}
+
+func TestParseMetadataFormats(t *testing.T) {
+	t.Run("should parse rules", func(t *testing.T) {
+		rules, err := objstore.ParseMetadataFormats([]string{
+			"*: commit = abc",
+			"*.html,*.css:team=web",
+			"",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{"commit": "abc", "team": "web"}, objstore.GetMetadata(rules, "a/index.html"))
+		assert.Equal(t, map[string]string{"commit": "abc"}, objstore.GetMetadata(rules, "app.js"))
+	})
+
+	t.Run("should reject rule without value", func(t *testing.T) {
+		_, err := objstore.ParseMetadataFormats([]string{"*.html:team"})
+		assert.ErrorContains(t, err, "pattern:name=value")
+	})
+
+	t.Run("should return nil without rules", func(t *testing.T) {
+		assert.Nil(t, objstore.GetMetadata(nil, "index.html"))
+	})
+}
+
+func TestObjectHeadersValidate(t *testing.T) {
+	expires, err := objstore.ParseGlobValues([]string{"*.pdf:Wed, 21 Oct 2015 07:28:00 GMT"})
+	require.NoError(t, err)
+
+	headers := objstore.ObjectHeaders{Expires: expires}
+	assert.NoError(t, headers.Validate())
+
+	headers.Expires, err = objstore.ParseGlobValues([]string{"*:tomorrow"})
+	require.NoError(t, err)
+	assert.ErrorContains(t, headers.Validate(), `expires "tomorrow"`)
+}
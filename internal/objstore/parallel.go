@@ -0,0 +1,140 @@
+package objstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+)
+
+// Upload retry parameters. The delay doubles after every failed attempt.
+var (
+	maxUploadAttempts = 5
+	retryBaseDelay    = 500 * time.Millisecond
+)
+
+// uploadReport is the outcome of uploading a single file with the log lines produced meanwhile.
+type uploadReport struct {
+	lines []string
+	err   error
+}
+
+// uploadWithRetry uploads the file, retrying transient Object Storage errors with exponential backoff.
+func uploadWithRetry(
+	ctx context.Context,
+	f afero.Fs,
+	storageService storage.StorageService,
+	entry FileEntry,
+	inputs *ActionInputs,
+) *uploadReport {
+	report := &uploadReport{
+		lines: []string{fmt.Sprintf("Uploading %s to %s/%s", entry.Path, inputs.Bucket, entry.Key)},
+	}
+
+	logf := func(line string) {
+		report.lines = append(report.lines, line)
+	}
+
+	delay := retryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := uploadFile(ctx, f, storageService, entry, inputs, logf)
+		if err == nil {
+			return report
+		}
+
+		if attempt >= maxUploadAttempts || !storage.IsRetryable(err) {
+			report.err = fmt.Errorf("%s: %w", entry.Path, err)
+			report.lines = append(report.lines, fmt.Sprintf("Failed after %d attempts: %v", attempt, err))
+
+			return report
+		}
+
+		report.lines = append(
+			report.lines,
+			fmt.Sprintf("Attempt %d/%d failed: %v. Retrying in %s", attempt, maxUploadAttempts, err, delay),
+		)
+
+		select {
+		case <-ctx.Done():
+			report.err = fmt.Errorf("%s: %w", entry.Path, ctx.Err())
+
+			return report
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
+
+// uploadAll uploads the files using up to inputs.Concurrency parallel workers.
+// Logs of each file are printed together and in the order of the files, whatever order
+// the uploads finish in. All failed files are reported in the returned error.
+func uploadAll(
+	ctx context.Context,
+	f afero.Fs,
+	storageService storage.StorageService,
+	entries []FileEntry,
+	inputs *ActionInputs,
+) error {
+	workers := max(inputs.Concurrency, 1)
+
+	reports := make([]*uploadReport, len(entries))
+	done := make([]chan struct{}, len(entries))
+
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				reports[i] = uploadWithRetry(ctx, f, storageService, entries[i], inputs)
+				close(done[i])
+			}
+		}()
+	}
+
+	go func() {
+		for i := range entries {
+			indexes <- i
+		}
+
+		close(indexes)
+	}()
+
+	var errs []error
+
+	for i := range entries {
+		<-done[i]
+
+		for _, line := range reports[i].lines {
+			sourcecraft.Info(line)
+		}
+
+		if reports[i].err != nil {
+			errs = append(errs, reports[i].err)
+		}
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to upload %d of %d files: %w", len(errs), len(entries), errors.Join(errs...))
+	}
+
+	return nil
+}
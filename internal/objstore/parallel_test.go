@@ -0,0 +1,64 @@
+package objstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage/mocks"
+)
+
+// TestUploadRetry tests that transient errors are retried and all failed files are reported.
+func TestUploadRetry(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+
+	t.Cleanup(func() { retryBaseDelay = 500 * time.Millisecond })
+
+	mockStorage := mocks.NewMockStorageService(t)
+	f := setupTest(t)
+
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string]int)
+	)
+
+	mockStorage.EXPECT().
+		PutObject(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, object *storage.StorageObject) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			attempts[object.ObjectName]++
+
+			switch {
+			case object.ObjectName == "index.html" && attempts[object.ObjectName] < 3:
+				return &smithy.GenericAPIError{Code: "SlowDown", Message: "reduce your request rate"}
+			case object.ObjectName == "main.css":
+				return &smithy.GenericAPIError{Code: "AccessDenied", Message: "access denied"}
+			}
+
+			return nil
+		})
+
+	err := Upload(context.Background(), f, mockStorage, &ActionInputs{
+		Bucket:      "test-bucket",
+		Root:        "src",
+		Include:     []string{"."},
+		Concurrency: 3,
+	})
+
+	assert.ErrorContains(t, err, "failed to upload 1 of 3 files")
+	assert.ErrorContains(t, err, "src/main.css")
+	assert.NotContains(t, err.Error(), "src/index.html")
+
+	var apiErr smithy.APIError
+	assert.True(t, errors.As(err, &apiErr))
+
+	assert.Equal(t, map[string]int{"index.html": 3, "main.css": 1, "a/index.js": 1}, attempts)
+}
@@ -0,0 +1,296 @@
+package objstore
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+)
+
+// maxKeysPerRequest is the maximum number of keys listed or deleted by one request.
+const maxKeysPerRequest = 1000
+
+// SyncDiff is the difference between the local files and the objects under the bucket prefix.
+type SyncDiff struct {
+	Upload []FileEntry `json:"upload"`
+	Skip   []FileEntry `json:"skip"`
+	Delete []string    `json:"delete"`
+}
+
+// SyncResult holds the counts of objects changed by a sync.
+type SyncResult struct {
+	Uploaded int
+	Skipped  int
+	Deleted  int
+}
+
+// remotePrefix returns the listing prefix of the objects uploaded under the prefix.
+func remotePrefix(prefix string) string {
+	prefix = filepath.Clean(prefix)
+	if prefix == "." {
+		return ""
+	}
+
+	return prefix + "/"
+}
+
+// listRemote lists all objects under the prefix keyed by object name.
+func listRemote(
+	ctx context.Context,
+	storageService storage.StorageService,
+	bucket, prefix string,
+) (map[string]storage.ObjectInfo, error) {
+	result := make(map[string]storage.ObjectInfo)
+
+	var continuationToken string
+
+	for {
+		objects, nextContinuationToken, truncated, err := storageService.ListObjects(
+			ctx,
+			bucket,
+			prefix,
+			maxKeysPerRequest,
+			continuationToken,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, object := range objects {
+			result[object.Key] = object
+		}
+
+		if !truncated {
+			return result, nil
+		}
+
+		continuationToken = nextContinuationToken
+	}
+}
+
+// fileMD5 returns the hex MD5 of the file content.
+func fileMD5(f afero.Fs, path string) (string, error) {
+	file, err := f.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	// Object Storage reports the MD5 of single-part uploads as the ETag
+	hash := md5.New()
+
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isUnchanged reports whether the remote object has the same size and content as the local file
+// would have when uploaded, compressed if requested.
+// Objects uploaded in multiple parts have no MD5 ETag and are always considered changed.
+func isUnchanged(
+	ctx context.Context,
+	f afero.Fs,
+	storageService storage.StorageService,
+	entry FileEntry,
+	remote storage.ObjectInfo,
+	inputs *ActionInputs,
+) (bool, error) {
+	info, err := f.Stat(entry.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if strings.Contains(remote.ETag, "-") {
+		return false, nil
+	}
+
+	encoding := compressionEncoding(entry, info.Size(), inputs.Compression)
+	if encoding != "" {
+		return isCompressedUnchanged(ctx, f, storageService, entry, remote, inputs, encoding)
+	}
+
+	if info.Size() != remote.Size {
+		return false, nil
+	}
+
+	sum, err := fileMD5(f, entry.Path)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(sum, remote.ETag), nil
+}
+
+// isCompressedUnchanged reports whether the object of a file matched for compression is unchanged.
+// The MD5 of the file is compared with the source MD5 stored in the object metadata on upload; the file is
+// compressed again and compared with the ETag only for objects uploaded without it.
+func isCompressedUnchanged(
+	ctx context.Context,
+	f afero.Fs,
+	storageService storage.StorageService,
+	entry FileEntry,
+	remote storage.ObjectInfo,
+	inputs *ActionInputs,
+	encoding string,
+) (bool, error) {
+	head, err := storageService.HeadObject(ctx, inputs.Bucket, remote.Key)
+	if err != nil {
+		return false, err
+	}
+
+	if sourceMD5, ok := head.Metadata[SourceMD5Metadata]; ok {
+		// An object uploaded as is, because compression didn't make it smaller, has no encoding
+		if head.ContentEncoding != "" && head.ContentEncoding != encoding {
+			return false, nil
+		}
+
+		sum, err := fileMD5(f, entry.Path)
+		if err != nil {
+			return false, err
+		}
+
+		return strings.EqualFold(sum, sourceMD5), nil
+	}
+
+	info, err := f.Stat(entry.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	compressed, _, err := compressFile(f, entry, info.Size(), inputs.Compression)
+	if err != nil {
+		return false, err
+	}
+
+	data := compressed
+	if data == nil {
+		data, err = afero.ReadFile(f, entry.Path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	sum := md5.Sum(data)
+
+	return int64(len(data)) == remote.Size && strings.EqualFold(hex.EncodeToString(sum[:]), remote.ETag), nil
+}
+
+// Diff compares the local files with the objects under the bucket prefix.
+// Remote objects missing locally are only listed for deletion when DeleteRemoved is set
+// and they don't match ClearExclude.
+func Diff(
+	ctx context.Context,
+	f afero.Fs,
+	storageService storage.StorageService,
+	inputs *ActionInputs,
+) (*SyncDiff, error) {
+	entries, err := ListFiles(f, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := listRemote(ctx, storageService, inputs.Bucket, remotePrefix(inputs.Prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SyncDiff{}
+
+	for _, entry := range entries {
+		object, ok := remote[entry.Key]
+		delete(remote, entry.Key)
+
+		unchanged := false
+		if ok {
+			unchanged, err = isUnchanged(ctx, f, storageService, entry, object, inputs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if unchanged {
+			diff.Skip = append(diff.Skip, entry)
+		} else {
+			diff.Upload = append(diff.Upload, entry)
+		}
+	}
+
+	if inputs.DeleteRemoved {
+		exclude, err := newClearExclude(inputs)
+		if err != nil {
+			return nil, err
+		}
+
+		for key := range remote {
+			if !isProtected(key, inputs, exclude) {
+				diff.Delete = append(diff.Delete, key)
+			}
+		}
+
+		sort.Strings(diff.Delete)
+	}
+
+	return diff, nil
+}
+
+// Sync uploads the changed files and deletes the remote objects removed locally if requested.
+func Sync(
+	ctx context.Context,
+	f afero.Fs,
+	storageService storage.StorageService,
+	inputs *ActionInputs,
+) (*SyncResult, error) {
+	sourcecraft.StartGroup("Sync")
+	defer sourcecraft.EndGroup()
+
+	diff, err := Diff(ctx, f, storageService, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the limit before uploading so an unexpected diff changes nothing
+	err = checkDeleteLimit(diff.Delete, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Skipped: len(diff.Skip)}
+
+	for _, entry := range diff.Skip {
+		sourcecraft.Debug(fmt.Sprintf("Skipping unchanged %s", entry.Key))
+	}
+
+	err = uploadAll(ctx, f, storageService, diff.Upload, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Uploaded = len(diff.Upload)
+
+	result.Deleted, err = DeleteKeys(ctx, storageService, inputs.Bucket, diff.Delete)
+	if err != nil {
+		return nil, err
+	}
+
+	sourcecraft.Info(
+		fmt.Sprintf(
+			"Sync complete: %d uploaded, %d skipped, %d deleted",
+			result.Uploaded,
+			result.Skipped,
+			result.Deleted,
+		),
+	)
+
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package objstore
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
+	"github.com/yc-actions/sourcecraft-actions/pkg/storage/mocks"
+)
+
+// etag returns the ETag Object Storage reports for a single-part upload of the content.
+func etag(content string) string {
+	sum := md5.Sum([]byte(content))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// TestSync tests that Sync uploads only changed files and deletes removed ones.
+func TestSync(t *testing.T) {
+	testCases := []struct {
+		name             string
+		deleteRemoved    bool
+		expectedUploads  []string
+		expectedDeletes  []string
+		expectedSkipped  int
+		expectedUploaded int
+		expectedDeleted  int
+	}{
+		{
+			name:             "Upload changed and new files",
+			expectedUploads:  []string{"assets/index.html", "assets/a/index.js"},
+			expectedSkipped:  1,
+			expectedUploaded: 2,
+		},
+		{
+			name:             "Delete removed files",
+			deleteRemoved:    true,
+			expectedUploads:  []string{"assets/index.html", "assets/a/index.js"},
+			expectedDeletes:  []string{"assets/old.js", "assets/removed.css"},
+			expectedSkipped:  1,
+			expectedUploaded: 2,
+			expectedDeleted:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockStorage := mocks.NewMockStorageService(t)
+			f := setupTest(t)
+
+			// Remote listing is split into two pages
+			mockStorage.EXPECT().
+				ListObjects(mock.Anything, "test-bucket", "assets/", int32(1000), "").
+				Return([]storage.ObjectInfo{
+					{Key: "assets/main.css", ETag: etag("body"), Size: 4},
+					{Key: "assets/index.html", ETag: etag("<html>old</html>"), Size: 16},
+				}, "page-2", true, nil)
+			mockStorage.EXPECT().
+				ListObjects(mock.Anything, "test-bucket", "assets/", int32(1000), "page-2").
+				Return([]storage.ObjectInfo{
+					{Key: "assets/old.js", ETag: etag("old"), Size: 3},
+					{Key: "assets/removed.css", ETag: etag("removed"), Size: 7},
+				}, "", false, nil)
+
+			var uploaded []string
+
+			mockStorage.EXPECT().
+				PutObject(mock.Anything, mock.Anything).
+				RunAndReturn(func(_ context.Context, object *storage.StorageObject) error {
+					uploaded = append(uploaded, object.ObjectName)
+
+					return nil
+				}).
+				Times(len(tc.expectedUploads))
+
+			if len(tc.expectedDeletes) > 0 {
+				mockStorage.EXPECT().
+					DeleteObjects(mock.Anything, "test-bucket", tc.expectedDeletes).
+					Return(len(tc.expectedDeletes), nil)
+			}
+
+			result, err := Sync(ctx, f, mockStorage, &ActionInputs{
+				Bucket:        "test-bucket",
+				Root:          "src",
+				Include:       []string{"."},
+				Prefix:        "assets",
+				Sync:          true,
+				DeleteRemoved: tc.deleteRemoved,
+			})
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expectedUploads, uploaded)
+			assert.Equal(t, tc.expectedUploaded, result.Uploaded)
+			assert.Equal(t, tc.expectedSkipped, result.Skipped)
+			assert.Equal(t, tc.expectedDeleted, result.Deleted)
+		})
+	}
+}
+
+// TestDiffMultipartETag tests that objects without an MD5 ETag are always uploaded.
+func TestDiffMultipartETag(t *testing.T) {
+	mockStorage := mocks.NewMockStorageService(t)
+	f := setupTest(t)
+
+	mockStorage.EXPECT().
+		ListObjects(mock.Anything, "test-bucket", "", int32(1000), "").
+		Return([]storage.ObjectInfo{
+			{Key: "main.css", ETag: etag("body") + "-2", Size: 4},
+		}, "", false, nil)
+
+	diff, err := Diff(context.Background(), f, mockStorage, &ActionInputs{
+		Bucket:  "test-bucket",
+		Root:    "src",
+		Include: []string{"main.css"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []FileEntry{{Path: "src/main.css", Key: "main.css"}}, diff.Upload)
+	assert.Empty(t, diff.Skip)
+}
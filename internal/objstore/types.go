@@ -9,10 +9,27 @@ type ActionInputs struct {
 	Exclude      []string
 	Clear        bool
 	CacheControl CacheControlConfig
+	// ClearExclude are glob patterns of object keys, relative to the prefix, that are never deleted.
+	ClearExclude []string
+	// ClearMaxDelete aborts the run if more objects would be deleted. Zero means no limit.
+	ClearMaxDelete int
+	// ClearAfterUpload deletes only the objects that were not overwritten, after a successful upload.
+	ClearAfterUpload bool
+	// Headers are the other headers and user metadata set by glob.
+	Headers ObjectHeaders
+	// Compression selects the files compressed before upload.
+	Compression CompressionConfig
+	// Sync uploads only files that differ from the objects under the prefix.
+	Sync bool
+	// DeleteRemoved deletes objects under the prefix that have no local file when syncing.
+	DeleteRemoved bool
+	// Concurrency is the number of files uploaded in parallel.
+	Concurrency int
 }
 
 // CacheControlConfig represents the cache control configuration.
 type CacheControlConfig struct {
-	Mapping map[string]string
+	// Rules are the patterns in declaration order, the last matching one wins.
+	Rules   []GlobRule
 	Default string
 }
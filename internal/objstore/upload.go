@@ -1,61 +1,92 @@
 package objstore
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"io/fs"
-	"mime"
+	"io"
 	"path/filepath"
 
 	"github.com/spf13/afero"
+	"github.com/yc-actions/sourcecraft-actions/pkg/fileset"
 	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
 	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
 )
 
-// uploadFile uploads a file to object storage.
+// FileEntry is a local file matched by the inputs and the object key it is uploaded to.
+type FileEntry struct {
+	Path string `json:"path"`
+	Key  string `json:"key"`
+}
+
+// uploadFile uploads a file to object storage. Log lines, such as the progress of multipart
+// uploads, are passed to logf.
 func uploadFile(
 	ctx context.Context,
 	f afero.Fs,
 	storageService storage.StorageService,
-	filePath, root, bucket, prefix string,
-	cacheControl CacheControlConfig,
+	entry FileEntry,
+	inputs *ActionInputs,
+	logf func(string),
 ) error {
-	// Check if file is a directory
-	info, err := f.Stat(filePath)
+	info, err := f.Stat(entry.Path)
 	if err != nil {
 		return fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	if info.IsDir() {
-		return nil
-	}
-
-	// Get relative path
-	relPath, err := filepath.Rel(root, filePath)
+	// Compress file if requested
+	compressed, encoding, err := compressFile(f, entry, info.Size(), inputs.Compression)
 	if err != nil {
-		return fmt.Errorf("failed to get relative path: %w", err)
+		return err
 	}
 
-	// Build object key
-	key := relPath
-	if prefix != "" {
-		key = filepath.Join(prefix, key)
+	// Create storage object
+	var storageObject *storage.StorageObject
+
+	if compressed != nil {
+		logf(fmt.Sprintf("Compressed with %s: %d -> %d bytes", encoding, info.Size(), len(compressed)))
+
+		storageObject = storage.NewStorageObject(inputs.Bucket, entry.Key, io.NopCloser(bytes.NewReader(compressed)))
+		storageObject.Size = int64(len(compressed))
+	} else {
+		file, err := f.Open(entry.Path)
+		if err != nil {
+			return err
+		}
+
+		storageObject = storage.NewStorageObject(inputs.Bucket, entry.Key, file)
+		storageObject.Size = info.Size()
 	}
+	defer storageObject.Close()
 
-	// Create storage object
-	sourcecraft.Info(fmt.Sprintf("Uploading %s to %s/%s", filePath, bucket, key))
+	storageObject.OnProgress = func(uploaded, total int64) {
+		logf(fmt.Sprintf("Uploaded %d of %d bytes", uploaded, total))
+	}
 
-	file, err := f.Open(filePath)
+	// Set headers and metadata matched by the input globs
+	err = setHeaders(storageObject, inputs)
 	if err != nil {
 		return err
 	}
 
-	storageObject := storage.NewStorageObject(bucket, key, file)
-	defer storageObject.Close()
+	if encoding != "" {
+		storageObject.ContentEncoding = encoding
+	}
+
+	// Files matched for compression keep the MD5 of the source, even if they are uploaded as is
+	// because compression doesn't make them smaller
+	if compressionEncoding(entry, info.Size(), inputs.Compression) != "" {
+		sourceMD5, err := fileMD5(f, entry.Path)
+		if err != nil {
+			return err
+		}
+
+		if storageObject.Metadata == nil {
+			storageObject.Metadata = make(map[string]string, 1)
+		}
 
-	// Set cache control header if specified
-	storageObject.CacheControl = GetCacheControlValue(cacheControl, key)
-	storageObject.ContentType = mime.TypeByExtension(filepath.Ext(key))
+		storageObject.Metadata[SourceMD5Metadata] = sourceMD5
+	}
 
 	// Upload object
 	err = storageService.PutObject(ctx, storageObject)
@@ -66,6 +97,47 @@ func uploadFile(
 	return nil
 }
 
+// ListFiles returns the files matched by the include patterns and not matched by the exclude patterns or
+// the .yc-ignore file in the root, together with the object keys they are uploaded to. Both are
+// gitignore-style patterns, the include ones anchored to the root.
+func ListFiles(f afero.Fs, inputs *ActionInputs) ([]FileEntry, error) {
+	// Get workspace directory
+	workspace := sourcecraft.GetSourcecraftWorkspace()
+
+	// Get source root
+	root := filepath.Join(workspace, inputs.Root)
+
+	include, err := fileset.NewAnchored(inputs.Include)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse include patterns: %w", err)
+	}
+
+	// Parse ignore patterns, after the ones from the ignore file in the root
+	ignore, err := fileset.Load(f, root, parseIgnoreGlobPatterns(inputs.Exclude), fileset.YCIgnoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ignore patterns: %w", err)
+	}
+
+	var entries []FileEntry
+
+	err = fileset.Walk(f, root, include, ignore, func(filePath, relPath string) error {
+		// Build object key
+		key := relPath
+		if inputs.Prefix != "" {
+			key = filepath.Join(inputs.Prefix, key)
+		}
+
+		entries = append(entries, FileEntry{Path: filePath, Key: key})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return entries, nil
+}
+
 // Upload uploads files to object storage.
 func Upload(
 	ctx context.Context,
@@ -78,150 +150,12 @@ func Upload(
 
 	sourcecraft.Info("Upload start")
 
-	// Get workspace directory
-	workspace := sourcecraft.GetSourcecraftWorkspace()
-
-	// Get source root
-	root := filepath.Join(workspace, inputs.Root)
-
-	// Parse ignore
-	ignore := parseIgnoreGlobPatterns(inputs.Exclude)
-
-	// Process include ignore
-	for _, include := range inputs.Include {
-		if include == "" {
-			continue
-		}
-
-		pathFromSourceRoot := filepath.Join(root, include)
-
-		matches, err := afero.Glob(f, pathFromSourceRoot)
-		if err != nil {
-			return fmt.Errorf("failed to glob pattern: %w", err)
-		}
-
-		for _, match := range matches {
-			info, err := f.Stat(match)
-			if err != nil {
-				return fmt.Errorf("failed to stat file: %w", err)
-			}
-
-			if info.IsDir() {
-				// Walk directory
-				err = afero.Walk(f, match, func(path string, info fs.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-
-					// Skip directories
-					if info.IsDir() {
-						return nil
-					}
-
-					// Check if file matches any ignore pattern
-					relPath, err := filepath.Rel(root, path)
-					if err != nil {
-						return fmt.Errorf("failed to get relative path: %w", err)
-					}
-
-					skip := false
-
-					for _, pattern := range ignore {
-						// Try to match the full path
-						matched, err := filepath.Match(pattern, relPath)
-						if err != nil {
-							return fmt.Errorf("failed to match pattern: %w", err)
-						}
-
-						if matched {
-							skip = true
-
-							break
-						}
-
-						// Try to match just the base name
-						baseName := filepath.Base(relPath)
-
-						matched, err = filepath.Match(pattern, baseName)
-						if err != nil {
-							return fmt.Errorf("failed to match pattern: %w", err)
-						}
-
-						if matched {
-							skip = true
-
-							break
-						}
-					}
-
-					if skip {
-						return nil
-					}
-
-					// Upload file
-					return uploadFile(
-						ctx,
-						f,
-						storageService,
-						path,
-						root,
-						inputs.Bucket,
-						inputs.Prefix,
-						inputs.CacheControl,
-					)
-				})
-				if err != nil {
-					return fmt.Errorf("failed to walk directory: %w", err)
-				}
-			} else {
-				// Check if file matches any ignore pattern
-				relPath, err := filepath.Rel(root, match)
-				if err != nil {
-					return fmt.Errorf("failed to get relative path: %w", err)
-				}
-
-				skip := false
-
-				for _, pattern := range ignore {
-					// Try to match the full path
-					matched, err := filepath.Match(pattern, relPath)
-					if err != nil {
-						return fmt.Errorf("failed to match pattern: %w", err)
-					}
-
-					if matched {
-						skip = true
-
-						break
-					}
-
-					// Try to match just the base name
-					baseName := filepath.Base(relPath)
-
-					matched, err = filepath.Match(pattern, baseName)
-					if err != nil {
-						return fmt.Errorf("failed to match pattern: %w", err)
-					}
-
-					if matched {
-						skip = true
-
-						break
-					}
-				}
-
-				if !skip {
-					// Upload file
-					err = uploadFile(ctx, f, storageService, match, root, inputs.Bucket, inputs.Prefix, inputs.CacheControl)
-					if err != nil {
-						return fmt.Errorf("failed to Upload file: %w", err)
-					}
-				}
-			}
-		}
+	entries, err := ListFiles(f, inputs)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return uploadAll(ctx, f, storageService, entries, inputs)
 }
 
 // Note: The getCacheControlValue function has been moved to cache-control.go
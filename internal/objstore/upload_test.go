@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/yc-actions/sourcecraft-actions/pkg/storage"
 	"github.com/yc-actions/sourcecraft-actions/pkg/storage/mocks"
 )
@@ -31,6 +32,36 @@ func setupTest(t *testing.T) afero.Fs {
 	return appFS
 }
 
+// parseCacheControl parses the cache control formats of a test.
+func parseCacheControl(t *testing.T, formats ...string) CacheControlConfig {
+	t.Helper()
+
+	config, err := ParseCacheControlFormats(formats)
+	require.NoError(t, err)
+
+	return config
+}
+
+// parseGlobValues parses the glob values of a test.
+func parseGlobValues(t *testing.T, formats ...string) GlobValues {
+	t.Helper()
+
+	config, err := ParseGlobValues(formats)
+	require.NoError(t, err)
+
+	return config
+}
+
+// parseMetadata parses the metadata rules of a test.
+func parseMetadata(t *testing.T, formats ...string) []MetadataRule {
+	t.Helper()
+
+	rules, err := ParseMetadataFormats(formats)
+	require.NoError(t, err)
+
+	return rules
+}
+
 // TestExist verifies that the test file system is set up correctly.
 func TestExist(t *testing.T) {
 	appFS := setupTest(t)
@@ -53,13 +84,12 @@ func TestUploadFile(t *testing.T) {
 		{
 			name: "Basic upload with exclude patterns",
 			inputs: ActionInputs{
-				CacheControl: CacheControlConfig{
-					Mapping: map[string]string{
-						"*.html": "public, max-age=3600",
-						"*.css":  "public, max-age=7200",
-					},
-					Default: "public, max-age=86400",
-				},
+				CacheControl: parseCacheControl(
+					t,
+					"*.html:public, max-age=3600",
+					"*.css:public, max-age=7200",
+					"*:public, max-age=86400",
+				),
 				Bucket:  "test-bucket",
 				Root:    "src",
 				Include: []string{"."},
@@ -73,13 +103,12 @@ func TestUploadFile(t *testing.T) {
 		{
 			name: "Upload with no exclude patterns",
 			inputs: ActionInputs{
-				CacheControl: CacheControlConfig{
-					Mapping: map[string]string{
-						"*.html": "public, max-age=3600",
-						"*.css":  "public, max-age=7200",
-					},
-					Default: "public, max-age=86400",
-				},
+				CacheControl: parseCacheControl(
+					t,
+					"*.html:public, max-age=3600",
+					"*.css:public, max-age=7200",
+					"*:public, max-age=86400",
+				),
 				Bucket:  "test-bucket",
 				Root:    "src",
 				Include: []string{"."},
@@ -93,13 +122,12 @@ func TestUploadFile(t *testing.T) {
 		{
 			name: "Upload with specific include pattern",
 			inputs: ActionInputs{
-				CacheControl: CacheControlConfig{
-					Mapping: map[string]string{
-						"*.html": "public, max-age=3600",
-						"*.css":  "public, max-age=7200",
-					},
-					Default: "public, max-age=86400",
-				},
+				CacheControl: parseCacheControl(
+					t,
+					"*.html:public, max-age=3600",
+					"*.css:public, max-age=7200",
+					"*:public, max-age=86400",
+				),
 				Bucket:  "test-bucket",
 				Root:    "src",
 				Include: []string{"*.html"},
@@ -113,13 +141,12 @@ func TestUploadFile(t *testing.T) {
 		{
 			name: "Upload with prefix",
 			inputs: ActionInputs{
-				CacheControl: CacheControlConfig{
-					Mapping: map[string]string{
-						"*.html": "public, max-age=3600",
-						"*.css":  "public, max-age=7200",
-					},
-					Default: "public, max-age=86400",
-				},
+				CacheControl: parseCacheControl(
+					t,
+					"*.html:public, max-age=3600",
+					"*.css:public, max-age=7200",
+					"*:public, max-age=86400",
+				),
 				Bucket:  "test-bucket",
 				Root:    "src",
 				Include: []string{"."},
@@ -165,3 +192,123 @@ func TestUploadFile(t *testing.T) {
 		})
 	}
 }
+
+// TestListFiles tests that ListFiles resolves local paths to object keys without uploading.
+func TestListFiles(t *testing.T) {
+	f := setupTest(t)
+
+	entries, err := ListFiles(f, &ActionInputs{
+		Root:    "src",
+		Include: []string{"."},
+		Exclude: []string{"*.css"},
+		Prefix:  "assets",
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []FileEntry{
+		{Path: "src/a/index.js", Key: "assets/a/index.js"},
+		{Path: "src/index.html", Key: "assets/index.html"},
+	}, entries)
+}
+
+// TestUploadHeaders tests that headers and metadata matched by glob are set on uploaded objects.
+func TestUploadHeaders(t *testing.T) {
+	mockStorage := mocks.NewMockStorageService(t)
+	f := setupTest(t)
+
+	mockStorage.EXPECT().
+		PutObject(mock.Anything, mock.MatchedBy(func(obj *storage.StorageObject) bool {
+			return obj.ObjectName == "index.html" &&
+				obj.ContentType == "text/html; charset=utf-8" &&
+				obj.CacheControl == "no-cache" &&
+				obj.ContentLanguage == "en" &&
+				obj.StorageClass == "" &&
+				obj.Metadata["commit"] == "abc"
+		})).
+		Return(nil).
+		Once()
+	mockStorage.EXPECT().
+		PutObject(mock.Anything, mock.MatchedBy(func(obj *storage.StorageObject) bool {
+			return obj.ObjectName == "main.css" &&
+				obj.ContentType == "text/css; charset=utf-8" &&
+				obj.StorageClass == "COLD" &&
+				obj.Expires != nil
+		})).
+		Return(nil).
+		Once()
+
+	err := Upload(context.Background(), f, mockStorage, &ActionInputs{
+		Bucket:       "test-bucket",
+		Root:         "src",
+		Include:      []string{"*.html", "*.css"},
+		CacheControl: parseCacheControl(t, "*.html:no-cache"),
+		Headers: ObjectHeaders{
+			ContentType:     parseGlobValues(t, "*.html:text/html; charset=utf-8"),
+			ContentLanguage: parseGlobValues(t, "*.html:en"),
+			StorageClass:    parseGlobValues(t, "*.css:COLD"),
+			Expires:         parseGlobValues(t, "*.css:Wed, 21 Oct 2037 07:28:00 GMT"),
+			Metadata:        parseMetadata(t, "*.html:commit=abc"),
+		},
+	})
+	assert.NoError(t, err)
+}
+
+// TestListFilesIgnore tests gitignore-style exclude patterns and the ignore file in the root.
+func TestListFilesIgnore(t *testing.T) {
+	f := setupTest(t)
+
+	err := afero.WriteFile(f, "src/.yc-ignore", []byte("a/\n.yc-ignore\n"), 0o644)
+	assert.NoError(t, err)
+	err = afero.WriteFile(f, "src/docs/guide/intro.md", []byte("# Intro"), 0o644)
+	assert.NoError(t, err)
+	err = afero.WriteFile(f, "src/docs/README.md", []byte("# Docs"), 0o644)
+	assert.NoError(t, err)
+
+	entries, err := ListFiles(f, &ActionInputs{
+		Root:    "src",
+		Include: []string{"."},
+		Exclude: []string{"docs/**/*.md", "!docs/README.md", "/*.css"},
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []FileEntry{
+		{Path: "src/docs/README.md", Key: "docs/README.md"},
+		{Path: "src/index.html", Key: "index.html"},
+	}, entries)
+}
+
+// TestListFilesIncludeDoubleStar tests gitignore-style include patterns anchored to the root.
+func TestListFilesIncludeDoubleStar(t *testing.T) {
+	f := setupTest(t)
+
+	err := afero.WriteFile(f, "src/a/b/page.html", []byte("<html></html>"), 0o644)
+	assert.NoError(t, err)
+	err = afero.WriteFile(f, "src/vendor/lib.js", []byte("lib"), 0o644)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		include  []string
+		expected []string
+	}{
+		{name: "any depth", include: []string{"**/*.html"}, expected: []string{"a/b/page.html", "index.html"}},
+		{name: "root only", include: []string{"*.html"}, expected: []string{"index.html"}},
+		{name: "under directory", include: []string{"a/**/*.js"}, expected: []string{"a/index.js"}},
+		{name: "directory", include: []string{"vendor"}, expected: []string{"vendor/lib.js"}},
+		{name: "overlapping", include: []string{".", "*.html"}, expected: []string{
+			"a/b/page.html", "a/index.js", "index.html", "main.css", "vendor/lib.js",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := ListFiles(f, &ActionInputs{Root: "src", Include: tc.include})
+			assert.NoError(t, err)
+
+			keys := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				keys = append(keys, entry.Key)
+			}
+
+			assert.ElementsMatch(t, tc.expected, keys)
+		})
+	}
+}
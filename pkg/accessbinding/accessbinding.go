@@ -0,0 +1,259 @@
+// Package accessbinding reconciles the IAM access bindings of a resource with a declared list.
+package accessbinding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/access"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/operation"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+	"google.golang.org/grpc"
+)
+
+// inputAccessBindings is the access bindings input shared by the function, container and API gateway actions.
+const inputAccessBindings = "ACCESS_BINDINGS"
+
+// Public is the shorthand for the binding that lets anyone invoke the resource.
+const Public = "PUBLIC"
+
+// Invoker roles of the public shorthand.
+const (
+	FunctionInvokerRole  = "functions.functionInvoker"
+	ContainerInvokerRole = "serverless.containers.invoker"
+)
+
+// Client is the part of a resource service that manages access bindings.
+type Client interface {
+	ListAccessBindings(
+		ctx context.Context,
+		in *access.ListAccessBindingsRequest,
+		opts ...grpc.CallOption,
+	) (*access.ListAccessBindingsResponse, error)
+	UpdateAccessBindings(
+		ctx context.Context,
+		in *access.UpdateAccessBindingsRequest,
+		opts ...grpc.CallOption,
+	) (*operation.Operation, error)
+}
+
+// PublicBinding returns the binding of the role to all users.
+func PublicBinding(role string) *access.AccessBinding {
+	return &access.AccessBinding{
+		RoleId: role,
+		Subject: &access.Subject{
+			Id:   "allUsers",
+			Type: "system",
+		},
+	}
+}
+
+// ParseBinding parses a binding in the format "ROLE:SUBJECT_TYPE:SUBJECT_ID", or the PUBLIC shorthand
+// for the public role. An empty public role means the resource doesn't support the shorthand.
+func ParseBinding(input, publicRole string) (*access.AccessBinding, error) {
+	input = strings.TrimSpace(input)
+
+	if strings.EqualFold(input, Public) {
+		if publicRole == "" {
+			return nil, fmt.Errorf("%s is not supported for this resource", Public)
+		}
+
+		return PublicBinding(publicRole), nil
+	}
+
+	// The subject ID may contain colons, e.g. group:organization:<id>:users
+	parts := strings.SplitN(input, ":", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("access binding has wrong format (should be role:subjectType:subjectID): %s", input)
+	}
+
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+		if parts[i] == "" {
+			return nil, fmt.Errorf("access binding has empty parts: %s", input)
+		}
+	}
+
+	return &access.AccessBinding{
+		RoleId: parts[0],
+		Subject: &access.Subject{
+			Type: parts[1],
+			Id:   parts[2],
+		},
+	}, nil
+}
+
+// ParseBindings parses multiple bindings, skipping empty lines and duplicates.
+func ParseBindings(inputs []string, publicRole string) ([]*access.AccessBinding, error) {
+	var bindings []*access.AccessBinding
+
+	seen := make(map[string]bool)
+
+	for _, input := range inputs {
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+
+		binding, err := ParseBinding(input, publicRole)
+		if err != nil {
+			return nil, err
+		}
+
+		if k := key(binding); !seen[k] {
+			seen[k] = true
+			bindings = append(bindings, binding)
+		}
+	}
+
+	return bindings, nil
+}
+
+// Declared reports whether the access bindings input is set. Declared bindings are authoritative:
+// the bindings of the resource that are not declared are removed.
+func Declared() bool {
+	return len(sourcecraft.GetMultilineInput(inputAccessBindings)) > 0
+}
+
+// InputBindings parses the access bindings input.
+func InputBindings(publicRole string) ([]*access.AccessBinding, error) {
+	return ParseBindings(sourcecraft.GetMultilineInput(inputAccessBindings), publicRole)
+}
+
+// key identifies a binding by its role and subject.
+func key(binding *access.AccessBinding) string {
+	return binding.GetRoleId() + ":" + binding.GetSubject().GetType() + ":" + binding.GetSubject().GetId()
+}
+
+// Deltas returns the deltas adding the declared bindings the resource doesn't have and,
+// with prune, removing the existing bindings that are not declared.
+func Deltas(existing, declared []*access.AccessBinding, prune bool) []*access.AccessBindingDelta {
+	var deltas []*access.AccessBindingDelta
+
+	existingKeys := make(map[string]bool, len(existing))
+	for _, binding := range existing {
+		existingKeys[key(binding)] = true
+	}
+
+	declaredKeys := make(map[string]bool, len(declared))
+
+	for _, binding := range declared {
+		k := key(binding)
+		if !existingKeys[k] && !declaredKeys[k] {
+			deltas = append(deltas, &access.AccessBindingDelta{
+				Action:        access.AccessBindingAction_ADD,
+				AccessBinding: binding,
+			})
+		}
+
+		declaredKeys[k] = true
+	}
+
+	if prune {
+		for _, binding := range existing {
+			if !declaredKeys[key(binding)] {
+				deltas = append(deltas, &access.AccessBindingDelta{
+					Action:        access.AccessBindingAction_REMOVE,
+					AccessBinding: binding,
+				})
+			}
+		}
+	}
+
+	return deltas
+}
+
+// ListBindings returns all access bindings of the resource.
+func ListBindings(ctx context.Context, client Client, resourceID string) ([]*access.AccessBinding, error) {
+	var (
+		bindings  []*access.AccessBinding
+		pageToken string
+	)
+
+	for {
+		resp, err := client.ListAccessBindings(ctx, &access.ListAccessBindingsRequest{
+			ResourceId: resourceID,
+			PageToken:  pageToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list access bindings: %w", err)
+		}
+
+		bindings = append(bindings, resp.AccessBindings...)
+
+		pageToken = resp.NextPageToken
+		if pageToken == "" {
+			return bindings, nil
+		}
+	}
+}
+
+// NewUpdateRequest builds the request reconciling the access bindings of the resource with the declared ones,
+// or returns nil if they are up to date. A resource that doesn't exist yet has an empty ID and no bindings.
+func NewUpdateRequest(
+	ctx context.Context,
+	client Client,
+	resourceID string,
+	declared []*access.AccessBinding,
+	prune bool,
+) (*access.UpdateAccessBindingsRequest, error) {
+	var existing []*access.AccessBinding
+
+	if resourceID != "" {
+		var err error
+
+		existing, err = ListBindings(ctx, client, resourceID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deltas := Deltas(existing, declared, prune)
+	if len(deltas) == 0 {
+		return nil, nil
+	}
+
+	return &access.UpdateAccessBindingsRequest{
+		ResourceId:          resourceID,
+		AccessBindingDeltas: deltas,
+	}, nil
+}
+
+// Reconcile adds the declared access bindings the resource doesn't have and, with prune,
+// removes the ones that are not declared.
+func Reconcile(
+	ctx context.Context,
+	sdk *ycsdk.SDK,
+	client Client,
+	resourceID string,
+	declared []*access.AccessBinding,
+	prune bool,
+) error {
+	request, err := NewUpdateRequest(ctx, client, resourceID, declared, prune)
+	if err != nil {
+		return err
+	}
+
+	if request == nil {
+		sourcecraft.Info("Access bindings are up to date")
+
+		return nil
+	}
+
+	for _, delta := range request.AccessBindingDeltas {
+		sourcecraft.Info(fmt.Sprintf("%s access binding %s", delta.Action, key(delta.AccessBinding)))
+	}
+
+	op, err := sdk.WrapOperation(client.UpdateAccessBindings(ctx, request))
+	if err != nil {
+		return fmt.Errorf("failed to update access bindings: %w", err)
+	}
+
+	err = op.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for operation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package accessbinding_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/access"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/operation"
+	"github.com/yc-actions/sourcecraft-actions/pkg/accessbinding"
+	"google.golang.org/grpc"
+)
+
+// fakeClient serves the access bindings in pages of one binding.
+type fakeClient struct {
+	bindings []*access.AccessBinding
+	listed   []string
+}
+
+func (c *fakeClient) ListAccessBindings(
+	_ context.Context,
+	in *access.ListAccessBindingsRequest,
+	_ ...grpc.CallOption,
+) (*access.ListAccessBindingsResponse, error) {
+	c.listed = append(c.listed, in.ResourceId)
+
+	index, _ := strconv.Atoi(in.PageToken)
+
+	resp := &access.ListAccessBindingsResponse{}
+	if index < len(c.bindings) {
+		resp.AccessBindings = c.bindings[index : index+1]
+	}
+
+	if index+1 < len(c.bindings) {
+		resp.NextPageToken = strconv.Itoa(index + 1)
+	}
+
+	return resp, nil
+}
+
+func (c *fakeClient) UpdateAccessBindings(
+	context.Context,
+	*access.UpdateAccessBindingsRequest,
+	...grpc.CallOption,
+) (*operation.Operation, error) {
+	return &operation.Operation{Done: true}, nil
+}
+
+func binding(role, subjectType, subjectID string) *access.AccessBinding {
+	return &access.AccessBinding{RoleId: role, Subject: &access.Subject{Type: subjectType, Id: subjectID}}
+}
+
+func TestParseBindings(t *testing.T) {
+	bindings, err := accessbinding.ParseBindings([]string{
+		"functions.functionInvoker:serviceAccount:sa-id",
+		"",
+		"public",
+		"viewer:system:group:organization:org-id:users",
+		"functions.functionInvoker:serviceAccount:sa-id",
+	}, accessbinding.FunctionInvokerRole)
+	require.NoError(t, err)
+
+	require.Len(t, bindings, 3)
+	assert.Equal(t, "functions.functionInvoker", bindings[0].RoleId)
+	assert.Equal(t, "serviceAccount", bindings[0].Subject.Type)
+	assert.Equal(t, "sa-id", bindings[0].Subject.Id)
+	assert.Equal(t, "allUsers", bindings[1].Subject.Id)
+	assert.Equal(t, "system", bindings[1].Subject.Type)
+	assert.Equal(t, "group:organization:org-id:users", bindings[2].Subject.Id)
+
+	_, err = accessbinding.ParseBindings([]string{"viewer:userAccount"}, "")
+	assert.ErrorContains(t, err, "wrong format")
+
+	_, err = accessbinding.ParseBindings([]string{"viewer::user-id"}, "")
+	assert.ErrorContains(t, err, "empty parts")
+
+	_, err = accessbinding.ParseBindings([]string{accessbinding.Public}, "")
+	assert.ErrorContains(t, err, "not supported")
+}
+
+func TestDeltas(t *testing.T) {
+	existing := []*access.AccessBinding{
+		binding("viewer", "userAccount", "user-1"),
+		binding("editor", "userAccount", "user-2"),
+	}
+	declared := []*access.AccessBinding{
+		binding("viewer", "userAccount", "user-1"),
+		binding("viewer", "serviceAccount", "sa-id"),
+	}
+
+	deltas := accessbinding.Deltas(existing, declared, true)
+	require.Len(t, deltas, 2)
+	assert.Equal(t, access.AccessBindingAction_ADD, deltas[0].Action)
+	assert.Equal(t, "sa-id", deltas[0].AccessBinding.Subject.Id)
+	assert.Equal(t, access.AccessBindingAction_REMOVE, deltas[1].Action)
+	assert.Equal(t, "user-2", deltas[1].AccessBinding.Subject.Id)
+
+	deltas = accessbinding.Deltas(existing, declared, false)
+	require.Len(t, deltas, 1)
+	assert.Equal(t, access.AccessBindingAction_ADD, deltas[0].Action)
+
+	assert.Empty(t, accessbinding.Deltas(existing, existing, true))
+}
+
+func TestNewUpdateRequest(t *testing.T) {
+	client := &fakeClient{bindings: []*access.AccessBinding{
+		binding("viewer", "userAccount", "user-1"),
+		accessbinding.PublicBinding(accessbinding.ContainerInvokerRole),
+	}}
+
+	request, err := accessbinding.NewUpdateRequest(
+		context.Background(),
+		client,
+		"container-id",
+		[]*access.AccessBinding{accessbinding.PublicBinding(accessbinding.ContainerInvokerRole)},
+		false,
+	)
+	require.NoError(t, err)
+	assert.Nil(t, request)
+	assert.Equal(t, []string{"container-id", "container-id"}, client.listed)
+
+	request, err = accessbinding.NewUpdateRequest(context.Background(), client, "container-id", nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, "container-id", request.ResourceId)
+	assert.Len(t, request.AccessBindingDeltas, 2)
+
+	// A resource that doesn't exist yet isn't listed
+	client.listed = nil
+	request, err = accessbinding.NewUpdateRequest(
+		context.Background(),
+		client,
+		"",
+		[]*access.AccessBinding{binding("viewer", "userAccount", "user-1")},
+		true,
+	)
+	require.NoError(t, err)
+	assert.Empty(t, client.listed)
+	assert.Len(t, request.AccessBindingDeltas, 1)
+}
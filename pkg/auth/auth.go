@@ -0,0 +1,125 @@
+// Package auth builds Yandex Cloud SDK instances from the credential inputs
+// shared by all actions.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// Credential inputs shared by all actions.
+const (
+	inputYcSaJsonCredentials = "YC_SA_JSON_CREDENTIALS"
+	inputYcSaKeyFile         = "YC_SA_KEY_FILE"
+	inputYcIamToken          = "YC_IAM_TOKEN"
+	inputYcOAuthToken        = "YC_OAUTH_TOKEN"
+	inputYcSaID              = "YC_SA_ID"
+	inputYcIDToken           = "YC_ID_TOKEN"
+	inputYcTokenExchangeURL  = "YC_TOKEN_EXCHANGE_URL"
+	inputYcMetadata          = "YC_METADATA_CREDENTIALS"
+)
+
+// ErrNoCredentials is returned when none of the credential sources is configured.
+var ErrNoCredentials = errors.New("no credentials provided")
+
+// Source is a single way of obtaining Yandex Cloud API credentials.
+type Source interface {
+	// Name returns the input the source is configured with. It is used in logs and errors.
+	Name() string
+	// Configured reports whether the inputs required by the source are set.
+	Configured() bool
+	// Credentials builds SDK credentials from the source.
+	Credentials(ctx context.Context) (ycsdk.Credentials, error)
+}
+
+// DefaultSources returns the credential sources read from the action inputs,
+// in precedence order.
+func DefaultSources() []Source {
+	return []Source{
+		&ServiceAccountKeyJSON{JSON: sourcecraft.GetInput(inputYcSaJsonCredentials)},
+		&ServiceAccountKeyFile{Path: sourcecraft.GetInput(inputYcSaKeyFile)},
+		&IAMToken{Token: sourcecraft.GetInput(inputYcIamToken)},
+		&OAuthToken{Token: sourcecraft.GetInput(inputYcOAuthToken)},
+		&WorkloadIdentity{
+			ServiceAccountID: sourcecraft.GetInput(inputYcSaID),
+			IDTokenSource:    idToken,
+			Endpoint:         sourcecraft.GetInput(inputYcTokenExchangeURL),
+		},
+		&InstanceMetadata{Enabled: sourcecraft.GetBooleanInput(inputYcMetadata)},
+	}
+}
+
+// idToken returns the OIDC ID token passed explicitly or issued to the pipeline.
+func idToken() string {
+	if token := sourcecraft.GetInput(inputYcIDToken); token != "" {
+		return token
+	}
+
+	return sourcecraft.GetSourcecraftIDToken()
+}
+
+// Resolve returns credentials from the first configured source.
+// Configured sources with lower precedence are reported and ignored.
+func Resolve(ctx context.Context, sources []Source) (ycsdk.Credentials, error) {
+	var selected Source
+
+	names := make([]string, 0, len(sources))
+
+	for _, source := range sources {
+		names = append(names, source.Name())
+
+		if !source.Configured() {
+			continue
+		}
+
+		if selected != nil {
+			sourcecraft.Info(
+				fmt.Sprintf("Ignoring %s: %s takes precedence", source.Name(), selected.Name()),
+			)
+
+			continue
+		}
+
+		selected = source
+	}
+
+	if selected == nil {
+		return nil, fmt.Errorf("%w: set one of %s", ErrNoCredentials, strings.Join(names, ", "))
+	}
+
+	credentials, err := selected.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credentials from %s: %w", selected.Name(), err)
+	}
+
+	sourcecraft.Info(fmt.Sprintf("Using credentials from %s", selected.Name()))
+
+	return credentials, nil
+}
+
+// NewSDK builds an SDK authenticated with the first configured source.
+// If no sources are given, DefaultSources is used.
+func NewSDK(ctx context.Context, sources ...Source) (*ycsdk.SDK, error) {
+	if len(sources) == 0 {
+		sources = DefaultSources()
+	}
+
+	credentials, err := Resolve(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	sdk, err := ycsdk.Build(ctx, ycsdk.Config{
+		Credentials: credentials,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SDK: %w", err)
+	}
+
+	return sdk, nil
+}
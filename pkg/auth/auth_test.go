@@ -0,0 +1,141 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yandex-cloud/go-sdk/iamkey"
+	"github.com/yc-actions/sourcecraft-actions/pkg/auth"
+)
+
+// testKeyJSON returns a service account authorized key with a freshly generated private key.
+func testKeyJSON(t *testing.T) string {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+
+	key := &iamkey.Key{
+		Id:         "key-id",
+		Subject:    &iamkey.Key_ServiceAccountId{ServiceAccountId: "sa-id"},
+		PrivateKey: string(privateKeyPEM),
+	}
+
+	data, err := json.Marshal(key)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no sources configured", func(t *testing.T) {
+		_, err := auth.Resolve(ctx, []auth.Source{
+			&auth.ServiceAccountKeyJSON{},
+			&auth.IAMToken{},
+		})
+
+		assert.ErrorIs(t, err, auth.ErrNoCredentials)
+		assert.ErrorContains(t, err, "YC_SA_JSON_CREDENTIALS, YC_IAM_TOKEN")
+	})
+
+	t.Run("IAM token", func(t *testing.T) {
+		credentials, err := auth.Resolve(ctx, []auth.Source{
+			&auth.ServiceAccountKeyJSON{},
+			&auth.IAMToken{Token: "t1.token"},
+		})
+		require.NoError(t, err)
+
+		nonExchangeable, ok := credentials.(ycsdk.NonExchangeableCredentials)
+		require.True(t, ok)
+
+		token, err := nonExchangeable.IAMToken(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "t1.token", token.IamToken)
+	})
+
+	t.Run("service account key takes precedence over IAM token", func(t *testing.T) {
+		credentials, err := auth.Resolve(ctx, []auth.Source{
+			&auth.ServiceAccountKeyJSON{JSON: testKeyJSON(t)},
+			&auth.IAMToken{Token: "t1.token"},
+		})
+		require.NoError(t, err)
+
+		_, ok := credentials.(ycsdk.ExchangeableCredentials)
+		assert.True(t, ok)
+	})
+
+	t.Run("invalid service account key", func(t *testing.T) {
+		_, err := auth.Resolve(ctx, []auth.Source{
+			&auth.ServiceAccountKeyJSON{JSON: "{not json"},
+			&auth.IAMToken{Token: "t1.token"},
+		})
+
+		assert.ErrorContains(t, err, "YC_SA_JSON_CREDENTIALS")
+	})
+
+	t.Run("service account key file relative to workspace", func(t *testing.T) {
+		workspace := t.TempDir()
+		t.Setenv("SOURCECRAFT_WORKSPACE", workspace)
+
+		err := os.WriteFile(filepath.Join(workspace, "key.json"), []byte(testKeyJSON(t)), 0o600)
+		require.NoError(t, err)
+
+		credentials, err := auth.Resolve(ctx, []auth.Source{
+			&auth.ServiceAccountKeyFile{Path: "key.json"},
+		})
+		require.NoError(t, err)
+
+		_, ok := credentials.(ycsdk.ExchangeableCredentials)
+		assert.True(t, ok)
+	})
+
+	t.Run("missing service account key file", func(t *testing.T) {
+		_, err := auth.Resolve(ctx, []auth.Source{
+			&auth.ServiceAccountKeyFile{Path: filepath.Join(t.TempDir(), "missing.json")},
+		})
+
+		assert.ErrorContains(t, err, "YC_SA_KEY_FILE")
+	})
+}
+
+func TestDefaultSources(t *testing.T) {
+	t.Setenv("YC_SA_JSON_CREDENTIALS", "")
+	t.Setenv("YC_SA_KEY_FILE", "")
+	t.Setenv("YC_IAM_TOKEN", "")
+	t.Setenv("YC_OAUTH_TOKEN", "oauth-token")
+	t.Setenv("YC_SA_ID", "")
+	t.Setenv("YC_METADATA_CREDENTIALS", "true")
+
+	var configured []string
+
+	for _, source := range auth.DefaultSources() {
+		if source.Configured() {
+			configured = append(configured, source.Name())
+		}
+	}
+
+	assert.Equal(t, []string{"YC_OAUTH_TOKEN", "YC_METADATA_CREDENTIALS"}, configured)
+}
+
+func TestNewSDK(t *testing.T) {
+	sdk, err := auth.NewSDK(context.Background(), &auth.IAMToken{Token: "t1.token"})
+	require.NoError(t, err)
+	assert.NotNil(t, sdk)
+}
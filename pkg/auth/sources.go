@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yandex-cloud/go-sdk/iamkey"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// ServiceAccountKeyJSON authenticates with an authorized key passed as JSON.
+type ServiceAccountKeyJSON struct {
+	JSON string
+}
+
+// Name returns the input the source is configured with.
+func (s *ServiceAccountKeyJSON) Name() string {
+	return inputYcSaJsonCredentials
+}
+
+// Configured reports whether the key JSON is set.
+func (s *ServiceAccountKeyJSON) Configured() bool {
+	return s.JSON != ""
+}
+
+// Credentials parses the key and returns credentials signing JWTs with it.
+func (s *ServiceAccountKeyJSON) Credentials(_ context.Context) (ycsdk.Credentials, error) {
+	key, err := iamkey.ReadFromJSONBytes([]byte(s.JSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account JSON: %w", err)
+	}
+
+	return ycsdk.ServiceAccountKey(key)
+}
+
+// ServiceAccountKeyFile authenticates with an authorized key stored in a file.
+// Relative paths are resolved against the Sourcecraft workspace.
+type ServiceAccountKeyFile struct {
+	Path string
+}
+
+// Name returns the input the source is configured with.
+func (s *ServiceAccountKeyFile) Name() string {
+	return inputYcSaKeyFile
+}
+
+// Configured reports whether the key file path is set.
+func (s *ServiceAccountKeyFile) Configured() bool {
+	return s.Path != ""
+}
+
+// Credentials reads the key file and returns credentials signing JWTs with it.
+func (s *ServiceAccountKeyFile) Credentials(_ context.Context) (ycsdk.Credentials, error) {
+	path := s.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(sourcecraft.GetSourcecraftWorkspace(), path)
+	}
+
+	key, err := iamkey.ReadFromJSONFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account key file %s: %w", path, err)
+	}
+
+	return ycsdk.ServiceAccountKey(key)
+}
+
+// IAMToken authenticates with a static IAM token.
+type IAMToken struct {
+	Token string
+}
+
+// Name returns the input the source is configured with.
+func (s *IAMToken) Name() string {
+	return inputYcIamToken
+}
+
+// Configured reports whether the token is set.
+func (s *IAMToken) Configured() bool {
+	return s.Token != ""
+}
+
+// Credentials returns credentials using the token as is.
+func (s *IAMToken) Credentials(_ context.Context) (ycsdk.Credentials, error) {
+	return ycsdk.NewIAMTokenCredentials(s.Token), nil
+}
+
+// OAuthToken authenticates with a Yandex Passport OAuth token.
+type OAuthToken struct {
+	Token string
+}
+
+// Name returns the input the source is configured with.
+func (s *OAuthToken) Name() string {
+	return inputYcOAuthToken
+}
+
+// Configured reports whether the token is set.
+func (s *OAuthToken) Configured() bool {
+	return s.Token != ""
+}
+
+// Credentials returns credentials exchanging the OAuth token for IAM tokens.
+func (s *OAuthToken) Credentials(_ context.Context) (ycsdk.Credentials, error) {
+	return ycsdk.OAuthToken(s.Token), nil
+}
+
+// WorkloadIdentity authenticates as a service account through workload identity federation:
+// the OIDC ID token issued to the pipeline is exchanged for a short-lived IAM token.
+type WorkloadIdentity struct {
+	ServiceAccountID string
+	IDToken          string
+	// IDTokenSource returns the current ID token, so that a refreshed one is used when the IAM token
+	// is exchanged again. IDToken is used if nil.
+	IDTokenSource func() string
+	// Endpoint is the token exchange URL. DefaultTokenExchangeURL is used if empty.
+	Endpoint string
+	// HTTPClient is used for token exchange requests. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+}
+
+// Name returns the input the source is configured with.
+func (s *WorkloadIdentity) Name() string {
+	return inputYcSaID
+}
+
+// Configured reports whether the service account ID is set.
+func (s *WorkloadIdentity) Configured() bool {
+	return s.ServiceAccountID != ""
+}
+
+// Credentials exchanges the ID token and returns credentials serving the resulting IAM token.
+func (s *WorkloadIdentity) Credentials(ctx context.Context) (ycsdk.Credentials, error) {
+	if s.idToken() == "" {
+		return nil, fmt.Errorf(
+			"no OIDC ID token: set %s or run inside a pipeline that provides %s",
+			inputYcIDToken,
+			sourcecraft.EnvSourcecraftIDToken,
+		)
+	}
+
+	token, err := s.exchange(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &exchangedTokenCredentials{source: s, token: token}, nil
+}
+
+// idToken returns the current ID token.
+func (s *WorkloadIdentity) idToken() string {
+	if s.IDTokenSource != nil {
+		return s.IDTokenSource()
+	}
+
+	return s.IDToken
+}
+
+// exchange exchanges the current ID token for an IAM token of the service account.
+// An expired ID token is reported without a request, since it can't be exchanged.
+func (s *WorkloadIdentity) exchange(ctx context.Context) (*iam.CreateIamTokenResponse, error) {
+	idToken := s.idToken()
+
+	expiresAt, ok := idTokenExpiry(idToken)
+	if ok && !time.Now().Before(expiresAt) {
+		return nil, fmt.Errorf(
+			"OIDC ID token expired at %s and can't be exchanged for an IAM token: "+
+				"provide a fresh token in %s or use another credential source for long-running jobs",
+			expiresAt.Format(time.RFC3339),
+			inputYcIDToken,
+		)
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultTokenExchangeURL
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return ExchangeToken(ctx, client, endpoint, s.ServiceAccountID, idToken)
+}
+
+// InstanceMetadata authenticates as the service account attached to the compute instance
+// the action runs on.
+type InstanceMetadata struct {
+	Enabled bool
+}
+
+// Name returns the input the source is configured with.
+func (s *InstanceMetadata) Name() string {
+	return inputYcMetadata
+}
+
+// Configured reports whether the metadata service should be used.
+func (s *InstanceMetadata) Configured() bool {
+	return s.Enabled
+}
+
+// Credentials returns credentials fetching IAM tokens from the metadata service.
+func (s *InstanceMetadata) Credentials(_ context.Context) (ycsdk.Credentials, error) {
+	return ycsdk.InstanceServiceAccount(), nil
+}
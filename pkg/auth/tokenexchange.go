@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultTokenExchangeURL is the IAM endpoint exchanging federated tokens for IAM tokens.
+const DefaultTokenExchangeURL = "https://auth.yandex.cloud/oauth/token"
+
+// RFC 8693 token exchange parameters.
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	tokenTypeIDToken       = "urn:ietf:params:oauth:token-type:id_token"
+)
+
+// tokenRefreshSkew is how long before its expiration the exchanged IAM token is refreshed, so that
+// a token handed out to a request doesn't expire while the request is in flight.
+const tokenRefreshSkew = 5 * time.Minute
+
+// tokenExchangeResponse is the body returned by the token exchange endpoint.
+type tokenExchangeResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// ExchangeToken exchanges an OIDC ID token for a short-lived IAM token of the service account.
+func ExchangeToken(
+	ctx context.Context,
+	client *http.Client,
+	endpoint, serviceAccountID, idToken string,
+) (*iam.CreateIamTokenResponse, error) {
+	form := url.Values{
+		"grant_type":           {grantTypeTokenExchange},
+		"requested_token_type": {tokenTypeAccessToken},
+		"audience":             {serviceAccountID},
+		"subject_token":        {idToken},
+		"subject_token_type":   {tokenTypeIDToken},
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		endpoint,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token exchange request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token exchange response: %w", err)
+	}
+
+	var result tokenExchangeResponse
+
+	err = json.Unmarshal(body, &result)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse token exchange response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if result.Error != "" {
+			return nil, fmt.Errorf(
+				"token exchange failed with %s: %s: %s",
+				resp.Status,
+				result.Error,
+				result.ErrorDescription,
+			)
+		}
+
+		return nil, fmt.Errorf("token exchange failed with %s: %s", resp.Status, body)
+	}
+
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("token exchange response has no access token")
+	}
+
+	return &iam.CreateIamTokenResponse{
+		IamToken:  result.AccessToken,
+		ExpiresAt: timestamppb.New(time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)),
+	}, nil
+}
+
+// idTokenExpiry returns the expiration time of the JWT ID token, if it can be read. The token isn't verified,
+// this is only used to report an expired token clearly.
+func idTokenExpiry(idToken string) (time.Time, bool) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+
+	if json.Unmarshal(payload, &claims) != nil || claims.ExpiresAt == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.ExpiresAt, 0), true
+}
+
+// exchangedTokenCredentials serves the exchanged IAM token and exchanges the current ID token
+// again shortly before it expires.
+type exchangedTokenCredentials struct {
+	source *WorkloadIdentity
+	mutex  sync.Mutex
+	token  *iam.CreateIamTokenResponse
+}
+
+// YandexCloudAPICredentials marks the type as SDK credentials.
+func (c *exchangedTokenCredentials) YandexCloudAPICredentials() {}
+
+// IAMToken returns the cached IAM token, refreshing it when it is about to expire. If the refresh fails,
+// the cached token is used until it expires.
+func (c *exchangedTokenCredentials) IAMToken(ctx context.Context) (*iam.CreateIamTokenResponse, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+
+	if c.token != nil && now.Add(tokenRefreshSkew).Before(c.token.GetExpiresAt().AsTime()) {
+		return c.token, nil
+	}
+
+	token, err := c.source.exchange(ctx)
+	if err != nil {
+		if c.token != nil && now.Before(c.token.GetExpiresAt().AsTime()) {
+			return c.token, nil
+		}
+
+		return nil, fmt.Errorf("failed to refresh IAM token: %w", err)
+	}
+
+	c.token = token
+
+	return token, nil
+}
@@ -0,0 +1,219 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/pkg/auth"
+)
+
+// newTokenExchangeServer starts a stand-in for the IAM token exchange endpoint
+// that issues iamToken for the given service account and ID token.
+func newTokenExchangeServer(t *testing.T, serviceAccountID, idToken, iamToken string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:token-exchange", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "urn:ietf:params:oauth:token-type:id_token", r.PostForm.Get("subject_token_type"))
+		assert.Equal(t, "urn:ietf:params:oauth:token-type:access_token", r.PostForm.Get("requested_token_type"))
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.PostForm.Get("audience") != serviceAccountID || r.PostForm.Get("subject_token") != idToken {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"subject token is not trusted"}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"access_token":"` + iamToken + `","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestExchangeToken(t *testing.T) {
+	ctx := context.Background()
+	server := newTokenExchangeServer(t, "sa-id", "id-token", "t1.exchanged")
+
+	t.Run("success", func(t *testing.T) {
+		token, err := auth.ExchangeToken(ctx, server.Client(), server.URL, "sa-id", "id-token")
+		require.NoError(t, err)
+		assert.Equal(t, "t1.exchanged", token.IamToken)
+		assert.True(t, token.ExpiresAt.IsValid())
+	})
+
+	t.Run("rejected token", func(t *testing.T) {
+		_, err := auth.ExchangeToken(ctx, server.Client(), server.URL, "sa-id", "other-token")
+		assert.ErrorContains(t, err, "invalid_grant: subject token is not trusted")
+	})
+}
+
+func TestWorkloadIdentity(t *testing.T) {
+	ctx := context.Background()
+	server := newTokenExchangeServer(t, "sa-id", "id-token", "t1.exchanged")
+
+	t.Run("exchanges ID token", func(t *testing.T) {
+		credentials, err := auth.Resolve(ctx, []auth.Source{
+			&auth.ServiceAccountKeyJSON{},
+			&auth.WorkloadIdentity{
+				ServiceAccountID: "sa-id",
+				IDToken:          "id-token",
+				Endpoint:         server.URL,
+				HTTPClient:       server.Client(),
+			},
+		})
+		require.NoError(t, err)
+
+		nonExchangeable, ok := credentials.(ycsdk.NonExchangeableCredentials)
+		require.True(t, ok)
+
+		token, err := nonExchangeable.IAMToken(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "t1.exchanged", token.IamToken)
+	})
+
+	t.Run("missing ID token", func(t *testing.T) {
+		_, err := auth.Resolve(ctx, []auth.Source{
+			&auth.WorkloadIdentity{ServiceAccountID: "sa-id", Endpoint: server.URL},
+		})
+		assert.ErrorContains(t, err, "no OIDC ID token")
+	})
+
+	t.Run("ID token from pipeline", func(t *testing.T) {
+		t.Setenv("YC_SA_JSON_CREDENTIALS", "")
+		t.Setenv("YC_SA_KEY_FILE", "")
+		t.Setenv("YC_IAM_TOKEN", "")
+		t.Setenv("YC_OAUTH_TOKEN", "")
+		t.Setenv("YC_SA_ID", "sa-id")
+		t.Setenv("YC_ID_TOKEN", "")
+		t.Setenv("SOURCECRAFT_OIDC_TOKEN", "id-token")
+		t.Setenv("YC_TOKEN_EXCHANGE_URL", server.URL)
+
+		_, err := auth.Resolve(ctx, auth.DefaultSources())
+		assert.NoError(t, err)
+	})
+}
+
+// newRecordingExchangeServer starts a token exchange endpoint that accepts any ID token, issues IAM tokens
+// living for expiresIn seconds and records the exchanged ID tokens.
+func newRecordingExchangeServer(t *testing.T, expiresIn int) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var (
+		mutex    sync.Mutex
+		subjects []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+
+		mutex.Lock()
+		subjects = append(subjects, r.PostForm.Get("subject_token"))
+		count := len(subjects)
+		mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"t1.token-%d","expires_in":%d}`, count, expiresIn)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return append([]string(nil), subjects...)
+	}
+}
+
+// newIDToken returns an unsigned JWT expiring at the time.
+func newIDToken(expiresAt time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	return encode([]byte(`{"alg":"none"}`)) + "." +
+		encode(fmt.Appendf(nil, `{"exp":%d}`, expiresAt.Unix())) + ".signature"
+}
+
+func TestWorkloadIdentityRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("refreshes ahead of expiry with current ID token", func(t *testing.T) {
+		// A token living for a minute is within the refresh skew, so it's refreshed on every use
+		server, subjects := newRecordingExchangeServer(t, 60)
+
+		idToken := "id-token-1"
+		credentials, err := (&auth.WorkloadIdentity{
+			ServiceAccountID: "sa-id",
+			IDTokenSource:    func() string { return idToken },
+			Endpoint:         server.URL,
+			HTTPClient:       server.Client(),
+		}).Credentials(ctx)
+		require.NoError(t, err)
+
+		idToken = "id-token-2"
+
+		token, err := credentials.(ycsdk.NonExchangeableCredentials).IAMToken(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "t1.token-2", token.IamToken)
+		assert.Equal(t, []string{"id-token-1", "id-token-2"}, subjects())
+	})
+
+	t.Run("keeps a token far from expiry", func(t *testing.T) {
+		server, subjects := newRecordingExchangeServer(t, 3600)
+
+		credentials, err := (&auth.WorkloadIdentity{
+			ServiceAccountID: "sa-id",
+			IDToken:          "id-token",
+			Endpoint:         server.URL,
+			HTTPClient:       server.Client(),
+		}).Credentials(ctx)
+		require.NoError(t, err)
+
+		token, err := credentials.(ycsdk.NonExchangeableCredentials).IAMToken(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "t1.token-1", token.IamToken)
+		assert.Len(t, subjects(), 1)
+	})
+
+	t.Run("expired ID token", func(t *testing.T) {
+		server, subjects := newRecordingExchangeServer(t, 60)
+
+		idToken := newIDToken(time.Now().Add(time.Hour))
+		credentials, err := (&auth.WorkloadIdentity{
+			ServiceAccountID: "sa-id",
+			IDTokenSource:    func() string { return idToken },
+			Endpoint:         server.URL,
+			HTTPClient:       server.Client(),
+		}).Credentials(ctx)
+		require.NoError(t, err)
+
+		// The IAM token is still valid, so it is used while the ID token can't be exchanged again
+		idToken = newIDToken(time.Now().Add(-time.Minute))
+
+		token, err := credentials.(ycsdk.NonExchangeableCredentials).IAMToken(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "t1.token-1", token.IamToken)
+		assert.Len(t, subjects(), 1)
+
+		_, err = (&auth.WorkloadIdentity{
+			ServiceAccountID: "sa-id",
+			IDToken:          idToken,
+			Endpoint:         server.URL,
+			HTTPClient:       server.Client(),
+		}).Credentials(ctx)
+		assert.ErrorContains(t, err, "OIDC ID token expired")
+		assert.Len(t, subjects(), 1)
+	})
+}
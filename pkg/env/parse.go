@@ -92,7 +92,7 @@ func ParseSecrets(inputs []string) []*Secret {
 		secrets = append(secrets, secret)
 	}
 
-	sourcecraft.Info(fmt.Sprintf("SecretsObject: %q", secrets))
+	sourcecraft.Info(fmt.Sprintf("Parsed secrets: %d", len(secrets)))
 
 	return secrets
 }
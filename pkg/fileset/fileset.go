@@ -0,0 +1,311 @@
+// Package fileset matches paths against gitignore-style patterns.
+package fileset
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// Ignore files read from the source root.
+const (
+	FuncIgnoreFile = ".funcignore"
+	YCIgnoreFile   = ".yc-ignore"
+)
+
+// pattern is a parsed gitignore-style pattern.
+type pattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// Matcher matches slash-separated paths relative to a root against gitignore-style patterns:
+//   - "*", "?" and "[...]" match within a path segment, "**" matches any number of segments;
+//   - a pattern with a slash at the beginning or in the middle is anchored to the root,
+//     otherwise it matches at any depth;
+//   - a trailing slash matches directories only;
+//   - "!" re-includes paths matched by a previous pattern, the last matching pattern wins;
+//   - a path inside a matched directory is matched too and can't be re-included.
+type Matcher struct {
+	patterns []pattern
+}
+
+// New parses the patterns. Blank lines and lines starting with "#" are skipped.
+func New(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+
+	for _, line := range patterns {
+		p, ok, err := parse(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+
+	return m, nil
+}
+
+// parse parses a single pattern line.
+func parse(line string) (pattern, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false, nil
+	}
+
+	var p pattern
+
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		// Escaped leading "!" or "#"
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	if line == "" {
+		return pattern{}, false, nil
+	}
+
+	p.segments = strings.Split(line, "/")
+	if !anchored && p.segments[0] != "**" {
+		p.segments = append([]string{"**"}, p.segments...)
+	}
+
+	for _, segment := range p.segments {
+		_, err := path.Match(segment, "")
+		if err != nil {
+			return pattern{}, false, fmt.Errorf("invalid pattern %q: %w", line, err)
+		}
+	}
+
+	return p, true, nil
+}
+
+// matchSegments matches path segments against pattern segments, "**" matching zero or more segments.
+func matchSegments(patternSegments, pathSegments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(pathSegments) == 0
+	}
+
+	if patternSegments[0] == "**" {
+		for i := 0; i <= len(pathSegments); i++ {
+			if matchSegments(patternSegments[1:], pathSegments[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(pathSegments) == 0 {
+		return false
+	}
+
+	matched, err := path.Match(patternSegments[0], pathSegments[0])
+	if err != nil || !matched {
+		return false
+	}
+
+	return matchSegments(patternSegments[1:], pathSegments[1:])
+}
+
+// matchPrefix reports whether the path segments can be the beginning of a path matched by the pattern segments,
+// or are inside a matched path.
+func matchPrefix(patternSegments, pathSegments []string) bool {
+	if len(patternSegments) == 0 || len(pathSegments) == 0 || patternSegments[0] == "**" {
+		return true
+	}
+
+	matched, err := path.Match(patternSegments[0], pathSegments[0])
+	if err != nil || !matched {
+		return false
+	}
+
+	return matchPrefix(patternSegments[1:], pathSegments[1:])
+}
+
+// matchOwn reports whether the path itself is matched, ignoring its parent directories.
+func (m *Matcher) matchOwn(segments []string, isDir bool) bool {
+	matched := false
+
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		if matchSegments(p.segments, segments) {
+			matched = !p.negate
+		}
+	}
+
+	return matched
+}
+
+// Match reports whether the path, relative to the root, is matched by the patterns.
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+	if relPath == "." || len(m.patterns) == 0 {
+		return false
+	}
+
+	segments := strings.Split(relPath, "/")
+
+	// A path inside a matched directory is matched
+	for i := 1; i < len(segments); i++ {
+		if m.matchOwn(segments[:i], true) {
+			return true
+		}
+	}
+
+	return m.matchOwn(segments, isDir)
+}
+
+// MayMatchInside reports whether paths inside the directory, relative to the root, may be matched by the patterns,
+// so that directories without matched paths are skipped when walking.
+func (m *Matcher) MayMatchInside(relDir string) bool {
+	relDir = filepath.ToSlash(filepath.Clean(relDir))
+	if relDir == "." {
+		return len(m.patterns) > 0
+	}
+
+	segments := strings.Split(relDir, "/")
+
+	for _, p := range m.patterns {
+		if !p.negate && matchPrefix(p.segments, segments) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewAnchored parses include patterns like New, except that every pattern is anchored to the root:
+// "*.html" matches HTML files in the root only and "**/*.html" matches them at any depth. "." matches
+// everything in the root.
+func NewAnchored(patterns []string) (*Matcher, error) {
+	anchored := make([]string, 0, len(patterns))
+	for _, line := range patterns {
+		anchored = append(anchored, anchor(line))
+	}
+
+	return New(anchored)
+}
+
+// anchor anchors the pattern line to the root.
+func anchor(line string) string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return line
+	}
+
+	negate := ""
+	if strings.HasPrefix(line, "!") {
+		negate = "!"
+		line = line[1:]
+	}
+
+	dirOnly := strings.HasSuffix(line, "/")
+
+	line = path.Clean("/" + line)
+	if line == "/" {
+		line = "/**"
+	}
+
+	if dirOnly {
+		line += "/"
+	}
+
+	return negate + line
+}
+
+// Walk calls fn with the path and the path relative to the root of every file in the root matched
+// by include and not matched by ignore. Ignored directories and directories without included files
+// are skipped as a whole.
+func Walk(f afero.Fs, root string, include, ignore *Matcher, fn func(filePath, relPath string) error) error {
+	return afero.Walk(f, root, func(filePath string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+
+		if info.IsDir() {
+			if relPath != "." && (ignore.Match(relPath, true) || !include.MayMatchInside(relPath)) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !include.Match(relPath, false) || ignore.Match(relPath, false) {
+			return nil
+		}
+
+		return fn(filePath, relPath)
+	})
+}
+
+// ReadIgnoreFile returns the pattern lines of the ignore file, or nil if it doesn't exist.
+func ReadIgnoreFile(f afero.Fs, filePath string) ([]string, error) {
+	file, err := f.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ignore file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	return lines, nil
+}
+
+// Load creates a matcher from the ignore files found in the root followed by the patterns,
+// so that the patterns may override the ignore files.
+func Load(f afero.Fs, root string, patterns []string, ignoreFiles ...string) (*Matcher, error) {
+	var lines []string
+
+	for _, name := range ignoreFiles {
+		fileLines, err := ReadIgnoreFile(f, filepath.Join(root, name))
+		if err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, fileLines...)
+	}
+
+	return New(append(lines, patterns...))
+}
@@ -0,0 +1,124 @@
+package fileset_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/pkg/fileset"
+)
+
+func TestMatcherMatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "unanchored at root", patterns: []string{"*.js"}, path: "index.js", expected: true},
+		{name: "unanchored nested", patterns: []string{"*.js"}, path: "a/b/index.js", expected: true},
+		{name: "no match", patterns: []string{"*.js"}, path: "index.html", expected: false},
+		{name: "anchored at root", patterns: []string{"/build"}, path: "build/app.js", expected: true},
+		{name: "anchored not nested", patterns: []string{"/build"}, path: "src/build/app.js", expected: false},
+		{name: "middle slash anchors", patterns: []string{"src/*.go"}, path: "pkg/src/main.go", expected: false},
+		{name: "double star prefix", patterns: []string{"**/testdata"}, path: "a/b/testdata/x.json", expected: true},
+		{name: "double star suffix", patterns: []string{"docs/**"}, path: "docs/a/b.md", expected: true},
+		{name: "double star middle", patterns: []string{"a/**/b.txt"}, path: "a/b.txt", expected: true},
+		{name: "double star middle deep", patterns: []string{"a/**/b.txt"}, path: "a/x/y/b.txt", expected: true},
+		{name: "dir only matches dir", patterns: []string{"node_modules/"}, path: "node_modules", isDir: true, expected: true},
+		{name: "dir only matches content", patterns: []string{"node_modules/"}, path: "web/node_modules/x.js", expected: true},
+		{name: "dir only skips file", patterns: []string{"cache/"}, path: "cache", expected: false},
+		{name: "negation", patterns: []string{"*.md", "!README.md"}, path: "README.md", expected: false},
+		{name: "negation keeps others", patterns: []string{"*.md", "!README.md"}, path: "CHANGES.md", expected: true},
+		{name: "last pattern wins", patterns: []string{"!a.txt", "*.txt"}, path: "a.txt", expected: true},
+		{name: "no re-include in ignored dir", patterns: []string{"dist/", "!dist/keep.js"}, path: "dist/keep.js", expected: true},
+		{name: "comments and blanks", patterns: []string{"# comment", "", `\#file`}, path: "#file", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := fileset.New(tc.patterns)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, m.Match(tc.path, tc.isDir))
+		})
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	_, err := fileset.New([]string{"[a-"})
+	assert.ErrorContains(t, err, "invalid pattern")
+}
+
+func TestLoad(t *testing.T) {
+	f := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(f, "src/.funcignore", []byte("# tests\n*_test.go\ntestdata/\n"), 0o644))
+
+	m, err := fileset.Load(f, "src", []string{"!main_test.go"}, fileset.FuncIgnoreFile, fileset.YCIgnoreFile)
+	require.NoError(t, err)
+
+	assert.True(t, m.Match("pkg/a_test.go", false))
+	assert.True(t, m.Match("testdata/x.json", false))
+	assert.False(t, m.Match("main_test.go", false))
+	assert.False(t, m.Match("main.go", false))
+}
+
+func TestNewAnchored(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		path     string
+		expected bool
+	}{
+		{name: "everything", patterns: []string{"."}, path: "a/b/c.txt", expected: true},
+		{name: "root file", patterns: []string{"*.html"}, path: "index.html", expected: true},
+		{name: "not nested", patterns: []string{"*.html"}, path: "docs/index.html", expected: false},
+		{name: "double star", patterns: []string{"**/*.html"}, path: "docs/a/index.html", expected: true},
+		{name: "directory", patterns: []string{"./dist/"}, path: "dist/app.js", expected: true},
+		{name: "nested directory", patterns: []string{"dist"}, path: "web/dist/app.js", expected: false},
+		{name: "negation", patterns: []string{"**/*.js", "!*.test.js"}, path: "app.test.js", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := fileset.NewAnchored(tc.patterns)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, m.Match(tc.path, false))
+		})
+	}
+}
+
+func TestMayMatchInside(t *testing.T) {
+	m, err := fileset.NewAnchored([]string{"src/**/*.go", "docs"})
+	require.NoError(t, err)
+
+	assert.True(t, m.MayMatchInside("src"))
+	assert.True(t, m.MayMatchInside("src/pkg/util"))
+	assert.True(t, m.MayMatchInside("docs/api"))
+	assert.False(t, m.MayMatchInside("node_modules"))
+	assert.False(t, m.MayMatchInside("web/src"))
+}
+
+func TestWalk(t *testing.T) {
+	f := afero.NewMemMapFs()
+	for _, name := range []string{"root/main.go", "root/pkg/a.go", "root/pkg/a_test.go", "root/web/app.js"} {
+		require.NoError(t, afero.WriteFile(f, name, []byte("x"), 0o644))
+	}
+
+	include, err := fileset.NewAnchored([]string{"**/*.go"})
+	require.NoError(t, err)
+
+	ignore, err := fileset.New([]string{"*_test.go"})
+	require.NoError(t, err)
+
+	var files []string
+
+	err = fileset.Walk(f, "root", include, ignore, func(_, relPath string) error {
+		files = append(files, relPath)
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "pkg/a.go"}, files)
+}
@@ -0,0 +1,446 @@
+// Package ociimage layers files of the workspace onto a base image without a container daemon, pushes images
+// to a registry and resolves image references to digests. It doesn't read Dockerfiles and doesn't run anything:
+// the image is the base image with a single layer of the listed files and the given configuration on top.
+package ociimage
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// Scratch is the base image name of an empty image.
+const Scratch = "scratch"
+
+// layerModTime is the modification time of all layer entries, so that the same files always give
+// the same layer.
+var layerModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Platform is the platform of the images, the one serverless containers run on.
+var Platform = v1.Platform{OS: "linux", Architecture: "amd64"}
+
+// File is a file or a directory of the source directory copied into the image.
+type File struct {
+	// Source is the path relative to the source directory
+	Source string
+	// Destination is the absolute path in the image. A file is copied into a destination ending with a slash,
+	// the contents of a directory are copied into the destination directory
+	Destination string
+}
+
+// Config is the configuration set on top of the configuration of the base image.
+type Config struct {
+	// Entrypoint replaces the entrypoint of the base image, and its command unless Cmd is set
+	Entrypoint []string
+	// Cmd replaces the command of the base image
+	Cmd []string
+	// WorkingDir replaces the working directory of the base image
+	WorkingDir string
+	// Env adds variables to the environment of the base image, replacing the ones with the same names
+	Env map[string]string
+}
+
+// Options are the options of layering files onto a base image.
+type Options struct {
+	// Base is the reference of the base image, or scratch for an empty one
+	Base string
+	// SourceDir is the directory the sources of the files are relative to
+	SourceDir string
+	// Files are the files copied into the image
+	Files []File
+	// Config is the configuration set on top of the base image
+	Config Config
+	// Keychain authenticates pulling the base image, anonymous if nil
+	Keychain authn.Keychain
+	// LayerDir is the directory the layer tarball is written to. The layer is read from it until
+	// the image is pushed, so it must be removed by the caller afterwards
+	LayerDir string
+}
+
+// ParseFiles parses the files copied into the image, one SOURCE:DESTINATION per line, e.g. bin/server:/srv/server.
+// Every invalid line is reported.
+func ParseFiles(lines []string) ([]File, error) {
+	var (
+		files []File
+		errs  []error
+	)
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		source, destination, ok := strings.Cut(line, ":")
+		source, destination = strings.TrimSpace(source), strings.TrimSpace(destination)
+
+		switch {
+		case !ok || source == "" || destination == "":
+			errs = append(errs, fmt.Errorf("%q: expected SOURCE:DESTINATION", line))
+		case !filepath.IsLocal(filepath.FromSlash(source)):
+			errs = append(errs, fmt.Errorf("%q: source must be a relative path inside the source directory", line))
+		case !path.IsAbs(destination):
+			errs = append(errs, fmt.Errorf("%q: destination must be an absolute path in the image", line))
+		default:
+			files = append(files, File{Source: source, Destination: destination})
+		}
+	}
+
+	return files, errors.Join(errs...)
+}
+
+// Validate checks that the base image reference is valid and that the files exist, so that nothing is pulled
+// for options that can't give an image. Every problem is reported.
+func (o *Options) Validate() error {
+	var errs []error
+
+	if o.Base == "" {
+		errs = append(errs, fmt.Errorf("base image is required"))
+	} else if o.Base != Scratch {
+		_, err := name.ParseReference(o.Base)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid base image %s: %w", o.Base, err))
+		}
+	}
+
+	if len(o.Files) == 0 {
+		errs = append(errs, fmt.Errorf("at least one file is required"))
+	}
+
+	for _, file := range o.Files {
+		if !filepath.IsLocal(filepath.FromSlash(file.Source)) {
+			errs = append(errs, fmt.Errorf("source %s is outside of the source directory", file.Source))
+
+			continue
+		}
+
+		_, err := os.Stat(filepath.Join(o.SourceDir, filepath.FromSlash(file.Source)))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("source %s: %w", file.Source, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// LayerFiles returns the base image with a layer of the files and the configuration on top of it.
+// The options are validated before the base image is pulled.
+func LayerFiles(ctx context.Context, options *Options) (v1.Image, error) {
+	if options.LayerDir == "" {
+		return nil, fmt.Errorf("layer directory is required")
+	}
+
+	err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := pullBase(ctx, options.Base, options.Keychain)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := base.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config of %s: %w", options.Base, err)
+	}
+
+	layer, err := writeLayer(options)
+	if err != nil {
+		return nil, err
+	}
+
+	createdBy := make([]string, len(options.Files))
+	for i, file := range options.Files {
+		createdBy[i] = file.Source + ":" + file.Destination
+	}
+
+	image, err := mutate.Append(base, mutate.Addendum{
+		Layer: layer,
+		History: v1.History{
+			CreatedBy: "layer " + strings.Join(createdBy, " "),
+			Created:   v1.Time{Time: layerModTime},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add layer: %w", err)
+	}
+
+	return mutate.Config(image, applyConfig(*configFile.Config.DeepCopy(), options.Config))
+}
+
+// pullBase returns the base image, an empty one for scratch.
+func pullBase(ctx context.Context, base string, keychain authn.Keychain) (v1.Image, error) {
+	if base == Scratch {
+		return mutate.ConfigFile(empty.Image, &v1.ConfigFile{
+			OS:           Platform.OS,
+			Architecture: Platform.Architecture,
+		})
+	}
+
+	ref, err := name.ParseReference(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base image %s: %w", base, err)
+	}
+
+	if keychain == nil {
+		keychain = authn.NewMultiKeychain()
+	}
+
+	image, err := remote.Image(
+		ref,
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(keychain),
+		remote.WithPlatform(Platform),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull base image %s: %w", base, err)
+	}
+
+	return image, nil
+}
+
+// applyConfig returns the configuration of the base image with the configuration set on top of it.
+func applyConfig(config v1.Config, overrides Config) v1.Config {
+	if len(overrides.Entrypoint) > 0 {
+		config.Entrypoint = overrides.Entrypoint
+		// The command of the base image doesn't apply to a new entrypoint
+		config.Cmd = nil
+	}
+
+	if len(overrides.Cmd) > 0 {
+		config.Cmd = overrides.Cmd
+	}
+
+	if overrides.WorkingDir != "" {
+		config.WorkingDir = overrides.WorkingDir
+	}
+
+	keys := make([]string, 0, len(overrides.Env))
+	for key := range overrides.Env {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		config.Env = setEnv(config.Env, key, overrides.Env[key])
+	}
+
+	return config
+}
+
+// setEnv sets the variable in the KEY=VALUE list, replacing its previous value.
+func setEnv(env []string, key, value string) []string {
+	for i, variable := range env {
+		if strings.HasPrefix(variable, key+"=") {
+			env[i] = key + "=" + value
+
+			return env
+		}
+	}
+
+	return append(env, key+"="+value)
+}
+
+// writeLayer writes the files to a layer tarball in the layer directory and returns the layer.
+func writeLayer(options *Options) (v1.Layer, error) {
+	layer, err := newLayerWriter(options.LayerDir)
+	if err != nil {
+		return nil, err
+	}
+	defer layer.file.Close()
+
+	for _, file := range options.Files {
+		err = layer.AddSource(options.SourceDir, file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = layer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := tarball.LayerFromFile(layer.file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create layer: %w", err)
+	}
+
+	return l, nil
+}
+
+// layerWriter writes a layer tarball with the parent directories of the entries to a file, so that
+// the size of the copied files isn't limited by memory.
+type layerWriter struct {
+	file *os.File
+	tw   *tar.Writer
+	dirs map[string]bool
+}
+
+func newLayerWriter(dir string) (*layerWriter, error) {
+	file, err := os.CreateTemp(dir, "layer-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create layer file: %w", err)
+	}
+
+	return &layerWriter{file: file, tw: tar.NewWriter(file), dirs: map[string]bool{"/": true}}, nil
+}
+
+// AddSource writes the source file, or the source directory and its contents, to the layer.
+func (l *layerWriter) AddSource(sourceDir string, file File) error {
+	root := filepath.Join(sourceDir, filepath.FromSlash(file.Source))
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", file.Source, err)
+	}
+
+	dest := path.Clean(file.Destination)
+
+	if !info.IsDir() {
+		if strings.HasSuffix(file.Destination, "/") {
+			dest = path.Join(dest, filepath.Base(root))
+		}
+
+		return l.AddFile(root, dest, info)
+	}
+
+	return filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		inner, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		target := path.Join(dest, filepath.ToSlash(inner))
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return l.AddDir(target, info)
+		}
+
+		return l.AddFile(p, target, info)
+	})
+}
+
+// addParents writes the parent directories of the path that aren't written yet.
+func (l *layerWriter) addParents(p string) error {
+	var parents []string
+
+	for dir := path.Dir(p); !l.dirs[dir]; dir = path.Dir(dir) {
+		parents = append(parents, dir)
+	}
+
+	sort.Strings(parents)
+
+	for _, dir := range parents {
+		err := l.writeDir(dir, 0o755)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *layerWriter) writeDir(dir string, mode fs.FileMode) error {
+	l.dirs[dir] = true
+
+	return l.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     strings.TrimPrefix(dir, "/") + "/",
+		Mode:     int64(mode.Perm()),
+		ModTime:  layerModTime,
+	})
+}
+
+// AddDir writes the directory to the layer.
+func (l *layerWriter) AddDir(dir string, info fs.FileInfo) error {
+	if l.dirs[dir] {
+		return nil
+	}
+
+	err := l.addParents(dir)
+	if err != nil {
+		return err
+	}
+
+	return l.writeDir(dir, info.Mode())
+}
+
+// AddFile writes the file to the layer.
+func (l *layerWriter) AddFile(source, dest string, info fs.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", source)
+	}
+
+	err := l.addParents(dest)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer file.Close()
+
+	err = l.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     strings.TrimPrefix(dest, "/"),
+		Mode:     int64(info.Mode().Perm()),
+		Size:     info.Size(),
+		ModTime:  layerModTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+
+	_, err = io.Copy(l.tw, file)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+
+	return nil
+}
+
+// Close finishes the layer tarball.
+func (l *layerWriter) Close() error {
+	err := l.tw.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write layer: %w", err)
+	}
+
+	err = l.file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write layer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package ociimage_test
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/pkg/ociimage"
+)
+
+// startRegistry starts an in-memory OCI registry and returns its host.
+func startRegistry(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	return u.Host
+}
+
+// writeFiles writes the files, relative to the directory.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for file, content := range files {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+// imageFiles returns the contents of the regular files of the flattened image.
+func imageFiles(t *testing.T, image v1.Image) map[string]string {
+	t.Helper()
+
+	reader := mutate.Extract(image)
+	defer reader.Close()
+
+	files := make(map[string]string)
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		if header.Typeflag == tar.TypeReg {
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+
+			files[header.Name] = string(content)
+		}
+	}
+
+	return files
+}
+
+func TestLayerFilesAndPush(t *testing.T) {
+	host := startRegistry(t)
+	sourceDir := t.TempDir()
+
+	writeFiles(t, sourceDir, map[string]string{
+		"secret.txt":        "secret",
+		"bin/server":        "binary",
+		"static/index.html": "<html></html>",
+	})
+
+	files, err := ociimage.ParseFiles([]string{"bin/server:/srv/", "static:/srv/static", "bin:/src/bin"})
+	require.NoError(t, err)
+
+	image, err := ociimage.LayerFiles(context.Background(), &ociimage.Options{
+		Base:      ociimage.Scratch,
+		SourceDir: sourceDir,
+		Files:     files,
+		Config: ociimage.Config{
+			Entrypoint: []string{"/srv/server"},
+			Cmd:        []string{"--port", "8080"},
+			WorkingDir: "/srv",
+			Env:        map[string]string{"HOME": "/srv", "APP_VERSION": "1.2.3"},
+		},
+		LayerDir: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	tag, err := ociimage.TagReference(host+"/app", "abc123")
+	require.NoError(t, err)
+
+	pinned, err := ociimage.Push(context.Background(), image, tag, authn.NewMultiKeychain())
+	require.NoError(t, err)
+
+	digest, err := image.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, host+"/app@"+digest.String(), pinned.String())
+
+	// The tag and the digest point to the same image
+	tagged, err := remote.Head(tag)
+	require.NoError(t, err)
+	assert.Equal(t, digest, tagged.Digest)
+
+	pushed, err := remote.Image(pinned)
+	require.NoError(t, err)
+
+	configFile, err := pushed.ConfigFile()
+	require.NoError(t, err)
+
+	assert.Equal(t, "linux", configFile.OS)
+	assert.Equal(t, "amd64", configFile.Architecture)
+	assert.Equal(t, []string{"APP_VERSION=1.2.3", "HOME=/srv"}, configFile.Config.Env)
+	assert.Equal(t, "/srv", configFile.Config.WorkingDir)
+	assert.Equal(t, []string{"/srv/server"}, configFile.Config.Entrypoint)
+	assert.Equal(t, []string{"--port", "8080"}, configFile.Config.Cmd)
+
+	layers, err := pushed.Layers()
+	require.NoError(t, err)
+	assert.Len(t, layers, 1)
+
+	imageContents := imageFiles(t, pushed)
+	assert.Equal(t, map[string]string{
+		"srv/server":            "binary",
+		"srv/static/index.html": "<html></html>",
+		"src/bin/server":        "binary",
+	}, imageContents)
+}
+
+func TestLayerFilesIsReproducible(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, map[string]string{"app": "binary"})
+
+	options := &ociimage.Options{
+		Base:      ociimage.Scratch,
+		SourceDir: sourceDir,
+		Files:     []ociimage.File{{Source: "app", Destination: "/app"}},
+		LayerDir:  t.TempDir(),
+	}
+
+	first, err := ociimage.LayerFiles(context.Background(), options)
+	require.NoError(t, err)
+
+	second, err := ociimage.LayerFiles(context.Background(), options)
+	require.NoError(t, err)
+
+	firstDigest, err := first.Digest()
+	require.NoError(t, err)
+
+	secondDigest, err := second.Digest()
+	require.NoError(t, err)
+
+	assert.Equal(t, firstDigest, secondDigest)
+}
+
+func TestLayerFilesOnRegistryBase(t *testing.T) {
+	host := startRegistry(t)
+
+	base, err := random.Image(256, 2)
+	require.NoError(t, err)
+
+	base, err = mutate.Config(base, v1.Config{
+		Env:        []string{"PATH=/bin", "HOME=/root"},
+		Entrypoint: []string{"/bin/sh"},
+		Cmd:        []string{"-c", "true"},
+	})
+	require.NoError(t, err)
+
+	baseRef, err := name.NewTag(host + "/base:1")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(baseRef, base))
+
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, map[string]string{"app": "binary"})
+
+	image, err := ociimage.LayerFiles(context.Background(), &ociimage.Options{
+		Base:      baseRef.String(),
+		SourceDir: sourceDir,
+		Files:     []ociimage.File{{Source: "app", Destination: "/app"}},
+		Config: ociimage.Config{
+			Entrypoint: []string{"/app"},
+			Env:        map[string]string{"HOME": "/srv"},
+		},
+		Keychain: ociimage.IAMKeychain("token"),
+		LayerDir: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	layers, err := image.Layers()
+	require.NoError(t, err)
+	assert.Len(t, layers, 3)
+
+	configFile, err := image.ConfigFile()
+	require.NoError(t, err)
+
+	// The environment of the base image is kept, and its command doesn't apply to the new entrypoint
+	assert.Equal(t, []string{"PATH=/bin", "HOME=/srv"}, configFile.Config.Env)
+	assert.Equal(t, []string{"/app"}, configFile.Config.Entrypoint)
+	assert.Empty(t, configFile.Config.Cmd)
+}
+
+func TestLayerFilesInvalid(t *testing.T) {
+	// The base image doesn't exist: invalid options are rejected before anything is pulled
+	const base = "unreachable.invalid/base"
+
+	tests := map[string]*ociimage.Options{
+		"no base":        {Files: []ociimage.File{{Source: "app", Destination: "/app"}}},
+		"invalid base":   {Base: "UPPER/Case", Files: []ociimage.File{{Source: "app", Destination: "/app"}}},
+		"no files":       {Base: base},
+		"missing source": {Base: base, Files: []ociimage.File{{Source: "missing", Destination: "/app"}}},
+		"outside source": {Base: base, Files: []ociimage.File{{Source: "../app", Destination: "/app"}}},
+	}
+
+	for name, options := range tests {
+		t.Run(name, func(t *testing.T) {
+			sourceDir := t.TempDir()
+			writeFiles(t, sourceDir, map[string]string{"app": "binary"})
+
+			options.SourceDir = sourceDir
+			options.LayerDir = t.TempDir()
+
+			_, err := ociimage.LayerFiles(context.Background(), options)
+			require.Error(t, err)
+			assert.NotContains(t, err.Error(), "failed to pull base image")
+		})
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	files, err := ociimage.ParseFiles([]string{"bin/server:/srv/server", "", " static : /srv/static/ "})
+	require.NoError(t, err)
+	assert.Equal(t, []ociimage.File{
+		{Source: "bin/server", Destination: "/srv/server"},
+		{Source: "static", Destination: "/srv/static/"},
+	}, files)
+
+	_, err = ociimage.ParseFiles([]string{"app", "../app:/app", "/app:/app", "app:app"})
+	require.Error(t, err)
+
+	for _, line := range []string{`"app"`, `"../app:/app"`, `"/app:/app"`, `"app:app"`} {
+		assert.Contains(t, err.Error(), line)
+	}
+}
+
+func TestIAMKeychain(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	keychain := ociimage.IAMKeychain("token")
+
+	for _, repository := range []string{"cr.yandex/registry/app", "ru-central1.cr.yandex/registry/app"} {
+		repo, err := name.NewRepository(repository)
+		require.NoError(t, err)
+
+		auth, err := keychain.Resolve(repo)
+		require.NoError(t, err)
+
+		config, err := auth.Authorization()
+		require.NoError(t, err)
+		assert.Equal(t, "iam", config.Username, repository)
+		assert.Equal(t, "token", config.Password, repository)
+	}
+
+	for _, repository := range []string{"docker.io/library/alpine", "ghcr.io/owner/app", "cr.yandex.example.com/app"} {
+		other, err := name.NewRepository(repository)
+		require.NoError(t, err)
+
+		auth, err := keychain.Resolve(other)
+		require.NoError(t, err)
+		assert.Equal(t, authn.Anonymous, auth, repository)
+	}
+}
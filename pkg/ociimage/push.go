@@ -0,0 +1,70 @@
+package ociimage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// iamUsername is the username Container Registry accepts IAM tokens with.
+const iamUsername = "iam"
+
+// containerRegistryHost is the host of Container Registry. Regional endpoints are its subdomains.
+const containerRegistryHost = "cr.yandex"
+
+// iamKeychain authenticates to Container Registry with an IAM token.
+type iamKeychain struct {
+	token string
+}
+
+// IAMKeychain returns the keychain authenticating to Container Registry (cr.yandex and its subdomains)
+// with the IAM token. The token is never sent to other registries: they are accessed with the credentials
+// of the default keychain, e.g. from the Docker config, or anonymously.
+func IAMKeychain(token string) authn.Keychain {
+	return &iamKeychain{token: token}
+}
+
+// Resolve returns the authenticator of the resource.
+func (k *iamKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	if !IsContainerRegistry(resource.RegistryStr()) {
+		return authn.DefaultKeychain.Resolve(resource)
+	}
+
+	return &authn.Basic{Username: iamUsername, Password: k.token}, nil
+}
+
+// IsContainerRegistry reports whether the registry host is Container Registry.
+func IsContainerRegistry(registry string) bool {
+	return registry == containerRegistryHost || strings.HasSuffix(registry, "."+containerRegistryHost)
+}
+
+// TagReference returns the reference of the repository, e.g. cr.yandex/REGISTRY_ID/NAME, with the tag.
+func TagReference(repository, tag string) (name.Tag, error) {
+	ref, err := name.NewTag(fmt.Sprintf("%s:%s", repository, tag))
+	if err != nil {
+		return name.Tag{}, fmt.Errorf("invalid image reference %s:%s: %w", repository, tag, err)
+	}
+
+	return ref, nil
+}
+
+// Push pushes the image with the tag and returns the reference pinned to its digest,
+// e.g. cr.yandex/REGISTRY_ID/NAME@sha256:...
+func Push(ctx context.Context, image v1.Image, tag name.Tag, keychain authn.Keychain) (name.Digest, error) {
+	digest, err := image.Digest()
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("failed to compute image digest: %w", err)
+	}
+
+	err = remote.Write(tag, image, remote.WithContext(ctx), remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("failed to push image %s: %w", tag, err)
+	}
+
+	return tag.Context().Digest(digest.String()), nil
+}
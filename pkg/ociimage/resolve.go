@@ -0,0 +1,43 @@
+package ociimage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ParseReference parses the image reference, e.g. cr.yandex/REGISTRY_ID/NAME:TAG.
+func ParseReference(imageURL string) (name.Reference, error) {
+	ref, err := name.ParseReference(imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %s: %w", imageURL, err)
+	}
+
+	return ref, nil
+}
+
+// Resolve checks that the image exists in the registry and returns its reference pinned to the digest.
+// A reference that is already pinned is returned as is once the image is found.
+func Resolve(ctx context.Context, ref name.Reference, keychain authn.Keychain) (name.Digest, error) {
+	descriptor, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+			return name.Digest{}, fmt.Errorf("image %s not found", ref)
+		}
+
+		return name.Digest{}, fmt.Errorf("failed to resolve image %s: %w", ref, err)
+	}
+
+	if digest, ok := ref.(name.Digest); ok {
+		return digest, nil
+	}
+
+	return ref.Context().Digest(descriptor.Digest.String()), nil
+}
@@ -0,0 +1,71 @@
+package ociimage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/pkg/ociimage"
+)
+
+func TestResolve(t *testing.T) {
+	host := startRegistry(t)
+	keychain := authn.NewMultiKeychain()
+
+	image, err := random.Image(256, 1)
+	require.NoError(t, err)
+
+	tag, err := name.NewTag(host + "/app:latest")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, image))
+
+	digest, err := image.Digest()
+	require.NoError(t, err)
+
+	pinned, err := ociimage.Resolve(context.Background(), tag, keychain)
+	require.NoError(t, err)
+	assert.Equal(t, host+"/app@"+digest.String(), pinned.String())
+	assert.Equal(t, digest.String(), pinned.DigestStr())
+
+	// A pinned reference resolves to itself
+	ref, err := ociimage.ParseReference(pinned.String())
+	require.NoError(t, err)
+
+	again, err := ociimage.Resolve(context.Background(), ref, keychain)
+	require.NoError(t, err)
+	assert.Equal(t, pinned, again)
+}
+
+func TestResolveMissing(t *testing.T) {
+	host := startRegistry(t)
+	keychain := authn.NewMultiKeychain()
+
+	image, err := random.Image(256, 1)
+	require.NoError(t, err)
+
+	tag, err := name.NewTag(host + "/app:v1")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, image))
+
+	references := []string{
+		host + "/app:v2",
+		host + "/other:v1",
+		host + "/app@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	for _, reference := range references {
+		ref, err := ociimage.ParseReference(reference)
+		require.NoError(t, err)
+
+		_, err = ociimage.Resolve(context.Background(), ref, keychain)
+		assert.ErrorContains(t, err, "not found", reference)
+	}
+
+	_, err = ociimage.ParseReference("Invalid:Reference:")
+	assert.Error(t, err)
+}
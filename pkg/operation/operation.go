@@ -0,0 +1,114 @@
+// Package operation waits for long-running Yandex Cloud operations with a timeout and progress logging.
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sdkoperation "github.com/yandex-cloud/go-sdk/operation"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Default waiting parameters.
+const (
+	DefaultTimeout      = 10 * time.Minute
+	DefaultPollInterval = 5 * time.Second
+)
+
+// maxNotFoundRetries is the number of NotFound poll errors tolerated right after
+// the operation was started, while it is not yet visible on all replicas.
+const maxNotFoundRetries = 3
+
+// Waiter waits for long-running operations to complete, logging their progress.
+type Waiter struct {
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// NewWaiter creates a Waiter with the given timeout and the default poll interval.
+// A non-positive timeout means DefaultTimeout.
+func NewWaiter(timeout time.Duration) *Waiter {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Waiter{
+		Timeout:      timeout,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// Wait polls the operation until it is done. It returns an error if the operation
+// fails, including the gRPC status details, or if it is not done within the timeout.
+func (w *Waiter) Wait(ctx context.Context, op *sdkoperation.Operation) error {
+	ctx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	notFound := 0
+
+	for !op.Done() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf(
+				"operation %s (%s) is not done after %s: %w",
+				op.Id(),
+				op.Description(),
+				time.Since(started).Round(time.Second),
+				ctx.Err(),
+			)
+		case <-time.After(w.PollInterval):
+		}
+
+		err := op.Poll(ctx)
+		if err != nil {
+			if status.Code(err) == codes.NotFound && notFound < maxNotFoundRetries {
+				notFound++
+
+				continue
+			}
+
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				continue
+			}
+
+			return fmt.Errorf("failed to poll operation %s: %w", op.Id(), err)
+		}
+
+		sourcecraft.Info(
+			fmt.Sprintf(
+				"Operation %s (%s): done=%v, elapsed %s",
+				op.Id(),
+				op.Description(),
+				op.Done(),
+				time.Since(started).Round(time.Second),
+			),
+		)
+	}
+
+	if st := op.ErrorStatus(); st != nil {
+		return fmt.Errorf("operation %s (%s) failed: %w", op.Id(), op.Description(), StatusError(st))
+	}
+
+	return nil
+}
+
+// StatusError converts a gRPC status to an error whose message includes the status details.
+func StatusError(st *status.Status) error {
+	details := st.Details()
+	if len(details) == 0 {
+		return st.Err()
+	}
+
+	parts := make([]string, 0, len(details))
+	for _, detail := range details {
+		parts = append(parts, fmt.Sprintf("%v", detail))
+	}
+
+	return fmt.Errorf("%w; details: %s", st.Err(), strings.Join(parts, "; "))
+}
@@ -0,0 +1,97 @@
+package operation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	genoperation "github.com/yandex-cloud/go-genproto/yandex/cloud/operation"
+	sdkoperation "github.com/yandex-cloud/go-sdk/operation"
+	"github.com/yc-actions/sourcecraft-actions/pkg/operation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClient returns the queued operation states on consecutive Get calls.
+type fakeClient struct {
+	states []*genoperation.Operation
+	calls  int
+}
+
+func (c *fakeClient) Get(
+	_ context.Context,
+	_ *genoperation.GetOperationRequest,
+	_ ...grpc.CallOption,
+) (*genoperation.Operation, error) {
+	state := c.states[min(c.calls, len(c.states)-1)]
+	c.calls++
+
+	return state, nil
+}
+
+func (c *fakeClient) Cancel(
+	_ context.Context,
+	_ *genoperation.CancelOperationRequest,
+	_ ...grpc.CallOption,
+) (*genoperation.Operation, error) {
+	return nil, status.Error(codes.Unimplemented, "cancel")
+}
+
+func newWaiter() *operation.Waiter {
+	return &operation.Waiter{Timeout: time.Second, PollInterval: time.Millisecond}
+}
+
+func TestWaiterWait(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("done after polling", func(t *testing.T) {
+		client := &fakeClient{states: []*genoperation.Operation{
+			{Id: "op1"},
+			{Id: "op1", Done: true},
+		}}
+		op := sdkoperation.New(client, &genoperation.Operation{Id: "op1"})
+
+		err := newWaiter().Wait(ctx, op)
+		require.NoError(t, err)
+		assert.True(t, op.Done())
+		assert.Equal(t, 2, client.calls)
+	})
+
+	t.Run("failed with details", func(t *testing.T) {
+		st, err := status.New(codes.InvalidArgument, "bad request").WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "zone_id", Description: "unknown zone"},
+			},
+		})
+		require.NoError(t, err)
+
+		client := &fakeClient{states: []*genoperation.Operation{
+			{Id: "op1", Done: true, Result: &genoperation.Operation_Error{Error: st.Proto()}},
+		}}
+		op := sdkoperation.New(client, &genoperation.Operation{Id: "op1"})
+
+		err = newWaiter().Wait(ctx, op)
+		assert.ErrorContains(t, err, "bad request")
+		assert.ErrorContains(t, err, "unknown zone")
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := &fakeClient{states: []*genoperation.Operation{{Id: "op1"}}}
+		op := sdkoperation.New(client, &genoperation.Operation{Id: "op1"})
+
+		waiter := &operation.Waiter{Timeout: 20 * time.Millisecond, PollInterval: time.Millisecond}
+
+		err := waiter.Wait(ctx, op)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestStatusError(t *testing.T) {
+	err := operation.StatusError(status.New(codes.NotFound, "no such instance"))
+	assert.EqualError(t, err, "rpc error: code = NotFound desc = no such instance")
+}
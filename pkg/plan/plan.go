@@ -0,0 +1,141 @@
+// Package plan collects the changes an action would make when it runs in dry-run mode.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Plan inputs shared by all actions.
+const (
+	inputDryRun   = "DRY_RUN"
+	inputPlanFile = "PLAN_FILE"
+)
+
+// DryRun reports whether the action should only build a plan without mutating calls.
+func DryRun() bool {
+	return sourcecraft.GetBooleanInput(inputDryRun)
+}
+
+// Step is a single change of the plan.
+type Step struct {
+	Description string          `json:"description"`
+	Details     json.RawMessage `json:"details,omitempty"`
+}
+
+// Plan is the list of changes an action would make.
+type Plan struct {
+	Action string  `json:"action"`
+	Steps  []*Step `json:"steps"`
+}
+
+// New creates an empty plan for the action.
+func New(action string) *Plan {
+	return &Plan{
+		Action: action,
+		Steps:  []*Step{},
+	}
+}
+
+// AddRequest adds a step performed by sending the API request.
+func (p *Plan) AddRequest(description string, request proto.Message) error {
+	data, err := protojson.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	p.Steps = append(p.Steps, &Step{Description: description, Details: data})
+
+	return nil
+}
+
+// AddValue adds a step described by an arbitrary JSON-serializable value.
+func (p *Plan) AddValue(description string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	p.Steps = append(p.Steps, &Step{Description: description, Details: data})
+
+	return nil
+}
+
+// Add adds a step that needs no details.
+func (p *Plan) Add(description string) {
+	p.Steps = append(p.Steps, &Step{Description: description})
+}
+
+// Print logs the human-readable plan.
+func (p *Plan) Print() {
+	sourcecraft.StartGroup("Plan")
+	defer sourcecraft.EndGroup()
+
+	if len(p.Steps) == 0 {
+		sourcecraft.Info("No changes")
+
+		return
+	}
+
+	for i, step := range p.Steps {
+		sourcecraft.Info(fmt.Sprintf("%d. %s", i+1, step.Description))
+
+		if len(step.Details) > 0 {
+			details, err := json.MarshalIndent(step.Details, "   ", "  ")
+			if err == nil {
+				sourcecraft.Info("   " + string(details))
+			}
+		}
+	}
+}
+
+// Write writes the plan as JSON to the file.
+func (p *Plan) Write(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create plan directory: %w", err)
+	}
+
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+
+	return nil
+}
+
+// Publish prints the plan, writes it to the plan file and sets the PLAN_FILE output.
+// The file is PLAN_FILE relative to the workspace, or <action>-plan.json by default.
+func (p *Plan) Publish() error {
+	p.Print()
+
+	path := sourcecraft.GetInput(inputPlanFile)
+	if path == "" {
+		path = p.Action + "-plan.json"
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(sourcecraft.GetSourcecraftWorkspace(), path)
+	}
+
+	err := p.Write(path)
+	if err != nil {
+		return err
+	}
+
+	sourcecraft.Info(fmt.Sprintf("Plan written to %s", path))
+	sourcecraft.SetOutput("PLAN_FILE", path)
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package plan_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/apigateway/v1"
+	"github.com/yc-actions/sourcecraft-actions/pkg/plan"
+)
+
+func TestPlanPublish(t *testing.T) {
+	workspace := t.TempDir()
+	t.Setenv("SOURCECRAFT_WORKSPACE", workspace)
+	t.Setenv("SOURCECRAFT_ENV", filepath.Join(workspace, "env"))
+	t.Setenv("PLAN_FILE", "")
+
+	p := plan.New("apigw")
+
+	err := p.AddRequest("Update API gateway", &apigateway.UpdateApiGatewayRequest{
+		ApiGatewayId: "gw-id",
+	})
+	require.NoError(t, err)
+
+	err = p.AddValue("Upload objects", []string{"index.html"})
+	require.NoError(t, err)
+
+	p.Add("Make gateway public")
+
+	err = p.Publish()
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(workspace, "apigw-plan.json"))
+	require.NoError(t, err)
+
+	var written struct {
+		Action string `json:"action"`
+		Steps  []struct {
+			Description string `json:"description"`
+			Details     any    `json:"details"`
+		} `json:"steps"`
+	}
+
+	err = json.Unmarshal(data, &written)
+	require.NoError(t, err)
+
+	assert.Equal(t, "apigw", written.Action)
+	require.Len(t, written.Steps, 3)
+	assert.Equal(t, "Update API gateway", written.Steps[0].Description)
+	assert.Equal(t, map[string]any{"apiGatewayId": "gw-id"}, written.Steps[0].Details)
+	assert.Equal(t, []any{"index.html"}, written.Steps[1].Details)
+	assert.Equal(t, "Make gateway public", written.Steps[2].Description)
+}
+
+func TestPlanFileInput(t *testing.T) {
+	workspace := t.TempDir()
+	t.Setenv("SOURCECRAFT_WORKSPACE", workspace)
+	t.Setenv("SOURCECRAFT_ENV", filepath.Join(workspace, "env"))
+	t.Setenv("PLAN_FILE", "plans/deploy.json")
+
+	err := plan.New("function").Publish()
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(workspace, "plans", "deploy.json"))
+}
@@ -0,0 +1,372 @@
+// Package smoke checks a deployed function or container by sending an HTTP request to it.
+package smoke
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// Smoke test inputs shared by the function and container actions.
+const (
+	inputSmokeTest       = "SMOKE_TEST"
+	inputSmokeMethod     = "SMOKE_METHOD"
+	inputSmokePath       = "SMOKE_PATH"
+	inputSmokeHeaders    = "SMOKE_HEADERS"
+	inputSmokeBody       = "SMOKE_BODY"
+	inputSmokeStatus     = "SMOKE_EXPECT_STATUS"
+	inputSmokeExpectBody = "SMOKE_EXPECT_BODY"
+	inputSmokeExpectJSON = "SMOKE_EXPECT_JSON"
+	inputSmokeTimeout    = "SMOKE_TIMEOUT"
+	inputSmokeAttempts   = "SMOKE_ATTEMPTS"
+	inputSmokeAnonymous  = "SMOKE_ANONYMOUS"
+	inputSmokeRollback   = "SMOKE_ROLLBACK"
+)
+
+// Default check parameters.
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultAttempts   = 3
+	DefaultRetryDelay = 5 * time.Second
+)
+
+// maxBodyLog is the number of response body bytes included in errors.
+const maxBodyLog = 512
+
+// JSONAssertion asserts the value at a dot-separated path of the JSON response body,
+// where array elements are addressed by index, e.g. "items.0.id".
+type JSONAssertion struct {
+	Path string
+	// Value is the expected value: a string as is, other values in JSON. Without a value the path must exist.
+	Value    string
+	HasValue bool
+}
+
+// Check is the request sent to the deployment and the assertions on its response.
+type Check struct {
+	Method       string
+	Path         string
+	Headers      http.Header
+	Body         string
+	ExpectStatus int
+	ExpectBody   *regexp.Regexp
+	ExpectJSON   []JSONAssertion
+	Timeout      time.Duration
+	Attempts     int
+	RetryDelay   time.Duration
+	// Anonymous disables sending the IAM token of the action.
+	Anonymous bool
+	// Rollback reverts the deployment if the check fails.
+	Rollback bool
+}
+
+// Enabled reports whether the smoke test is requested.
+func Enabled() bool {
+	return sourcecraft.GetBooleanInput(inputSmokeTest)
+}
+
+// InputCheck parses the check from the action inputs.
+func InputCheck() (*Check, error) {
+	headers, err := ParseHeaders(sourcecraft.GetMultilineInput(inputSmokeHeaders))
+	if err != nil {
+		return nil, err
+	}
+
+	assertions, err := ParseJSONAssertions(sourcecraft.GetMultilineInput(inputSmokeExpectJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	check := &Check{
+		Method:       strings.ToUpper(sourcecraft.GetInput(inputSmokeMethod)),
+		Path:         sourcecraft.GetInput(inputSmokePath),
+		Headers:      headers,
+		Body:         sourcecraft.GetInput(inputSmokeBody),
+		ExpectStatus: sourcecraft.GetIntInput(inputSmokeStatus, http.StatusOK),
+		ExpectJSON:   assertions,
+		Timeout:      time.Duration(sourcecraft.GetIntInput(inputSmokeTimeout, 0)) * time.Second,
+		Attempts:     sourcecraft.GetIntInput(inputSmokeAttempts, DefaultAttempts),
+		RetryDelay:   DefaultRetryDelay,
+		Anonymous:    sourcecraft.GetBooleanInput(inputSmokeAnonymous),
+		Rollback:     sourcecraft.GetBooleanInput(inputSmokeRollback),
+	}
+
+	if check.Method == "" {
+		check.Method = http.MethodGet
+	}
+
+	if check.Timeout <= 0 {
+		check.Timeout = DefaultTimeout
+	}
+
+	if expectBody := sourcecraft.GetInput(inputSmokeExpectBody); expectBody != "" {
+		check.ExpectBody, err = regexp.Compile(expectBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", inputSmokeExpectBody, err)
+		}
+	}
+
+	return check, nil
+}
+
+// ParseHeaders parses headers in the "Name: value" format, one per line.
+func ParseHeaders(lines []string) (http.Header, error) {
+	headers := make(http.Header)
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("header has wrong format: %s", line)
+		}
+
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return headers, nil
+}
+
+// ParseJSONAssertions parses JSON assertions in the "path=value" or "path" format, one per line.
+func ParseJSONAssertions(lines []string) ([]JSONAssertion, error) {
+	var assertions []JSONAssertion
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		path, value, hasValue := strings.Cut(line, "=")
+
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, fmt.Errorf("JSON assertion has wrong format: %s", line)
+		}
+
+		assertions = append(assertions, JSONAssertion{
+			Path:     path,
+			Value:    strings.TrimSpace(value),
+			HasValue: hasValue,
+		})
+	}
+
+	return assertions, nil
+}
+
+// URL returns the URL of the check for the deployment base URL.
+func (c *Check) URL(base string, query url.Values) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL %q: %w", base, err)
+	}
+
+	if c.Path != "" {
+		u = u.JoinPath(c.Path)
+	}
+
+	if len(query) > 0 {
+		values := u.Query()
+		for name, value := range query {
+			values[name] = value
+		}
+
+		u.RawQuery = values.Encode()
+	}
+
+	return u.String(), nil
+}
+
+// Run sends the request to the URL until the response passes the assertions or the attempts run out.
+// The token, if not empty, is sent as the bearer token.
+func (c *Check) Run(ctx context.Context, client *http.Client, target, token string) error {
+	attempts := max(c.Attempts, 1)
+
+	var err error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.RetryDelay):
+			}
+		}
+
+		err = c.do(ctx, client, target, token)
+		if err == nil {
+			sourcecraft.Info(fmt.Sprintf("Smoke test passed: %s %s", c.Method, target))
+
+			return nil
+		}
+
+		sourcecraft.Info(fmt.Sprintf("Smoke test attempt %d of %d failed: %v", attempt, attempts, err))
+	}
+
+	return fmt.Errorf("smoke test failed after %d attempts: %w", attempts, err)
+}
+
+// do sends the request once and verifies the response.
+func (c *Check) do(ctx context.Context, client *http.Client, target, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var body io.Reader
+	if c.Body != "" {
+		body = strings.NewReader(c.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.Method, target, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for name, values := range c.Headers {
+		req.Header[name] = values
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return c.Verify(resp.StatusCode, data)
+}
+
+// Verify checks the response status and body against the assertions.
+func (c *Check) Verify(status int, body []byte) error {
+	if c.ExpectStatus != 0 && status != c.ExpectStatus {
+		return fmt.Errorf("expected status %d, got %d: %s", c.ExpectStatus, status, truncate(body))
+	}
+
+	if c.ExpectBody != nil && !c.ExpectBody.Match(body) {
+		return fmt.Errorf("body doesn't match %q: %s", c.ExpectBody, truncate(body))
+	}
+
+	if len(c.ExpectJSON) == 0 {
+		return nil
+	}
+
+	var document any
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	err := decoder.Decode(&document)
+	if err != nil {
+		return fmt.Errorf("failed to parse body as JSON: %w", err)
+	}
+
+	var errs []error
+
+	for _, assertion := range c.ExpectJSON {
+		err = assertion.verify(document)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// verify checks the value at the path of the document.
+func (a JSONAssertion) verify(document any) error {
+	value, ok := lookup(document, a.Path)
+	if !ok {
+		return fmt.Errorf("JSON path %s not found", a.Path)
+	}
+
+	if !a.HasValue {
+		return nil
+	}
+
+	actual, ok := value.(string)
+	if !ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON path %s: %w", a.Path, err)
+		}
+
+		actual = string(data)
+	}
+
+	if actual != a.Value {
+		return fmt.Errorf("JSON path %s: expected %s, got %s", a.Path, a.Value, actual)
+	}
+
+	return nil
+}
+
+// lookup returns the value at the dot-separated path of the document.
+func lookup(document any, path string) (any, bool) {
+	value := document
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+
+			value = next
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// truncate returns the beginning of the body for error messages.
+func truncate(body []byte) string {
+	if len(body) > maxBodyLog {
+		return string(body[:maxBodyLog]) + "..."
+	}
+
+	return string(body)
+}
+
+// Token returns the IAM token of the action to send with the request, or an empty string for anonymous checks.
+func (c *Check) Token(ctx context.Context, sdk *ycsdk.SDK) (string, error) {
+	if c.Anonymous {
+		return "", nil
+	}
+
+	resp, err := sdk.CreateIAMToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create IAM token: %w", err)
+	}
+
+	return resp.IamToken, nil
+}
@@ -0,0 +1,159 @@
+package smoke_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yc-actions/sourcecraft-actions/pkg/smoke"
+)
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := smoke.ParseHeaders([]string{"Content-Type: application/json", "", "X-Trace: a:b"})
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Equal(t, "a:b", headers.Get("X-Trace"))
+
+	_, err = smoke.ParseHeaders([]string{"no colon"})
+	assert.ErrorContains(t, err, "header has wrong format")
+}
+
+func TestParseJSONAssertions(t *testing.T) {
+	assertions, err := smoke.ParseJSONAssertions([]string{"status=ok", "items.0.id", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []smoke.JSONAssertion{
+		{Path: "status", Value: "ok", HasValue: true},
+		{Path: "items.0.id"},
+	}, assertions)
+
+	_, err = smoke.ParseJSONAssertions([]string{"=ok"})
+	assert.ErrorContains(t, err, "JSON assertion has wrong format")
+}
+
+func TestCheckURL(t *testing.T) {
+	check := &smoke.Check{Path: "/health"}
+
+	target, err := check.URL("https://functions.yandexcloud.net/fn-id", url.Values{"tag": {"$latest"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://functions.yandexcloud.net/fn-id/health?tag=%24latest", target)
+}
+
+func TestCheckVerify(t *testing.T) {
+	body := []byte(`{"status":"ok","count":2,"items":[{"id":"a"}]}`)
+
+	tests := []struct {
+		name    string
+		check   smoke.Check
+		status  int
+		wantErr string
+	}{
+		{
+			name: "passes",
+			check: smoke.Check{
+				ExpectStatus: http.StatusOK,
+				ExpectBody:   regexp.MustCompile(`"status":"ok"`),
+				ExpectJSON: []smoke.JSONAssertion{
+					{Path: "status", Value: "ok", HasValue: true},
+					{Path: "count", Value: "2", HasValue: true},
+					{Path: "items.0.id"},
+				},
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:    "status",
+			check:   smoke.Check{ExpectStatus: http.StatusOK},
+			status:  http.StatusBadGateway,
+			wantErr: "expected status 200, got 502",
+		},
+		{
+			name:    "body",
+			check:   smoke.Check{ExpectBody: regexp.MustCompile(`healthy`)},
+			status:  http.StatusOK,
+			wantErr: `body doesn't match "healthy"`,
+		},
+		{
+			name:    "missing path",
+			check:   smoke.Check{ExpectJSON: []smoke.JSONAssertion{{Path: "items.1.id"}}},
+			status:  http.StatusOK,
+			wantErr: "JSON path items.1.id not found",
+		},
+		{
+			name:    "value",
+			check:   smoke.Check{ExpectJSON: []smoke.JSONAssertion{{Path: "count", Value: "3", HasValue: true}}},
+			status:  http.StatusOK,
+			wantErr: "JSON path count: expected 3, got 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check.Verify(tt.status, body)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckRun(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/invoke", r.URL.Path)
+		assert.Equal(t, "Bearer t1.token", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"ping":true}`, string(body))
+
+		// Fail the first call like a cold start
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"pong":true}`))
+	}))
+	t.Cleanup(server.Close)
+
+	check := &smoke.Check{
+		Method:       http.MethodPost,
+		Path:         "/invoke",
+		Headers:      http.Header{"Content-Type": {"application/json"}},
+		Body:         `{"ping":true}`,
+		ExpectStatus: http.StatusOK,
+		ExpectJSON:   []smoke.JSONAssertion{{Path: "pong", Value: "true", HasValue: true}},
+		Timeout:      smoke.DefaultTimeout,
+		Attempts:     2,
+	}
+
+	target, err := check.URL(server.URL, nil)
+	require.NoError(t, err)
+
+	err = check.Run(ctx, server.Client(), target, "t1.token")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	check.Attempts = 1
+	check.ExpectJSON = []smoke.JSONAssertion{{Path: "pong", Value: "false", HasValue: true}}
+
+	err = check.Run(ctx, server.Client(), target, "t1.token")
+	assert.ErrorContains(t, err, "smoke test failed after 1 attempts")
+}
@@ -12,6 +12,7 @@ import (
 const (
 	EnvSourcecraftWorkspace = "SOURCECRAFT_WORKSPACE"
 	EnvSourcecraftSHA       = "SOURCECRAFT_COMMIT_SHA"
+	EnvSourcecraftIDToken   = "SOURCECRAFT_OIDC_TOKEN"
 )
 
 // GetInput gets an input value from environment variables.
@@ -202,6 +203,11 @@ func GetSourcecraftSHA() string {
 	return os.Getenv(EnvSourcecraftSHA)
 }
 
+// GetSourcecraftIDToken gets the OIDC ID token issued to the pipeline.
+func GetSourcecraftIDToken() string {
+	return os.Getenv(EnvSourcecraftIDToken)
+}
+
 // ParseRepoOwnerFromURL extracts the repository owner from a URL string.
 func ParseRepoOwnerFromURL(repoURL string) string {
 	if repoURL == "" {
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// IsRetryable reports whether the Object Storage request failed with a transient error,
+// such as throttling, a 5xx response or a connection error, and may succeed if repeated.
+// Callers retrying uploads on such errors should set Options.SingleAttemptUploads,
+// otherwise every attempt is also retried by the SDK.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
@@ -38,63 +38,6 @@ func (_m *MockStorageService) EXPECT() *MockStorageService_Expecter {
 	return &MockStorageService_Expecter{mock: &_m.Mock}
 }
 
-// ClearBucket provides a mock function for the type MockStorageService
-func (_mock *MockStorageService) ClearBucket(ctx context.Context, bucketName string) error {
-	ret := _mock.Called(ctx, bucketName)
-
-	if len(ret) == 0 {
-		panic("no return value specified for ClearBucket")
-	}
-
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = returnFunc(ctx, bucketName)
-	} else {
-		r0 = ret.Error(0)
-	}
-	return r0
-}
-
-// MockStorageService_ClearBucket_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClearBucket'
-type MockStorageService_ClearBucket_Call struct {
-	*mock.Call
-}
-
-// ClearBucket is a helper method to define mock.On call
-//   - ctx context.Context
-//   - bucketName string
-func (_e *MockStorageService_Expecter) ClearBucket(ctx interface{}, bucketName interface{}) *MockStorageService_ClearBucket_Call {
-	return &MockStorageService_ClearBucket_Call{Call: _e.mock.On("ClearBucket", ctx, bucketName)}
-}
-
-func (_c *MockStorageService_ClearBucket_Call) Run(run func(ctx context.Context, bucketName string)) *MockStorageService_ClearBucket_Call {
-	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 context.Context
-		if args[0] != nil {
-			arg0 = args[0].(context.Context)
-		}
-		var arg1 string
-		if args[1] != nil {
-			arg1 = args[1].(string)
-		}
-		run(
-			arg0,
-			arg1,
-		)
-	})
-	return _c
-}
-
-func (_c *MockStorageService_ClearBucket_Call) Return(err error) *MockStorageService_ClearBucket_Call {
-	_c.Call.Return(err)
-	return _c
-}
-
-func (_c *MockStorageService_ClearBucket_Call) RunAndReturn(run func(ctx context.Context, bucketName string) error) *MockStorageService_ClearBucket_Call {
-	_c.Call.Return(run)
-	return _c
-}
-
 // DeleteObjects provides a mock function for the type MockStorageService
 func (_mock *MockStorageService) DeleteObjects(ctx context.Context, bucketName string, objectKeys []string) (int, error) {
 	ret := _mock.Called(ctx, bucketName, objectKeys)
@@ -241,40 +184,114 @@ func (_c *MockStorageService_GetObject_Call) RunAndReturn(run func(ctx context.C
 	return _c
 }
 
+// HeadObject provides a mock function for the type MockStorageService
+func (_mock *MockStorageService) HeadObject(ctx context.Context, bucketName string, objectName string) (*storage.ObjectInfo, error) {
+	ret := _mock.Called(ctx, bucketName, objectName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HeadObject")
+	}
+
+	var r0 *storage.ObjectInfo
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*storage.ObjectInfo, error)); ok {
+		return returnFunc(ctx, bucketName, objectName)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *storage.ObjectInfo); ok {
+		r0 = returnFunc(ctx, bucketName, objectName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*storage.ObjectInfo)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, bucketName, objectName)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockStorageService_HeadObject_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HeadObject'
+type MockStorageService_HeadObject_Call struct {
+	*mock.Call
+}
+
+// HeadObject is a helper method to define mock.On call
+//   - ctx context.Context
+//   - bucketName string
+//   - objectName string
+func (_e *MockStorageService_Expecter) HeadObject(ctx interface{}, bucketName interface{}, objectName interface{}) *MockStorageService_HeadObject_Call {
+	return &MockStorageService_HeadObject_Call{Call: _e.mock.On("HeadObject", ctx, bucketName, objectName)}
+}
+
+func (_c *MockStorageService_HeadObject_Call) Run(run func(ctx context.Context, bucketName string, objectName string)) *MockStorageService_HeadObject_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockStorageService_HeadObject_Call) Return(objectInfo *storage.ObjectInfo, err error) *MockStorageService_HeadObject_Call {
+	_c.Call.Return(objectInfo, err)
+	return _c
+}
+
+func (_c *MockStorageService_HeadObject_Call) RunAndReturn(run func(ctx context.Context, bucketName string, objectName string) (*storage.ObjectInfo, error)) *MockStorageService_HeadObject_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ListObjects provides a mock function for the type MockStorageService
-func (_mock *MockStorageService) ListObjects(ctx context.Context, bucketName string, maxKeys int32, continuationToken string) ([]string, string, bool, error) {
-	ret := _mock.Called(ctx, bucketName, maxKeys, continuationToken)
+func (_mock *MockStorageService) ListObjects(ctx context.Context, bucketName string, prefix string, maxKeys int32, continuationToken string) ([]storage.ObjectInfo, string, bool, error) {
+	ret := _mock.Called(ctx, bucketName, prefix, maxKeys, continuationToken)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ListObjects")
 	}
 
-	var r0 []string
+	var r0 []storage.ObjectInfo
 	var r1 string
 	var r2 bool
 	var r3 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int32, string) ([]string, string, bool, error)); ok {
-		return returnFunc(ctx, bucketName, maxKeys, continuationToken)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, int32, string) ([]storage.ObjectInfo, string, bool, error)); ok {
+		return returnFunc(ctx, bucketName, prefix, maxKeys, continuationToken)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int32, string) []string); ok {
-		r0 = returnFunc(ctx, bucketName, maxKeys, continuationToken)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, int32, string) []storage.ObjectInfo); ok {
+		r0 = returnFunc(ctx, bucketName, prefix, maxKeys, continuationToken)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]string)
+			r0 = ret.Get(0).([]storage.ObjectInfo)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string, int32, string) string); ok {
-		r1 = returnFunc(ctx, bucketName, maxKeys, continuationToken)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, int32, string) string); ok {
+		r1 = returnFunc(ctx, bucketName, prefix, maxKeys, continuationToken)
 	} else {
 		r1 = ret.Get(1).(string)
 	}
-	if returnFunc, ok := ret.Get(2).(func(context.Context, string, int32, string) bool); ok {
-		r2 = returnFunc(ctx, bucketName, maxKeys, continuationToken)
+	if returnFunc, ok := ret.Get(2).(func(context.Context, string, string, int32, string) bool); ok {
+		r2 = returnFunc(ctx, bucketName, prefix, maxKeys, continuationToken)
 	} else {
 		r2 = ret.Get(2).(bool)
 	}
-	if returnFunc, ok := ret.Get(3).(func(context.Context, string, int32, string) error); ok {
-		r3 = returnFunc(ctx, bucketName, maxKeys, continuationToken)
+	if returnFunc, ok := ret.Get(3).(func(context.Context, string, string, int32, string) error); ok {
+		r3 = returnFunc(ctx, bucketName, prefix, maxKeys, continuationToken)
 	} else {
 		r3 = ret.Error(3)
 	}
@@ -289,13 +306,14 @@ type MockStorageService_ListObjects_Call struct {
 // ListObjects is a helper method to define mock.On call
 //   - ctx context.Context
 //   - bucketName string
+//   - prefix string
 //   - maxKeys int32
 //   - continuationToken string
-func (_e *MockStorageService_Expecter) ListObjects(ctx interface{}, bucketName interface{}, maxKeys interface{}, continuationToken interface{}) *MockStorageService_ListObjects_Call {
-	return &MockStorageService_ListObjects_Call{Call: _e.mock.On("ListObjects", ctx, bucketName, maxKeys, continuationToken)}
+func (_e *MockStorageService_Expecter) ListObjects(ctx interface{}, bucketName interface{}, prefix interface{}, maxKeys interface{}, continuationToken interface{}) *MockStorageService_ListObjects_Call {
+	return &MockStorageService_ListObjects_Call{Call: _e.mock.On("ListObjects", ctx, bucketName, prefix, maxKeys, continuationToken)}
 }
 
-func (_c *MockStorageService_ListObjects_Call) Run(run func(ctx context.Context, bucketName string, maxKeys int32, continuationToken string)) *MockStorageService_ListObjects_Call {
+func (_c *MockStorageService_ListObjects_Call) Run(run func(ctx context.Context, bucketName string, prefix string, maxKeys int32, continuationToken string)) *MockStorageService_ListObjects_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -305,30 +323,35 @@ func (_c *MockStorageService_ListObjects_Call) Run(run func(ctx context.Context,
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
-		var arg2 int32
+		var arg2 string
 		if args[2] != nil {
-			arg2 = args[2].(int32)
+			arg2 = args[2].(string)
 		}
-		var arg3 string
+		var arg3 int32
 		if args[3] != nil {
-			arg3 = args[3].(string)
+			arg3 = args[3].(int32)
+		}
+		var arg4 string
+		if args[4] != nil {
+			arg4 = args[4].(string)
 		}
 		run(
 			arg0,
 			arg1,
 			arg2,
 			arg3,
+			arg4,
 		)
 	})
 	return _c
 }
 
-func (_c *MockStorageService_ListObjects_Call) Return(strings []string, s string, b bool, err error) *MockStorageService_ListObjects_Call {
-	_c.Call.Return(strings, s, b, err)
+func (_c *MockStorageService_ListObjects_Call) Return(objectInfos []storage.ObjectInfo, s string, b bool, err error) *MockStorageService_ListObjects_Call {
+	_c.Call.Return(objectInfos, s, b, err)
 	return _c
 }
 
-func (_c *MockStorageService_ListObjects_Call) RunAndReturn(run func(ctx context.Context, bucketName string, maxKeys int32, continuationToken string) ([]string, string, bool, error)) *MockStorageService_ListObjects_Call {
+func (_c *MockStorageService_ListObjects_Call) RunAndReturn(run func(ctx context.Context, bucketName string, prefix string, maxKeys int32, continuationToken string) ([]storage.ObjectInfo, string, bool, error)) *MockStorageService_ListObjects_Call {
 	_c.Call.Return(run)
 	return _c
 }
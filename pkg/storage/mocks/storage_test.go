@@ -21,12 +21,11 @@ func TestStorageServiceMock(t *testing.T) {
 		Return(mockObject, nil)
 	mockService.EXPECT().PutObject(mock.Anything, mockObject).Return(nil)
 	mockService.EXPECT().
-		ListObjects(mock.Anything, "test-bucket", int32(10), "").
-		Return([]string{"test-object"}, "", false, nil)
+		ListObjects(mock.Anything, "test-bucket", "", int32(10), "").
+		Return([]storage.ObjectInfo{{Key: "test-object", ETag: "etag", Size: 12}}, "", false, nil)
 	mockService.EXPECT().
 		DeleteObjects(mock.Anything, "test-bucket", []string{"test-object"}).
 		Return(1, nil)
-	mockService.EXPECT().ClearBucket(mock.Anything, "test-bucket").Return(nil)
 
 	// Test the mock
 	ctx := context.Background()
@@ -43,9 +42,9 @@ func TestStorageServiceMock(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test ListObjects
-	keys, token, truncated, err := mockService.ListObjects(ctx, "test-bucket", 10, "")
+	objects, token, truncated, err := mockService.ListObjects(ctx, "test-bucket", "", 10, "")
 	assert.NoError(t, err)
-	assert.Equal(t, []string{"test-object"}, keys)
+	assert.Equal(t, []storage.ObjectInfo{{Key: "test-object", ETag: "etag", Size: 12}}, objects)
 	assert.Equal(t, "", token)
 	assert.False(t, truncated)
 
@@ -53,8 +52,4 @@ func TestStorageServiceMock(t *testing.T) {
 	deleted, err := mockService.DeleteObjects(ctx, "test-bucket", []string{"test-object"})
 	assert.NoError(t, err)
 	assert.Equal(t, 1, deleted)
-
-	// Test ClearBucket
-	err = mockService.ClearBucket(ctx, "test-bucket")
-	assert.NoError(t, err)
 }
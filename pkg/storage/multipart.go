@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ProgressFunc reports the bytes of the object uploaded so far. Total is -1 if the size is unknown.
+type ProgressFunc func(uploaded, total int64)
+
+// part is a chunk of the object read for upload.
+type part struct {
+	number int32
+	data   []byte
+}
+
+// partSize returns the part size that keeps an object of the given size within the parts limit.
+func (s *StorageServiceImpl) partSize(size int64) int64 {
+	partSize := s.options.PartSize
+	if size > partSize*maxParts {
+		partSize = (size + maxParts - 1) / maxParts
+	}
+
+	return partSize
+}
+
+// putMultipart uploads the object in parts, PartConcurrency parts at a time.
+// The upload is aborted if any part fails so no incomplete parts are left in the bucket.
+func (s *StorageServiceImpl) putMultipart(ctx context.Context, object *StorageObject, body io.Reader) error {
+	created, err := s.s3Client.CreateMultipartUpload(ctx, newCreateMultipartUploadInput(object), s.uploadOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	completed, err := s.uploadParts(ctx, object, body, created.UploadId)
+	if err == nil {
+		_, err = s.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(object.BucketName),
+			Key:             aws.String(object.ObjectName),
+			UploadId:        created.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+		}, s.uploadOptions()...)
+		if err == nil {
+			return nil
+		}
+
+		err = fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	// Abort even if the context is canceled, otherwise the uploaded parts are kept and billed
+	_, abortErr := s.s3Client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(object.BucketName),
+		Key:      aws.String(object.ObjectName),
+		UploadId: created.UploadId,
+	})
+	if abortErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to abort multipart upload: %w", abortErr))
+	}
+
+	return err
+}
+
+// uploadParts reads the body part by part and uploads the parts in parallel.
+func (s *StorageServiceImpl) uploadParts(
+	ctx context.Context,
+	object *StorageObject,
+	body io.Reader,
+	uploadID *string,
+) ([]types.CompletedPart, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	total := object.Size
+	if total <= 0 {
+		total = -1
+	}
+
+	partSize := s.partSize(object.Size)
+	parts := make(chan part)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		completed []types.CompletedPart
+		uploaded  int64
+		errs      []error
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+		cancel()
+	}
+
+	for range s.options.PartConcurrency {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for p := range parts {
+				output, err := s.s3Client.UploadPart(ctx, &s3.UploadPartInput{
+					Bucket:        aws.String(object.BucketName),
+					Key:           aws.String(object.ObjectName),
+					UploadId:      uploadID,
+					PartNumber:    aws.Int32(p.number),
+					Body:          bytes.NewReader(p.data),
+					ContentLength: aws.Int64(int64(len(p.data))),
+				}, s.uploadOptions()...)
+				if err != nil {
+					fail(fmt.Errorf("failed to upload part %d: %w", p.number, err))
+
+					continue
+				}
+
+				mu.Lock()
+				completed = append(completed, types.CompletedPart{ETag: output.ETag, PartNumber: aws.Int32(p.number)})
+				uploaded += int64(len(p.data))
+
+				if object.OnProgress != nil {
+					object.OnProgress(uploaded, total)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Read the body sequentially and hand the parts to the workers
+	for number := int32(1); ctx.Err() == nil; number++ {
+		if number > maxParts {
+			fail(fmt.Errorf("object exceeds %d parts of %d bytes", maxParts, partSize))
+
+			break
+		}
+
+		data := make([]byte, partSize)
+
+		n, err := io.ReadFull(body, data)
+		if n > 0 {
+			select {
+			case parts <- part{number: number, data: data[:n]}:
+			case <-ctx.Done():
+			}
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+
+		if err != nil {
+			fail(fmt.Errorf("failed to read object: %w", err))
+
+			break
+		}
+	}
+
+	close(parts)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(completed, func(a, b types.CompletedPart) int {
+		return int(aws.ToInt32(a.PartNumber) - aws.ToInt32(b.PartNumber))
+	})
+
+	return completed, nil
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a minimal Object Storage stand-in supporting single and multipart uploads.
+type fakeS3 struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	parts      map[int][]byte
+	failPart   int
+	singlePuts int
+	completed  bool
+	aborted    bool
+	// headers are the headers of the last request creating an object or a multipart upload
+	headers http.Header
+	// failPuts is the number of first single put requests failing with a transient error
+	failPuts    int
+	putAttempts int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.headers = r.Header.Clone()
+
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		var number int
+		_, _ = fmt.Sscan(query.Get("partNumber"), &number)
+
+		if number == f.failPart {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>denied</Message></Error>`)
+
+			return
+		}
+
+		f.parts[number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		numbers := make([]int, 0, len(f.parts))
+		for number := range f.parts {
+			numbers = append(numbers, number)
+		}
+
+		sort.Ints(numbers)
+
+		var object []byte
+		for _, number := range numbers {
+			object = append(object, f.parts[number]...)
+		}
+
+		f.objects[r.URL.Path] = object
+		f.completed = true
+
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"etag"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		f.aborted = true
+
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.putAttempts++
+		if f.putAttempts <= f.failPuts {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `<Error><Code>SlowDown</Code><Message>slow down</Message></Error>`)
+
+			return
+		}
+
+		f.headers = r.Header.Clone()
+		f.objects[r.URL.Path] = body
+		f.singlePuts++
+	case r.Method == http.MethodHead:
+		object, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		// The headers of the last created object are returned with it
+		for name, values := range f.headers {
+			if strings.HasPrefix(name, "X-Amz-Meta-") || name == "Content-Encoding" {
+				w.Header()[name] = values
+			}
+		}
+
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, md5.Sum(object)))
+		w.Header().Set("Content-Length", strconv.Itoa(len(object)))
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// newTestService starts the fake Object Storage and returns a service uploading to it.
+func newTestService(t *testing.T, options Options) (*StorageServiceImpl, *fakeS3) {
+	t.Helper()
+
+	fake := &fakeS3{objects: make(map[string][]byte), parts: make(map[int][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := s3.New(s3.Options{
+		Region:                     "ru-central1",
+		BaseEndpoint:               aws.String(server.URL),
+		UsePathStyle:               true,
+		Credentials:                aws.AnonymousCredentials{},
+		Retryer:                    newTestRetryer(),
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	})
+
+	return newStorageService(client, options), fake
+}
+
+// newTestRetryer returns the SDK retryer retrying transient errors three times without delay.
+func newTestRetryer() aws.Retryer {
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		o.MaxAttempts = 3
+		o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) {
+			return 0, nil
+		})
+	})
+}
+
+func TestPutObjectMultipart(t *testing.T) {
+	ctx := context.Background()
+	options := Options{MultipartThreshold: 2 * minPartSize, PartSize: minPartSize, PartConcurrency: 3}
+	content := bytes.Repeat([]byte("0123456789"), int(minPartSize)*3/10+7)
+
+	t.Run("small object in single request", func(t *testing.T) {
+		service, fake := newTestService(t, options)
+
+		object := NewStorageObjectFromString("bucket", "small.txt", "content")
+
+		err := service.PutObject(ctx, object)
+		require.NoError(t, err)
+		assert.Equal(t, 1, fake.singlePuts)
+		assert.Equal(t, []byte("content"), fake.objects["/bucket/small.txt"])
+	})
+
+	for _, size := range []int64{int64(len(content)), 0} {
+		t.Run(fmt.Sprintf("large object of size %d", size), func(t *testing.T) {
+			service, fake := newTestService(t, options)
+
+			var progress []int64
+
+			object := NewStorageObject("bucket", "large.bin", io.NopCloser(bytes.NewReader(content)))
+			object.Size = size
+			object.OnProgress = func(uploaded, _ int64) {
+				progress = append(progress, uploaded)
+			}
+
+			err := service.PutObject(ctx, object)
+			require.NoError(t, err)
+			assert.True(t, fake.completed)
+			assert.Zero(t, fake.singlePuts)
+			assert.Len(t, fake.parts, 4)
+			assert.Equal(t, content, fake.objects["/bucket/large.bin"])
+			assert.Len(t, progress, 4)
+			assert.Equal(t, int64(len(content)), progress[len(progress)-1])
+		})
+	}
+
+	t.Run("failed part aborts upload", func(t *testing.T) {
+		service, fake := newTestService(t, options)
+		fake.failPart = 2
+
+		object := NewStorageObject("bucket", "large.bin", io.NopCloser(bytes.NewReader(content)))
+
+		err := service.PutObject(ctx, object)
+		assert.ErrorContains(t, err, "failed to upload part 2")
+		assert.True(t, fake.aborted)
+		assert.False(t, fake.completed)
+	})
+}
+
+func TestPutObjectRetries(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name             string
+		singleAttempt    bool
+		failPuts         int
+		expectedAttempts int
+		expectedErr      bool
+	}{
+		{name: "retried by SDK", failPuts: 2, expectedAttempts: 3},
+		{name: "single attempt", singleAttempt: true, failPuts: 2, expectedAttempts: 1, expectedErr: true},
+		{name: "single attempt succeeds", singleAttempt: true, expectedAttempts: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options := DefaultOptions()
+			options.SingleAttemptUploads = tc.singleAttempt
+
+			service, fake := newTestService(t, options)
+			fake.failPuts = tc.failPuts
+
+			err := service.PutObject(ctx, NewStorageObjectFromString("bucket", "small.txt", "content"))
+
+			if tc.expectedErr {
+				require.Error(t, err)
+				assert.True(t, IsRetryable(err))
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedAttempts, fake.putAttempts)
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	assert.NoError(t, DefaultOptions().Validate())
+	assert.Error(t, Options{MultipartThreshold: minPartSize, PartSize: minPartSize - 1, PartConcurrency: 1}.Validate())
+}
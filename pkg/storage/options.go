@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/yc-actions/sourcecraft-actions/pkg/memory"
+	"github.com/yc-actions/sourcecraft-actions/pkg/sourcecraft"
+)
+
+// Multipart upload inputs shared by the actions uploading to Object Storage.
+const (
+	inputMultipartThreshold   = "MULTIPART_THRESHOLD"
+	inputMultipartPartSize    = "MULTIPART_PART_SIZE"
+	inputMultipartConcurrency = "MULTIPART_CONCURRENCY"
+)
+
+// Multipart upload defaults and Object Storage limits.
+const (
+	DefaultMultipartThreshold = 64 * memory.MB
+	DefaultPartSize           = 16 * memory.MB
+	DefaultPartConcurrency    = 4
+
+	minPartSize = 5 * memory.MB
+	maxParts    = 10000
+)
+
+// Options configures how the service uploads objects.
+type Options struct {
+	// MultipartThreshold is the object size from which multipart upload is used.
+	MultipartThreshold int64
+	// PartSize is the size of a part of multipart upload.
+	PartSize int64
+	// PartConcurrency is the number of parts uploaded in parallel.
+	PartConcurrency int
+	// SingleAttemptUploads disables the SDK retries of the upload requests
+	// for callers that retry failed uploads themselves.
+	SingleAttemptUploads bool
+}
+
+// DefaultOptions returns the default upload options.
+func DefaultOptions() Options {
+	return Options{
+		MultipartThreshold: DefaultMultipartThreshold,
+		PartSize:           DefaultPartSize,
+		PartConcurrency:    DefaultPartConcurrency,
+	}
+}
+
+// InputOptions returns the upload options set by the MULTIPART_* inputs, falling back to the defaults.
+func InputOptions() (Options, error) {
+	options := DefaultOptions()
+
+	var err error
+
+	if threshold := sourcecraft.GetInput(inputMultipartThreshold); threshold != "" {
+		options.MultipartThreshold, err = memory.ParseMemory(threshold)
+		if err != nil {
+			return options, fmt.Errorf("failed to parse multipart threshold: %w", err)
+		}
+	}
+
+	if partSize := sourcecraft.GetInput(inputMultipartPartSize); partSize != "" {
+		options.PartSize, err = memory.ParseMemory(partSize)
+		if err != nil {
+			return options, fmt.Errorf("failed to parse multipart part size: %w", err)
+		}
+	}
+
+	options.PartConcurrency = sourcecraft.GetIntInput(inputMultipartConcurrency, DefaultPartConcurrency)
+
+	return options, options.Validate()
+}
+
+// Validate checks the options against Object Storage limits.
+func (o Options) Validate() error {
+	if o.PartSize < minPartSize {
+		return fmt.Errorf("multipart part size must be at least 5Mb")
+	}
+
+	if o.MultipartThreshold < o.PartSize {
+		return fmt.Errorf("multipart threshold must not be less than part size")
+	}
+
+	if o.PartConcurrency < 1 {
+		return fmt.Errorf("multipart concurrency must be positive")
+	}
+
+	return nil
+}
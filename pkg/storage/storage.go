@@ -1,9 +1,13 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -15,29 +19,30 @@ import (
 // StorageService defines the interface for interacting with Yandex Cloud Object Storage.
 type StorageService interface {
 	GetObject(ctx context.Context, bucketName, objectName string) (*StorageObject, error)
+	HeadObject(ctx context.Context, bucketName, objectName string) (*ObjectInfo, error)
 	PutObject(ctx context.Context, object *StorageObject) error
 	ListObjects(
 		ctx context.Context,
-		bucketName string,
+		bucketName, prefix string,
 		maxKeys int32,
 		continuationToken string,
-	) ([]string, string, bool, error)
+	) ([]ObjectInfo, string, bool, error)
 	DeleteObjects(ctx context.Context, bucketName string, objectKeys []string) (int, error)
-	ClearBucket(ctx context.Context, bucketName string) error
 }
 
 // StorageServiceImpl implements the StorageService interface using direct HTTP requests.
 type StorageServiceImpl struct {
 	s3Client *s3.Client
+	options  Options
 }
 
 // NewStorageService creates a new StorageService with default options.
 func NewStorageService(sdk *ycsdk.SDK) *StorageServiceImpl {
-	return NewStorageServiceWithOptions(sdk)
+	return NewStorageServiceWithOptions(sdk, DefaultOptions())
 }
 
 // NewStorageServiceWithOptions creates a new S3StorageService with the specified options.
-func NewStorageServiceWithOptions(sdk *ycsdk.SDK) *StorageServiceImpl {
+func NewStorageServiceWithOptions(sdk *ycsdk.SDK, options Options) *StorageServiceImpl {
 	// Create S3 client
 	s3Client := s3.New(s3.Options{
 		Region:             "ru-central1",
@@ -46,11 +51,15 @@ func NewStorageServiceWithOptions(sdk *ycsdk.SDK) *StorageServiceImpl {
 		swapAuth(sdk),
 	)
 
-	service := &StorageServiceImpl{
+	return newStorageService(s3Client, options)
+}
+
+// newStorageService creates a StorageServiceImpl using the given S3 client.
+func newStorageService(s3Client *s3.Client, options Options) *StorageServiceImpl {
+	return &StorageServiceImpl{
 		s3Client: s3Client,
+		options:  options,
 	}
-
-	return service
 }
 
 // GetObject retrieves an object from Yandex Cloud Object Storage.
@@ -68,46 +77,149 @@ func (s *StorageServiceImpl) GetObject(
 		return nil, err
 	}
 
-	return NewStorageObject(bucketName, objectName, object.Body), nil
+	result := NewStorageObject(bucketName, objectName, object.Body)
+	result.Size = aws.ToInt64(object.ContentLength)
+	result.ContentType = aws.ToString(object.ContentType)
+	result.CacheControl = aws.ToString(object.CacheControl)
+	result.ContentEncoding = aws.ToString(object.ContentEncoding)
+	result.ContentDisposition = aws.ToString(object.ContentDisposition)
+	result.ContentLanguage = aws.ToString(object.ContentLanguage)
+	result.Expires = object.Expires
+	result.StorageClass = string(object.StorageClass)
+	result.Metadata = object.Metadata
+
+	return result, nil
+}
+
+// HeadObject returns the ETag, size, content encoding and user metadata of an object without its content.
+func (s *StorageServiceImpl) HeadObject(
+	ctx context.Context,
+	bucketName, objectName string,
+) (*ObjectInfo, error) {
+	output, err := s.s3Client.HeadObject(
+		ctx,
+		&s3.HeadObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectName),
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s: %w", objectName, err)
+	}
+
+	return &ObjectInfo{
+		Key:             objectName,
+		ETag:            strings.Trim(aws.ToString(output.ETag), `"`),
+		Size:            aws.ToInt64(output.ContentLength),
+		ContentEncoding: aws.ToString(output.ContentEncoding),
+		Metadata:        output.Metadata,
+	}, nil
+}
+
+// optionalString returns nil for an empty string so the header is not sent.
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return aws.String(value)
+}
+
+// newPutObjectInput builds the request uploading the object with its headers and metadata.
+func newPutObjectInput(object *StorageObject, body io.Reader) *s3.PutObjectInput {
+	return &s3.PutObjectInput{
+		Bucket:             aws.String(object.BucketName),
+		Key:                aws.String(object.ObjectName),
+		Body:               body,
+		ContentType:        optionalString(object.ContentType),
+		CacheControl:       optionalString(object.CacheControl),
+		ContentEncoding:    optionalString(object.ContentEncoding),
+		ContentDisposition: optionalString(object.ContentDisposition),
+		ContentLanguage:    optionalString(object.ContentLanguage),
+		Expires:            object.Expires,
+		StorageClass:       types.StorageClass(object.StorageClass),
+		ACL:                types.ObjectCannedACL(object.ACL),
+		Metadata:           object.Metadata,
+	}
+}
+
+// newCreateMultipartUploadInput builds the request starting a multipart upload of the object
+// with its headers and metadata.
+func newCreateMultipartUploadInput(object *StorageObject) *s3.CreateMultipartUploadInput {
+	return &s3.CreateMultipartUploadInput{
+		Bucket:             aws.String(object.BucketName),
+		Key:                aws.String(object.ObjectName),
+		ContentType:        optionalString(object.ContentType),
+		CacheControl:       optionalString(object.CacheControl),
+		ContentEncoding:    optionalString(object.ContentEncoding),
+		ContentDisposition: optionalString(object.ContentDisposition),
+		ContentLanguage:    optionalString(object.ContentLanguage),
+		Expires:            object.Expires,
+		StorageClass:       types.StorageClass(object.StorageClass),
+		ACL:                types.ObjectCannedACL(object.ACL),
+		Metadata:           object.Metadata,
+	}
 }
 
 // PutObject uploads an object to Yandex Cloud Object Storage.
+// Objects of MultipartThreshold bytes or more are uploaded in parts. If the object size
+// is unknown, up to MultipartThreshold bytes are buffered to choose the upload method.
 func (s *StorageServiceImpl) PutObject(ctx context.Context, object *StorageObject) error {
-	metadata := make(map[string]string)
+	body := object.GetReader()
 
-	if object.ContentType != "" {
-		metadata["Content-Type"] = object.ContentType
+	switch {
+	case object.Size >= s.options.MultipartThreshold:
+		return s.putMultipart(ctx, object, body)
+	case object.Size > 0:
+		return s.putSingle(ctx, object, body)
 	}
 
-	if object.CacheControl != "" {
-		metadata["Cache-Control"] = object.CacheControl
+	head := new(bytes.Buffer)
+
+	_, err := io.CopyN(head, body, s.options.MultipartThreshold)
+	if errors.Is(err, io.EOF) {
+		return s.putSingle(ctx, object, bytes.NewReader(head.Bytes()))
 	}
 
-	// Get IAM token from cache or create a new one
-	_, err := s.s3Client.PutObject(
-		ctx,
-		&s3.PutObjectInput{
-			Bucket:   aws.String(object.BucketName),
-			Key:      aws.String(object.ObjectName),
-			Body:     object.GetReader(),
-			Metadata: metadata,
-		})
+	if err != nil {
+		return fmt.Errorf("failed to read object: %w", err)
+	}
+
+	return s.putMultipart(ctx, object, io.MultiReader(head, body))
+}
+
+// putSingle uploads the object with a single request.
+func (s *StorageServiceImpl) putSingle(ctx context.Context, object *StorageObject, body io.Reader) error {
+	_, err := s.s3Client.PutObject(ctx, newPutObjectInput(object, body), s.uploadOptions()...)
 
 	return err
 }
 
-// ListObjects lists objects in a bucket.
+// uploadOptions returns the per-request options of the upload requests.
+func (s *StorageServiceImpl) uploadOptions() []func(*s3.Options) {
+	if !s.options.SingleAttemptUploads {
+		return nil
+	}
+
+	return []func(*s3.Options){func(o *s3.Options) { o.RetryMaxAttempts = 1 }}
+}
+
+// ListObjects lists objects in a bucket whose keys start with the prefix.
 func (s *StorageServiceImpl) ListObjects(
 	ctx context.Context,
-	bucketName string,
+	bucketName, prefix string,
 	maxKeys int32,
 	continuationToken string,
-) ([]string, string, bool, error) {
+) ([]ObjectInfo, string, bool, error) {
 	// Create list objects input
 	input := &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucketName),
 	}
 
+	// Set prefix if specified
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
 	// Set max keys if specified
 	if maxKeys > 0 {
 		input.MaxKeys = aws.Int32(maxKeys)
@@ -124,12 +236,16 @@ func (s *StorageServiceImpl) ListObjects(
 		return nil, "", false, fmt.Errorf("failed to list objects: %w", err)
 	}
 
-	// Extract object keys
-	var keys []string
+	// Extract object keys, ETags and sizes
+	var objects []ObjectInfo
 
 	for _, obj := range output.Contents {
 		if obj.Key != nil {
-			keys = append(keys, *obj.Key)
+			objects = append(objects, ObjectInfo{
+				Key:  *obj.Key,
+				ETag: strings.Trim(aws.ToString(obj.ETag), `"`),
+				Size: aws.ToInt64(obj.Size),
+			})
 		}
 	}
 
@@ -145,7 +261,7 @@ func (s *StorageServiceImpl) ListObjects(
 		isTruncated = *output.IsTruncated
 	}
 
-	return keys, nextContinuationToken, isTruncated, nil
+	return objects, nextContinuationToken, isTruncated, nil
 }
 
 // DeleteObjects deletes objects from a bucket.
@@ -189,52 +305,6 @@ func (s *StorageServiceImpl) DeleteObjects(
 	return 0, nil
 }
 
-// ClearBucket clears all objects from a bucket.
-func (s *StorageServiceImpl) ClearBucket(ctx context.Context, bucketName string) error {
-	// Set max keys to 1000 (default and maximum)
-	maxKeys := int32(1000)
-
-	// Initialize variables
-	var continuationToken string
-
-	isTruncated := true
-	totalDeleted := 0
-
-	// Loop until all objects are deleted
-	for isTruncated {
-		// List objects
-		keys, nextContinuationToken, truncated, err := s.ListObjects(
-			ctx,
-			bucketName,
-			maxKeys,
-			continuationToken,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to list objects: %w", err)
-		}
-
-		// Check if there are any objects to delete
-		if len(keys) == 0 {
-			break
-		}
-
-		// Update variables for next iteration
-		isTruncated = truncated
-		continuationToken = nextContinuationToken
-
-		// Delete objects
-		deleted, err := s.DeleteObjects(ctx, bucketName, keys)
-		if err != nil {
-			return fmt.Errorf("failed to delete objects: %w", err)
-		}
-
-		// Update total deleted count
-		totalDeleted += deleted
-	}
-
-	return nil
-}
-
 type resolverV2 struct {
 	// you could inject additional application context here as well
 }
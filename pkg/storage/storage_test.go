@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutObjectHeaders(t *testing.T) {
+	expires := time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	newObject := func(content []byte) *StorageObject {
+		object := NewStorageObject("bucket", "index.html", io.NopCloser(bytes.NewReader(content)))
+		object.ContentType = "text/html; charset=utf-8"
+		object.CacheControl = "public, max-age=3600"
+		object.ContentEncoding = "gzip"
+		object.ContentDisposition = "inline"
+		object.ContentLanguage = "en"
+		object.Expires = &expires
+		object.StorageClass = "COLD"
+		object.ACL = "public-read"
+		object.Metadata = map[string]string{"Commit": "abc"}
+
+		return object
+	}
+
+	options := Options{MultipartThreshold: 2 * minPartSize, PartSize: minPartSize, PartConcurrency: 1}
+
+	testCases := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "single request", content: []byte("<html></html>")},
+		{name: "multipart upload", content: bytes.Repeat([]byte("a"), int(3*minPartSize))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, fake := newTestService(t, options)
+
+			err := service.PutObject(context.Background(), newObject(tc.content))
+			require.NoError(t, err)
+
+			assert.Equal(t, "text/html; charset=utf-8", fake.headers.Get("Content-Type"))
+			assert.Equal(t, "public, max-age=3600", fake.headers.Get("Cache-Control"))
+			assert.Equal(t, "gzip", fake.headers.Get("Content-Encoding"))
+			assert.Equal(t, "inline", fake.headers.Get("Content-Disposition"))
+			assert.Equal(t, "en", fake.headers.Get("Content-Language"))
+			assert.Equal(t, expires.Format(http.TimeFormat), fake.headers.Get("Expires"))
+			assert.Equal(t, "COLD", fake.headers.Get("X-Amz-Storage-Class"))
+			assert.Equal(t, "public-read", fake.headers.Get("X-Amz-Acl"))
+			assert.Equal(t, "abc", fake.headers.Get("X-Amz-Meta-Commit"))
+			assert.Empty(t, fake.headers.Get("X-Amz-Meta-Content-Type"))
+		})
+	}
+}
+
+func TestHeadObject(t *testing.T) {
+	service, _ := newTestService(t, Options{MultipartThreshold: minPartSize, PartSize: minPartSize, PartConcurrency: 1})
+
+	object := NewStorageObjectFromString("bucket", "index.html", "compressed")
+	object.ContentEncoding = "gzip"
+	object.Metadata = map[string]string{"source-md5": "abc"}
+
+	require.NoError(t, service.PutObject(context.Background(), object))
+
+	info, err := service.HeadObject(context.Background(), "bucket", "index.html")
+	require.NoError(t, err)
+
+	sum := md5.Sum([]byte("compressed"))
+	assert.Equal(t, "index.html", info.Key)
+	assert.Equal(t, hex.EncodeToString(sum[:]), info.ETag)
+	assert.Equal(t, int64(len("compressed")), info.Size)
+	assert.Equal(t, "gzip", info.ContentEncoding)
+	assert.Equal(t, map[string]string{"source-md5": "abc"}, info.Metadata)
+
+	_, err = service.HeadObject(context.Background(), "bucket", "missing.html")
+	assert.Error(t, err)
+}
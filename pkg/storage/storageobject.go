@@ -5,15 +5,43 @@ import (
 	"io"
 	"io/ioutil"
 	"strings"
+	"time"
 )
 
 // StorageObject represents an object in Yandex Cloud Object Storage.
+// Header fields left empty are not sent.
 type StorageObject struct {
-	BucketName   string
-	ObjectName   string
-	reader       io.ReadCloser
-	CacheControl string
-	ContentType  string
+	BucketName         string
+	ObjectName         string
+	reader             io.ReadCloser
+	CacheControl       string
+	ContentType        string
+	ContentEncoding    string
+	ContentDisposition string
+	ContentLanguage    string
+	Expires            *time.Time
+	// StorageClass is STANDARD, COLD or ICE.
+	StorageClass string
+	// ACL is a canned ACL, e.g. public-read.
+	ACL string
+	// Metadata is the user metadata sent as x-amz-meta-* headers.
+	Metadata map[string]string
+	// Size is the content length in bytes, 0 if unknown.
+	Size int64
+	// OnProgress is called after each uploaded part of a multipart upload.
+	OnProgress ProgressFunc
+}
+
+// ObjectInfo describes an object listed in a bucket.
+type ObjectInfo struct {
+	Key string
+	// ETag is the entity tag without quotes. For objects uploaded in a single part it is the hex MD5 of the content.
+	ETag string
+	Size int64
+	// ContentEncoding is only set by HeadObject.
+	ContentEncoding string
+	// Metadata is the user metadata, with lowercase names. It is only set by HeadObject.
+	Metadata map[string]string
 }
 
 // NewStorageObject creates a new StorageObject